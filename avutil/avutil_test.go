@@ -0,0 +1,233 @@
+package avutil
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// The expected values below were computed with the av_rescale_rnd,
+// av_compare_ts and av_reduce code of libavutil 4.4.
+
+func TestRescaleRnd(t *testing.T) {
+	tests := []struct {
+		a, b, c int64
+		rnd     Rounding
+		want    int64
+	}{
+		{7, 1, 2, RoundZero, 3},
+		{7, 1, 2, RoundInf, 4},
+		{7, 1, 2, RoundDown, 3},
+		{7, 1, 2, RoundUp, 4},
+		{7, 1, 2, RoundNearInf, 4},
+		{-7, 1, 2, RoundZero, -3},
+		{-7, 1, 2, RoundInf, -4},
+		{-7, 1, 2, RoundDown, -4},
+		{-7, 1, 2, RoundUp, -3},
+		{-7, 1, 2, RoundNearInf, -4},
+		{-5, 1, 2, RoundNearInf, -3},
+		{5, 1, 2, RoundNearInf, 3},
+		{10, 3, 4, Rounding(4), math.MinInt64},
+		{10, 3, 4, Rounding(6), math.MinInt64},
+		{1, -1, 1, RoundNearInf, math.MinInt64},
+		{1, 1, 0, RoundNearInf, math.MinInt64},
+		{math.MinInt64, 1, 2, RoundNearInf, -4611686018427387904},
+		{math.MaxInt64, 1, 2, RoundNearInf, 4611686018427387904},
+		{math.MinInt64, 1, 2, RoundNearInf | RoundPassMinMax, math.MinInt64},
+		{math.MaxInt64, 3, 2, RoundNearInf | RoundPassMinMax, math.MaxInt64},
+		{-math.MaxInt64, 1, 1, RoundDown | RoundPassMinMax, -math.MaxInt64},
+		{math.MaxInt64, 1, 1, RoundNearInf, math.MaxInt64},
+		{math.MaxInt64, 2, 1, RoundNearInf, math.MinInt64},
+		{4611686018427387903, 3, 1, RoundNearInf, math.MinInt64},
+		{4611686018427387904, 2, 1, RoundZero, math.MinInt64},
+		{3000000000, 90000, 1001, RoundNearInf, 269730269730},
+		{3000000000, 3000000000, 7, RoundNearInf, 1285714285714285714},
+		{123456789012345, 1000000007, 3000000019, RoundZero, 41152263031549},
+		{123456789012345, 1000000007, 3000000019, RoundInf, 41152263031550},
+		{123456789012345, 1000000007, 3000000019, RoundDown, 41152263031549},
+		{123456789012345, 1000000007, 3000000019, RoundUp, 41152263031550},
+		{-123456789012345, 1000000007, 3000000019, RoundDown, -41152263031550},
+		{-123456789012345, 1000000007, 3000000019, RoundUp, -41152263031549},
+		{-123456789012345, 1000000007, 3000000019, RoundNearInf, -41152263031550},
+		{math.MaxInt64, math.MaxInt64, math.MaxInt64, RoundNearInf, math.MaxInt64},
+		{math.MaxInt64, 9223372036854775806, math.MaxInt64, RoundNearInf, 9223372036854775806},
+		{math.MaxInt64, math.MaxInt64, 9223372036854775806, RoundNearInf, math.MinInt64},
+		{1099511627776, 1099511627776, 2199023255552, RoundNearInf, 549755813888},
+		{90000, 1, 3, RoundInf, 30000},
+	}
+	for _, tt := range tests {
+		if got := RescaleRnd(tt.a, tt.b, tt.c, tt.rnd); got != tt.want {
+			t.Errorf("RescaleRnd(%d, %d, %d, %d) = %d, want %d", tt.a, tt.b, tt.c, tt.rnd, got, tt.want)
+		}
+	}
+}
+
+func TestRescaleQ(t *testing.T) {
+	if got := RescaleQ(3003, Q(1, 90000), Q(1001, 30000)); got != 1 {
+		t.Errorf("RescaleQ(3003, 1/90000, 1001/30000) = %d, want 1", got)
+	}
+	if got := RescaleQRnd(NoPTS, Q(1, 90000), Q(1, 1000), RoundNearInf|RoundPassMinMax); got != NoPTS {
+		t.Errorf("RescaleQRnd(NoPTS) with RoundPassMinMax = %d", got)
+	}
+	if got := RescaleQRnd(1, Q(1, 3), Q(1, 2), RoundUp); got != 1 {
+		t.Errorf("RescaleQRnd(1, 1/3, 1/2, RoundUp) = %d, want 1", got)
+	}
+}
+
+func TestCompareTS(t *testing.T) {
+	tests := []struct {
+		a    int64
+		tbA  Rational
+		b    int64
+		tbB  Rational
+		want int
+	}{
+		{1, Q(1, 2), 1, Q(1, 3), 1},
+		{2, Q(1, 2), 3, Q(1, 3), 0},
+		{1, Q(1, 1000), 90, Q(1, 90000), 0},
+		{1, Q(1, 1000), 89, Q(1, 90000), 1},
+		{math.MaxInt64, Q(1, 1), math.MaxInt64, Q(1, 2), -1},
+		{102481911520608, Q(1, 90000), 102481911520608, Q(1, 90000), 0},
+		{-1099511627776, Q(1, 1000), -98956046499840, Q(1, 90000), 0},
+		{1099511627776, Q(1001, 30000), 3301833418211328, Q(1, 90000), 0},
+		{1099511627776, Q(1001, 30000), 3301833418211329, Q(1, 90000), -1},
+		{5000000000, Q(1, 1000000), 5000, Q(1, 1), 0},
+		{-5000000001, Q(1, 1000000), -5000, Q(1, 1), -1},
+		{4611686018427387904, Q(1, 48000), 4611686018427387904, Q(1, 44100), -1},
+	}
+	for _, tt := range tests {
+		if got := CompareTS(tt.a, tt.tbA, tt.b, tt.tbB); got != tt.want {
+			t.Errorf("CompareTS(%d, %v, %d, %v) = %d, want %d", tt.a, tt.tbA, tt.b, tt.tbB, got, tt.want)
+		}
+	}
+}
+
+// TestReduce includes the arguments av_d2q passes for the double in the
+// comment: the double scaled by a power of two close to 2^61.
+func TestReduce(t *testing.T) {
+	tests := []struct {
+		num, den, max int64
+		want          Rational
+		exact         bool
+	}{
+		{6, 4, 2147483647, Q(3, 2), true},
+		{-6, 4, 2147483647, Q(-3, 2), true},
+		{6, -4, 2147483647, Q(-3, 2), true},
+		{0, 5, 2147483647, Q(0, 1), true},
+		{5, 0, 2147483647, Q(1, 0), true},
+		{30000, 1001, 1000, Q(989, 33), false},
+		{3000000021, 10737418235, 2147483647, Q(243391961, 871133754), false},
+		{math.MaxInt64, 3, 2147483647, Q(2147483647, 1), false},
+		{3, math.MaxInt64, 2147483647, Q(0, 1), false},
+		{math.MaxInt64, 9223372036854775806, 255, Q(1, 1), false},
+		{314159265358979, 100000000000000, 65535, Q(65298, 20785), false},
+		{3622009729038561280, 1152921504606846976, 2147483647, Q(1881244168, 598818617), false},   // 3.1415926535897931
+		{3622009729038561280, 1152921504606846976, 1000, Q(355, 113), false},                      // 3.1415926535897931
+		{3622009729038561280, 1152921504606846976, 65535, Q(65298, 20785), false},                 // 3.1415926535897931
+		{3622009729038561280, 1152921504606846976, 255, Q(245, 78), false},                        // 3.1415926535897931
+		{-3622009729038561280, 1152921504606846976, 2147483647, Q(-1881244168, 598818617), false}, // -3.1415926535897931
+		{-3622009729038561280, 1152921504606846976, 1000, Q(-355, 113), false},                    // -3.1415926535897931
+		{-3622009729038561280, 1152921504606846976, 65535, Q(-65298, 20785), false},               // -3.1415926535897931
+		{-3622009729038561280, 1152921504606846976, 255, Q(-245, 78), false},                      // -3.1415926535897931
+		{4319136505769906176, 144115188075855872, 2147483647, Q(30000, 1001), false},              // 29.970029970029969
+		{4319136505769906176, 144115188075855872, 1000, Q(989, 33), false},                        // 29.970029970029969
+		{4319136505769906176, 144115188075855872, 65535, Q(30000, 1001), false},                   // 29.970029970029969
+		{4319136505769906176, 144115188075855872, 255, Q(30, 1), false},                           // 29.970029970029969
+		{768614336404564608, 2305843009213693952, 2147483647, Q(1, 3), false},                     // 0.33333333333333331
+		{768614336404564608, 2305843009213693952, 1000, Q(1, 3), false},                           // 0.33333333333333331
+		{768614336404564608, 2305843009213693952, 65535, Q(1, 3), false},                          // 0.33333333333333331
+		{768614336404564608, 2305843009213693952, 255, Q(1, 3), false},                            // 0.33333333333333331
+		{4294967298147483648, 4294967296, 2147483647, Q(2000000001, 2), true},                     // 1000000000.5
+		{4294967298147483648, 4294967296, 1000, Q(1000, 1), false},                                // 1000000000.5
+		{4294967298147483648, 4294967296, 65535, Q(65535, 1), false},                              // 1000000000.5
+		{4294967298147483648, 4294967296, 255, Q(255, 1), false},                                  // 1000000000.5
+		{4611686016279904256, 2147483648, 2147483647, Q(2147483647, 1), true},                     // 2147483647
+		{4611686016279904256, 2147483648, 1000, Q(1000, 1), false},                                // 2147483647
+		{4611686016279904256, 2147483648, 65535, Q(65535, 1), false},                              // 2147483647
+		{4611686016279904256, 2147483648, 255, Q(255, 1), false},                                  // 2147483647
+		{230584300921369408, 2305843009213693952, 2147483647, Q(1, 10), false},                    // 0.10000000000000001
+		{230584300921369408, 2305843009213693952, 1000, Q(1, 10), false},                          // 0.10000000000000001
+		{230584300921369408, 2305843009213693952, 65535, Q(1, 10), false},                         // 0.10000000000000001
+		{230584300921369408, 2305843009213693952, 255, Q(1, 10), false},                           // 0.10000000000000001
+		{2305843, 2305843009213693952, 2147483647, Q(0, 1), false},                                // 9.9999999999999998e-13
+		{2305843, 2305843009213693952, 1000, Q(0, 1), false},                                      // 9.9999999999999998e-13
+		{2305843, 2305843009213693952, 65535, Q(0, 1), false},                                     // 9.9999999999999998e-13
+		{2305843, 2305843009213693952, 255, Q(0, 1), false},                                       // 9.9999999999999998e-13
+		{2305843043573432320, 137438953472, 2147483647, Q(67108865, 4), true},                     // 16777216.25
+		{2305843043573432320, 137438953472, 1000, Q(1000, 1), false},                              // 16777216.25
+		{2305843043573432320, 137438953472, 65535, Q(65535, 1), false},                            // 16777216.25
+		{2305843043573432320, 137438953472, 255, Q(255, 1), false},                                // 16777216.25
+		{3174565331740880896, 72057594037927936, 2147483647, Q(6300, 143), false},                 // 44.055944055944053
+		{3174565331740880896, 72057594037927936, 1000, Q(793, 18), false},                         // 44.055944055944053
+		{3174565331740880896, 72057594037927936, 65535, Q(6300, 143), false},                      // 44.055944055944053
+		{3174565331740880896, 72057594037927936, 255, Q(44, 1), false},                            // 44.055944055944053
+	}
+	for _, tt := range tests {
+		got, exact := Reduce(tt.num, tt.den, tt.max)
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("Reduce(%d, %d, %d) = %v, %v, want %v, %v", tt.num, tt.den, tt.max, got, exact, tt.want, tt.exact)
+		}
+	}
+}
+
+func TestRationalArithmetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want Rational
+	}{
+		{"mul", Q(30000, 1001).Mul(Q(1001, 60000)), Q(1, 2)},
+		{"div", Q(1, 25).Div(Q(1, 50)), Q(2, 1)},
+		{"add", Q(1, 2).Add(Q(1, 3)), Q(5, 6)},
+		{"sub", Q(1, 2).Sub(Q(3, 4)), Q(-1, 4)},
+		{"inv", Q(1001, 30000).Inv(), Q(30000, 1001)},
+		{"reduce", Q(48000, 1920).Reduce(), Q(25, 1)},
+		{"mul overflow", Q(math.MaxInt32, 1).Mul(Q(math.MaxInt32, 1)), Q(math.MaxInt32, 1)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	cmps := []struct {
+		a, b Rational
+		want int
+	}{
+		{Q(1, 2), Q(2, 4), 0},
+		{Q(1, 3), Q(1, 2), -1},
+		{Q(-1, 2), Q(1, -3), -1},
+		{Q(1, 0), Q(1000, 1), 1},
+		{Q(-1, 0), Q(1, 0), -1},
+		{Q(0, 0), Q(1, 2), math.MinInt32},
+	}
+	for _, tt := range cmps {
+		if got := tt.a.Cmp(tt.b); got != tt.want {
+			t.Errorf("%v.Cmp(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	ts := Timestamp{Value: 900000, TB: Q(1, 90000)}
+	if d, ok := ts.Duration(); !ok || d != 10*time.Second {
+		t.Errorf("Duration() = %v, %v", d, ok)
+	}
+	if got := ts.Rescale(Q(1, 1000)); got.Value != 10000 {
+		t.Errorf("Rescale(1/1000) = %v", got)
+	}
+	if got := ts.Add(time.Second / 3); got.Value != 930000 {
+		t.Errorf("Add(1s/3) = %v", got)
+	}
+	if got := FromDuration(time.Second/30, Q(1001, 30000)); got.Value != 1 {
+		t.Errorf("FromDuration(1s/30, 1001/30000) = %v", got)
+	}
+	none := Timestamp{Value: NoPTS, TB: Q(1, 90000)}
+	if _, ok := none.Duration(); ok || none.Rescale(Q(1, 1000)).Valid() || none.Add(time.Second).Valid() {
+		t.Error("NoPTS is not kept")
+	}
+	if none.Compare(ts) != -1 || ts.Compare(none) != 1 || none.Compare(none) != 0 {
+		t.Error("NoPTS does not sort first")
+	}
+	if got := ts.Compare(Timestamp{Value: 10, TB: Q(1, 1)}); got != 0 {
+		t.Errorf("Compare(10s) = %d", got)
+	}
+}
@@ -0,0 +1,149 @@
+// Package avutil provides Go versions of the libavutil rational and
+// timestamp arithmetic, computed without calling into FFmpeg and giving the
+// same results.
+package avutil
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Rational is an AVRational with arithmetic methods. Convert with
+// Rational(q) and libavutil.AVRational(r).
+type Rational libavutil.AVRational
+
+// Q returns num/den, not reduced.
+func Q(num, den int32) Rational {
+	return Rational{Num: ffcommon.FInt(num), Den: ffcommon.FInt(den)}
+}
+
+// AV returns r as an AVRational.
+func (r Rational) AV() libavutil.AVRational {
+	return libavutil.AVRational(r)
+}
+
+// Mul returns r*q like av_mul_q.
+func (r Rational) Mul(q Rational) Rational {
+	res, _ := Reduce(int64(r.Num)*int64(q.Num), int64(r.Den)*int64(q.Den), math.MaxInt32)
+	return res
+}
+
+// Div returns r/q like av_div_q.
+func (r Rational) Div(q Rational) Rational {
+	return r.Mul(Rational{Num: q.Den, Den: q.Num})
+}
+
+// Add returns r+q like av_add_q.
+func (r Rational) Add(q Rational) Rational {
+	res, _ := Reduce(int64(r.Num)*int64(q.Den)+int64(q.Num)*int64(r.Den), int64(r.Den)*int64(q.Den), math.MaxInt32)
+	return res
+}
+
+// Sub returns r-q like av_sub_q.
+func (r Rational) Sub(q Rational) Rational {
+	return r.Add(Rational{Num: -q.Num, Den: q.Den})
+}
+
+// Inv returns 1/r like av_inv_q.
+func (r Rational) Inv() Rational {
+	return Rational{Num: r.Den, Den: r.Num}
+}
+
+// Cmp compares r and q like av_cmp_q: 0 if equal, 1 if r > q, -1 if
+// r < q and math.MinInt32 if either is 0/0.
+func (r Rational) Cmp(q Rational) int {
+	tmp := int64(r.Num)*int64(q.Den) - int64(q.Num)*int64(r.Den)
+	if tmp != 0 {
+		return int((tmp^int64(r.Den)^int64(q.Den))>>63) | 1
+	}
+	if q.Den != 0 && r.Den != 0 {
+		return 0
+	}
+	if r.Num != 0 && q.Num != 0 {
+		return int(r.Num>>31) - int(q.Num>>31)
+	}
+	return math.MinInt32
+}
+
+// Reduce returns r in lowest terms.
+func (r Rational) Reduce() Rational {
+	res, _ := Reduce(int64(r.Num), int64(r.Den), math.MaxInt32)
+	return res
+}
+
+// Float64 returns r as a float64 like av_q2d.
+func (r Rational) Float64() float64 {
+	return float64(r.Num) / float64(r.Den)
+}
+
+// Valid tells whether r has a non-zero denominator.
+func (r Rational) Valid() bool {
+	return r.Den != 0
+}
+
+func (r Rational) String() string {
+	return fmt.Sprintf("%d/%d", r.Num, r.Den)
+}
+
+// Reduce reduces num/den to a fraction whose terms do not exceed max, like
+// av_reduce. exact tells whether no approximation was needed.
+func Reduce(num, den, max int64) (r Rational, exact bool) {
+	a0n, a0d := int64(0), int64(1)
+	a1n, a1d := int64(1), int64(0)
+	sign := (num < 0) != (den < 0)
+	if g := gcd(abs(num), abs(den)); g != 0 {
+		num = abs(num) / g
+		den = abs(den) / g
+	}
+	if num <= max && den <= max {
+		a1n, a1d = num, den
+		den = 0
+	}
+	for den != 0 {
+		x := uint64(num) / uint64(den)
+		nextDen := num - den*int64(x)
+		a2n := int64(x)*a1n + a0n
+		a2d := int64(x)*a1d + a0d
+		if a2n > max || a2d > max {
+			if a1n != 0 {
+				x = uint64((max - a0n) / a1n)
+			}
+			if a1d != 0 {
+				if y := uint64((max - a0d) / a1d); y < x {
+					x = y
+				}
+			}
+			// unsigned like the C expression mixing uint64_t and int64_t
+			if uint64(den)*(2*x*uint64(a1d)+uint64(a0d)) > uint64(num*a1d) {
+				a1n, a1d = int64(x)*a1n+a0n, int64(x)*a1d+a0d
+			}
+			break
+		}
+		a0n, a0d = a1n, a1d
+		a1n, a1d = a2n, a2d
+		num = den
+		den = nextDen
+	}
+	if sign {
+		a1n = -a1n
+	}
+	return Rational{Num: ffcommon.FInt(a1n), Den: ffcommon.FInt(a1d)}, den == 0
+}
+
+// gcd is av_gcd.
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return abs(a)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
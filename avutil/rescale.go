@@ -0,0 +1,145 @@
+package avutil
+
+import (
+	"math"
+)
+
+// Rounding is an AVRounding mode.
+type Rounding int32
+
+const (
+	// RoundZero rounds toward zero.
+	RoundZero Rounding = 0
+	// RoundInf rounds away from zero.
+	RoundInf Rounding = 1
+	// RoundDown rounds toward -infinity.
+	RoundDown Rounding = 2
+	// RoundUp rounds toward +infinity.
+	RoundUp Rounding = 3
+	// RoundNearInf rounds to nearest, halfway cases away from zero.
+	RoundNearInf Rounding = 5
+	// RoundPassMinMax is a flag passing math.MinInt64 (AV_NOPTS_VALUE) and
+	// math.MaxInt64 through unchanged.
+	RoundPassMinMax Rounding = 8192
+)
+
+// Rescale returns a*b/c rounded to nearest like av_rescale.
+func Rescale(a, b, c int64) int64 {
+	return RescaleRnd(a, b, c, RoundNearInf)
+}
+
+// RescaleRnd returns a*b/c with the given rounding like av_rescale_rnd,
+// without overflowing in the intermediate product. Like FFmpeg it returns
+// math.MinInt64 for invalid arguments or a result out of range.
+func RescaleRnd(a, b, c int64, rnd Rounding) int64 {
+	if mode := rnd &^ RoundPassMinMax; c <= 0 || b < 0 || uint32(mode) > 5 || mode == 4 {
+		return math.MinInt64
+	}
+	if rnd&RoundPassMinMax != 0 {
+		if a == math.MinInt64 || a == math.MaxInt64 {
+			return a
+		}
+		rnd -= RoundPassMinMax
+	}
+	if a < 0 {
+		if a < -math.MaxInt64 {
+			a = -math.MaxInt64
+		}
+		return int64(-uint64(RescaleRnd(-a, b, c, rnd^((rnd>>1)&1))))
+	}
+
+	var r int64
+	if rnd == RoundNearInf {
+		r = c / 2
+	} else if rnd&1 != 0 {
+		r = c - 1
+	}
+
+	if b <= math.MaxInt32 && c <= math.MaxInt32 {
+		if a <= math.MaxInt32 {
+			return (a*b + r) / c
+		}
+		ad := a / c
+		a2 := (a%c*b + r) / c
+		if ad >= math.MaxInt32 && b != 0 && ad > (math.MaxInt64-a2)/b {
+			return math.MinInt64
+		}
+		return ad*b + a2
+	}
+
+	// 128 bit product divided bit by bit
+	a0 := uint64(a) & 0xFFFFFFFF
+	a1 := uint64(a) >> 32
+	b0 := uint64(b) & 0xFFFFFFFF
+	b1 := uint64(b) >> 32
+	t1 := a0*b1 + a1*b0
+	t1a := t1 << 32
+
+	a0 = a0*b0 + t1a
+	a1 = a1*b1 + t1>>32
+	if a0 < t1a {
+		a1++
+	}
+	a0 += uint64(r)
+	if a0 < uint64(r) {
+		a1++
+	}
+
+	uc := uint64(c)
+	for i := 63; i >= 0; i-- {
+		a1 += a1 + (a0>>uint(i))&1
+		t1 += t1
+		if uc <= a1 {
+			a1 -= uc
+			t1++
+		}
+	}
+	if t1 > math.MaxInt64 {
+		return math.MinInt64
+	}
+	return int64(t1)
+}
+
+// RescaleQ converts a from time base bq to cq rounding to nearest, like
+// av_rescale_q.
+func RescaleQ(a int64, bq, cq Rational) int64 {
+	return RescaleQRnd(a, bq, cq, RoundNearInf)
+}
+
+// RescaleQRnd converts a from time base bq to cq like av_rescale_q_rnd.
+func RescaleQRnd(a int64, bq, cq Rational, rnd Rounding) int64 {
+	b := int64(bq.Num) * int64(cq.Den)
+	c := int64(cq.Num) * int64(bq.Den)
+	return RescaleRnd(a, b, c, rnd)
+}
+
+// CompareTS compares timestamp a in time base tbA with b in tbB like
+// av_compare_ts: -1 if a is before b, 1 if after and 0 if equal.
+func CompareTS(a int64, tbA Rational, b int64, tbB Rational) int {
+	qa := int64(tbA.Num) * int64(tbB.Den)
+	qb := int64(tbB.Num) * int64(tbA.Den)
+	if uabs(a)|uint64(qa)|uabs(b)|uint64(qb) <= math.MaxInt32 {
+		switch {
+		case a*qa > b*qb:
+			return 1
+		case a*qa < b*qb:
+			return -1
+		}
+		return 0
+	}
+	if RescaleRnd(a, qa, qb, RoundDown) < b {
+		return -1
+	}
+	if RescaleRnd(b, qb, qa, RoundDown) < a {
+		return 1
+	}
+	return 0
+}
+
+// uabs is FFABS64U.
+func uabs(v int64) uint64 {
+	if v < 0 {
+		return -uint64(v)
+	}
+	return uint64(v)
+}
@@ -0,0 +1,89 @@
+package avutil
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// NoPTS is AV_NOPTS_VALUE, an unknown timestamp.
+const NoPTS int64 = libavutil.AV_NOPTS_VALUE
+
+var nanosecond = Rational{Num: 1, Den: int32(time.Second)}
+
+// Timestamp is a timestamp with its time base. A Value of NoPTS means the
+// timestamp is unknown, which every method keeps.
+type Timestamp struct {
+	Value int64
+	TB    Rational
+}
+
+// FromDuration returns d in time base tb, rounded to nearest.
+func FromDuration(d time.Duration, tb Rational) Timestamp {
+	return Timestamp{Value: RescaleQ(int64(d), nanosecond, tb), TB: tb}
+}
+
+// Valid tells whether t is known.
+func (t Timestamp) Valid() bool {
+	return t.Value != NoPTS
+}
+
+// Duration returns t as a time.Duration, rounded to nearest. ok is false
+// for NoPTS or a time base with a zero denominator.
+func (t Timestamp) Duration() (d time.Duration, ok bool) {
+	if !t.Valid() || !t.TB.Valid() {
+		return 0, false
+	}
+	v := RescaleQRnd(t.Value, t.TB, nanosecond, RoundNearInf|RoundPassMinMax)
+	if v == math.MinInt64 {
+		return 0, false
+	}
+	return time.Duration(v), true
+}
+
+// Rescale returns t in time base tb, rounded to nearest.
+func (t Timestamp) Rescale(tb Rational) Timestamp {
+	return t.RescaleRnd(tb, RoundNearInf)
+}
+
+// RescaleRnd returns t in time base tb with the given rounding.
+func (t Timestamp) RescaleRnd(tb Rational, rnd Rounding) Timestamp {
+	if !t.Valid() {
+		return Timestamp{Value: NoPTS, TB: tb}
+	}
+	return Timestamp{Value: RescaleQRnd(t.Value, t.TB, tb, rnd|RoundPassMinMax), TB: tb}
+}
+
+// Add returns t moved by d, rounded to nearest in the time base of t.
+func (t Timestamp) Add(d time.Duration) Timestamp {
+	if !t.Valid() {
+		return t
+	}
+	return Timestamp{Value: t.Value + FromDuration(d, t.TB).Value, TB: t.TB}
+}
+
+// Compare compares t and u like av_compare_ts. Unknown timestamps sort
+// before all known ones.
+func (t Timestamp) Compare(u Timestamp) int {
+	switch {
+	case !t.Valid() && !u.Valid():
+		return 0
+	case !t.Valid():
+		return -1
+	case !u.Valid():
+		return 1
+	}
+	return CompareTS(t.Value, t.TB, u.Value, u.TB)
+}
+
+func (t Timestamp) String() string {
+	if !t.Valid() {
+		return "NOPTS"
+	}
+	if d, ok := t.Duration(); ok {
+		return fmt.Sprintf("%d (%v)", t.Value, d)
+	}
+	return fmt.Sprintf("%d@%v", t.Value, t.TB)
+}
@@ -0,0 +1,186 @@
+// Package bsf runs packets through chains of bitstream filters.
+package bsf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Packet is the packet type filters consume and produce.
+type Packet = libavcodec.AVPacket
+
+// Filter is an initialized chain of bitstream filters.
+type Filter struct {
+	ctx *libavcodec.AVBSFContext
+	out *Packet
+	eof bool
+	// pending is set when a loop stopped before the chain was drained,
+	// queue holds the packets given meanwhile, nil standing for the end
+	// of stream.
+	pending bool
+	queue   []*Packet
+}
+
+// New creates the filter chain described by chain, in the
+// "bsf1[=opt1=val1:opt2=val2][,bsf2]" syntax of the ffmpeg -bsf option,
+// for packets with codec parameters par and time base tb. An empty chain
+// passes packets through unchanged.
+func New(chain string, par *libavcodec.AVCodecParameters, tb libavutil.AVRational) (*Filter, error) {
+	f := &Filter{}
+	var ret ffcommon.FInt
+	if chain == "" {
+		ret = libavcodec.AvBsfGetNullFilter(&f.ctx)
+	} else {
+		ret = libavcodec.AvBsfListParseStr(chain, &f.ctx)
+	}
+	if ret < 0 {
+		return nil, fmt.Errorf("bsf: parse %q: %w", chain, libavutil.ErrorFromCode(ret))
+	}
+	if par != nil {
+		if ret = libavcodec.AvcodecParametersCopy(f.ctx.ParIn, par); ret < 0 {
+			f.Close()
+			return nil, fmt.Errorf("bsf: copy codec parameters: %w", libavutil.ErrorFromCode(ret))
+		}
+	}
+	f.ctx.TimeBaseIn = tb
+	if ret = f.ctx.AvBsfInit(); ret < 0 {
+		f.Close()
+		return nil, fmt.Errorf("bsf: init %q: %w", chain, libavutil.ErrorFromCode(ret))
+	}
+	if f.out = libavcodec.AvPacketAlloc(); f.out == nil {
+		f.Close()
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	return f, nil
+}
+
+// Params returns the codec parameters of the filtered packets, to copy to
+// the muxer stream they are written to.
+func (f *Filter) Params() *libavcodec.AVCodecParameters {
+	return f.ctx.ParOut
+}
+
+// TimeBase returns the time base of the filtered packets.
+func (f *Filter) TimeBase() libavutil.AVRational {
+	return f.ctx.TimeBaseOut
+}
+
+// Filter sends pkt to the chain when iterated and yields the packets that
+// come out. The chain takes over the data of pkt and leaves it blank. The
+// yielded packet is reused: it is only valid until the next iteration, and
+// must be referenced or moved to be kept. Nothing is lost when the loop
+// stops early: the packets left in the chain are yielded first by the next
+// call, and pkt is sent then if the chain had no room for it. A nil pkt ends
+// the stream, like Flush.
+func (f *Filter) Filter(pkt *Packet) iter.Seq2[*Packet, error] {
+	return func(yield func(*Packet, error) bool) {
+		if err := f.hold(pkt); err != nil {
+			yield(nil, err)
+			return
+		}
+		if f.pending && !f.receive(yield) {
+			return
+		}
+		for len(f.queue) > 0 {
+			p := f.queue[0]
+			f.queue = f.queue[1:]
+			err := f.send(p)
+			libavcodec.AvPacketFree(&p)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !f.receive(yield) {
+				return
+			}
+		}
+	}
+}
+
+// hold queues pkt, taking over its data.
+func (f *Filter) hold(pkt *Packet) error {
+	var p *Packet
+	if pkt != nil {
+		if p = libavcodec.AvPacketAlloc(); p == nil {
+			pkt.AvPacketUnref()
+			return libavutil.AVError(-libavutil.ENOMEM)
+		}
+		libavcodec.AvPacketMoveRef(p, pkt)
+	}
+	f.queue = append(f.queue, p)
+	return nil
+}
+
+// send sends pkt to the chain, nil ending the stream.
+func (f *Filter) send(pkt *Packet) error {
+	if f.eof {
+		if pkt != nil {
+			return errors.New("bsf: packet after end of stream")
+		}
+		return nil
+	}
+	if pkt == nil {
+		f.eof = true
+	}
+	if ret := f.ctx.AvBsfSendPacket(pkt); ret < 0 {
+		return fmt.Errorf("bsf: send packet: %w", libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// receive yields the packets the chain has ready. It returns false if the
+// loop stopped or failed before the chain was drained.
+func (f *Filter) receive(yield func(*Packet, error) bool) bool {
+	for {
+		err := libavutil.ErrorFromCode(f.ctx.AvBsfReceivePacket(f.out))
+		if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+			f.pending = false
+			return true
+		}
+		if err != nil {
+			f.pending = false
+			yield(nil, fmt.Errorf("bsf: receive packet: %w", err))
+			return false
+		}
+		more := yield(f.out, nil)
+		f.out.AvPacketUnref()
+		if !more {
+			f.pending = true
+			return false
+		}
+	}
+}
+
+// Flush ends the stream and yields the packets still buffered in the
+// chain. Call Reset to filter another stream afterwards.
+func (f *Filter) Flush() iter.Seq2[*Packet, error] {
+	return f.Filter(nil)
+}
+
+// Reset discards the state of the chain, e.g. after seeking.
+func (f *Filter) Reset() {
+	f.ctx.AvBsfFlush()
+	f.eof = false
+	f.pending = false
+	f.clearQueue()
+}
+
+func (f *Filter) clearQueue() {
+	for i := range f.queue {
+		libavcodec.AvPacketFree(&f.queue[i])
+	}
+	f.queue = nil
+}
+
+// Close frees the chain.
+func (f *Filter) Close() {
+	f.clearQueue()
+	libavcodec.AvPacketFree(&f.out)
+	libavcodec.AvBsfFree(&f.ctx)
+}
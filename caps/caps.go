@@ -0,0 +1,319 @@
+// Package caps lists what the loaded FFmpeg build supports: codecs,
+// muxers, demuxers, filters, bitstream filters and protocols.
+package caps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavfilter"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Codec is an encoder or a decoder.
+type Codec struct {
+	Name     string
+	LongName string
+	Type     libavutil.AVMediaType
+	ID       libavcodec.AVCodecID
+	Encoder  bool
+	// Capabilities holds the AV_CODEC_CAP_* flags.
+	Capabilities int32
+	// Hardware tells whether the codec only runs on hardware, and Hybrid
+	// whether it may use hardware or an external library.
+	Hardware bool
+	Hybrid   bool
+	// Wrapper names the external library the codec is backed by, e.g.
+	// "libx264", or is "".
+	Wrapper string
+
+	// The supported values, nil if unknown or unrestricted.
+	PixelFormats   []libavutil.AVPixelFormat
+	SampleFormats  []libavutil.AVSampleFormat
+	SampleRates    []int
+	ChannelLayouts []uint64
+	FrameRates     []libavutil.AVRational
+	Profiles       []string
+}
+
+// MediaType returns the name of the codec type, e.g. "video".
+func (c *Codec) MediaType() string {
+	return libavutil.AvGetMediaTypeString(c.Type)
+}
+
+// Format is a muxer or a demuxer.
+type Format struct {
+	Name     string
+	LongName string
+	// Extensions is the comma separated list of file extensions.
+	Extensions string
+	MimeType   string
+	// Flags holds the AVFMT_* flags.
+	Flags int32
+	// The default codecs of a muxer.
+	VideoCodec, AudioCodec, SubtitleCodec libavcodec.AVCodecID
+}
+
+// Filter is a libavfilter filter.
+type Filter struct {
+	Name        string
+	Description string
+	// Inputs and Outputs count the static pads; filters with
+	// AVFILTER_FLAG_DYNAMIC_INPUTS or _OUTPUTS create more.
+	Inputs, Outputs int
+	// Flags holds the AVFILTER_FLAG_* flags.
+	Flags int32
+}
+
+// BSF is a bitstream filter.
+type BSF struct {
+	Name string
+	// CodecIDs lists the codecs the filter works with, nil for any.
+	CodecIDs []libavcodec.AVCodecID
+}
+
+// Registry is everything a build supports, sorted by name.
+type Registry struct {
+	Encoders        []Codec
+	Decoders        []Codec
+	Muxers          []Format
+	Demuxers        []Format
+	Filters         []Filter
+	BSFs            []BSF
+	InputProtocols  []string
+	OutputProtocols []string
+}
+
+// Load enumerates the capabilities of the loaded libraries.
+func Load() *Registry {
+	r := &Registry{}
+	var it ffcommon.FVoidP
+	for c := libavcodec.AvCodecIterate(&it); c != nil; c = libavcodec.AvCodecIterate(&it) {
+		codec := describeCodec(c)
+		if codec.Encoder {
+			r.Encoders = append(r.Encoders, codec)
+		} else {
+			r.Decoders = append(r.Decoders, codec)
+		}
+	}
+	it = 0
+	for f := libavformat.AvMuxerIterate(&it); f != nil; f = libavformat.AvMuxerIterate(&it) {
+		r.Muxers = append(r.Muxers, Format{
+			Name:          ffcommon.GoString(f.Name),
+			LongName:      ffcommon.GoString(f.LongName),
+			Extensions:    ffcommon.GoString(f.Extensions),
+			MimeType:      ffcommon.GoString(f.MimeType),
+			Flags:         f.Flags,
+			VideoCodec:    f.VideoCodec,
+			AudioCodec:    f.AudioCodec,
+			SubtitleCodec: f.SubtitleCodec,
+		})
+	}
+	it = 0
+	for f := libavformat.AvDemuxerIterate(&it); f != nil; f = libavformat.AvDemuxerIterate(&it) {
+		r.Demuxers = append(r.Demuxers, Format{
+			Name:       ffcommon.GoString(f.Name),
+			LongName:   ffcommon.GoString(f.LongName),
+			Extensions: ffcommon.GoString(f.Extensions),
+			MimeType:   ffcommon.GoString(f.MimeType),
+			Flags:      f.Flags,
+		})
+	}
+	it = 0
+	for f := libavfilter.AvFilterIterate(&it); f != nil; f = libavfilter.AvFilterIterate(&it) {
+		filter := Filter{
+			Name:        ffcommon.GoString(f.Name),
+			Description: ffcommon.GoString(f.Description),
+			Flags:       f.Flags,
+		}
+		if f.Inputs != nil {
+			filter.Inputs = int(f.Inputs.AvfilterPadCount())
+		}
+		if f.Outputs != nil {
+			filter.Outputs = int(f.Outputs.AvfilterPadCount())
+		}
+		r.Filters = append(r.Filters, filter)
+	}
+	it = 0
+	for f := libavcodec.AvBsfIterate(&it); f != nil; f = libavcodec.AvBsfIterate(&it) {
+		r.BSFs = append(r.BSFs, BSF{
+			Name:     ffcommon.GoString(f.Name),
+			CodecIDs: terminated(f.CodecIds, libavcodec.AV_CODEC_ID_NONE),
+		})
+	}
+	for _, output := range []ffcommon.FInt{0, 1} {
+		var names []string
+		it = 0
+		for name := libavformat.AvioEnumProtocols(&it, output); name != ""; name = libavformat.AvioEnumProtocols(&it, output) {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if output == 0 {
+			r.InputProtocols = names
+		} else {
+			r.OutputProtocols = names
+		}
+	}
+
+	sortBy(r.Encoders, func(c Codec) string { return c.Name })
+	sortBy(r.Decoders, func(c Codec) string { return c.Name })
+	sortBy(r.Muxers, func(f Format) string { return f.Name })
+	sortBy(r.Demuxers, func(f Format) string { return f.Name })
+	sortBy(r.Filters, func(f Filter) string { return f.Name })
+	sortBy(r.BSFs, func(f BSF) string { return f.Name })
+	return r
+}
+
+func describeCodec(c *libavcodec.AVCodec) Codec {
+	codec := Codec{
+		Name:         ffcommon.GoString(c.Name),
+		LongName:     ffcommon.GoString(c.LongName),
+		Type:         c.Type,
+		ID:           c.Id,
+		Encoder:      c.AvCodecIsEncoder() != 0,
+		Capabilities: c.Capabilities,
+		Hardware:     c.Capabilities&libavcodec.AV_CODEC_CAP_HARDWARE != 0,
+		Hybrid:       c.Capabilities&libavcodec.AV_CODEC_CAP_HYBRID != 0,
+		Wrapper:      ffcommon.GoString(c.WrapperName),
+	}
+	codec.PixelFormats = terminated(c.PixFmts, libavutil.AV_PIX_FMT_NONE)
+	codec.SampleFormats = terminated(c.SampleFmts, libavutil.AV_SAMPLE_FMT_NONE)
+	for _, rate := range terminated(c.SupportedSamplerates, 0) {
+		codec.SampleRates = append(codec.SampleRates, int(rate))
+	}
+	codec.ChannelLayouts = terminated(c.ChannelLayouts, 0)
+	codec.FrameRates = terminated(c.SupportedFramerates, libavutil.AVRational{})
+	// FF_PROFILE_UNKNOWN ends the list
+	for _, p := range terminatedFunc(c.Profiles, func(p libavcodec.AVProfile) bool { return p.Profile == -99 }) {
+		codec.Profiles = append(codec.Profiles, ffcommon.GoString(p.Name))
+	}
+	return codec
+}
+
+// terminated returns the C array at p up to the element equal to end.
+func terminated[T comparable](p *T, end T) []T {
+	return terminatedFunc(p, func(v T) bool { return v == end })
+}
+
+// terminatedFunc returns the C array at p up to the first element for
+// which end is true.
+func terminatedFunc[T any](p *T, end func(T) bool) []T {
+	if p == nil {
+		return nil
+	}
+	var s []T
+	for ; !end(*p); p = (*T)(unsafe.Add(unsafe.Pointer(p), unsafe.Sizeof(*p))) {
+		s = append(s, *p)
+	}
+	return s
+}
+
+func sortBy[T any](s []T, key func(T) string) {
+	sort.SliceStable(s, func(i, j int) bool { return key(s[i]) < key(s[j]) })
+}
+
+func find[T any](s []T, name string, key func(T) string) *T {
+	i := sort.Search(len(s), func(i int) bool { return key(s[i]) >= name })
+	if i < len(s) && key(s[i]) == name {
+		return &s[i]
+	}
+	return nil
+}
+
+// Encoder returns the encoder called name, or nil.
+func (r *Registry) Encoder(name string) *Codec {
+	return find(r.Encoders, name, func(c Codec) string { return c.Name })
+}
+
+// Decoder returns the decoder called name, or nil.
+func (r *Registry) Decoder(name string) *Codec {
+	return find(r.Decoders, name, func(c Codec) string { return c.Name })
+}
+
+// Muxer returns the muxer called name, or nil.
+func (r *Registry) Muxer(name string) *Format {
+	return find(r.Muxers, name, func(f Format) string { return f.Name })
+}
+
+// Demuxer returns the demuxer called name, or nil. Demuxers may have
+// several comma separated names, e.g. "mov,mp4,m4a,3gp,3g2,mj2", and any
+// of them matches.
+func (r *Registry) Demuxer(name string) *Format {
+	for i := range r.Demuxers {
+		for _, n := range strings.Split(r.Demuxers[i].Name, ",") {
+			if n == name {
+				return &r.Demuxers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Filter returns the filter called name, or nil.
+func (r *Registry) Filter(name string) *Filter {
+	return find(r.Filters, name, func(f Filter) string { return f.Name })
+}
+
+// BSF returns the bitstream filter called name, or nil.
+func (r *Registry) BSF(name string) *BSF {
+	return find(r.BSFs, name, func(f BSF) string { return f.Name })
+}
+
+// Protocol tells whether the protocol called name is supported for input
+// or output.
+func (r *Registry) Protocol(name string, output bool) bool {
+	list := r.InputProtocols
+	if output {
+		list = r.OutputProtocols
+	}
+	i := sort.SearchStrings(list, name)
+	return i < len(list) && list[i] == name
+}
+
+// Requirements names the components a caller needs.
+type Requirements struct {
+	Encoders, Decoders []string
+	Muxers, Demuxers   []string
+	Filters, BSFs      []string
+	InputProtocols     []string
+	OutputProtocols    []string
+}
+
+// MissingError lists the required components a build lacks, as
+// "kind name" strings such as "encoder libx264".
+type MissingError struct {
+	Missing []string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("caps: missing %s", strings.Join(e.Missing, ", "))
+}
+
+// Check returns a *MissingError if any of req is not supported.
+func (r *Registry) Check(req Requirements) error {
+	var missing []string
+	check := func(kind string, names []string, ok func(string) bool) {
+		for _, n := range names {
+			if !ok(n) {
+				missing = append(missing, kind+" "+n)
+			}
+		}
+	}
+	check("encoder", req.Encoders, func(n string) bool { return r.Encoder(n) != nil })
+	check("decoder", req.Decoders, func(n string) bool { return r.Decoder(n) != nil })
+	check("muxer", req.Muxers, func(n string) bool { return r.Muxer(n) != nil })
+	check("demuxer", req.Demuxers, func(n string) bool { return r.Demuxer(n) != nil })
+	check("filter", req.Filters, func(n string) bool { return r.Filter(n) != nil })
+	check("bsf", req.BSFs, func(n string) bool { return r.BSF(n) != nil })
+	check("input protocol", req.InputProtocols, func(n string) bool { return r.Protocol(n, false) })
+	check("output protocol", req.OutputProtocols, func(n string) bool { return r.Protocol(n, true) })
+	if len(missing) > 0 {
+		return &MissingError{Missing: missing}
+	}
+	return nil
+}
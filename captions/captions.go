@@ -0,0 +1,207 @@
+// Package captions extracts the CEA-608 and CEA-708 closed captions that
+// broadcast video carries in its bitstream (ATSC A/53 user data) and turns
+// them into timed text cues for sidecar SRT or WebVTT files.
+package captions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+	"github.com/dwdcth/ffmpeg-go/v7/subs"
+)
+
+// Frame is the caption data of one video frame.
+type Frame struct {
+	PTS time.Duration
+	// Data holds cc_data_pkt triplets: a marker, valid and type byte
+	// followed by two bytes of data.
+	Data []byte
+}
+
+var microsecond = libavutil.AVRational{Num: 1, Den: 1000000}
+
+// triplets yields the valid triplets of cc_data.
+func triplets(data []byte) iter.Seq[[3]byte] {
+	return func(yield func([3]byte) bool) {
+		for i := 0; i+3 <= len(data); i += 3 {
+			if data[i]&0x04 == 0 {
+				continue
+			}
+			if !yield([3]byte{data[i], data[i+1], data[i+2]}) {
+				return
+			}
+		}
+	}
+}
+
+// Collect decodes the video stream with index stream of the file at path,
+// or the best video stream if stream is negative, and returns the caption
+// data of its frames in presentation order. Frames without captions are
+// left out.
+func Collect(ctx context.Context, path string, stream int) ([]Frame, error) {
+	ic, err := remux.OpenInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("captions: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+	if stream < 0 {
+		stream = int(ic.AvFindBestStream(libavutil.AVMEDIA_TYPE_VIDEO, -1, -1, nil, 0))
+		if stream < 0 {
+			return nil, fmt.Errorf("captions: %s: no video stream", path)
+		}
+	}
+	if stream >= int(ic.NbStreams) {
+		return nil, fmt.Errorf("captions: %s: no stream %d", path, stream)
+	}
+	st := ic.GetStream(ffcommon.FUnsignedInt(stream))
+	for i := ffcommon.FUnsignedInt(0); i < ic.NbStreams; i++ {
+		if int(i) != stream {
+			ic.GetStream(i).Discard = libavcodec.AVDISCARD_ALL
+		}
+	}
+
+	codec := libavcodec.AvcodecFindDecoder(st.Codecpar.CodecId)
+	if codec == nil {
+		return nil, fmt.Errorf("captions: no decoder for %s", libavcodec.AvcodecGetName(st.Codecpar.CodecId))
+	}
+	dec := codec.AvcodecAllocContext3()
+	if dec == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvcodecFreeContext(&dec)
+	if ret := dec.AvcodecParametersToContext(st.Codecpar); ret < 0 {
+		return nil, fmt.Errorf("captions: %w", libavutil.ErrorFromCode(ret))
+	}
+	dec.PktTimebase = st.TimeBase
+	if ret := dec.AvcodecOpen2(codec, nil); ret < 0 {
+		return nil, fmt.Errorf("captions: open decoder: %w", libavutil.ErrorFromCode(ret))
+	}
+	frame := libavutil.AvFrameAlloc()
+	if frame == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavutil.AvFrameFree(&frame)
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+
+	var frames []Frame
+	receive := func() error {
+		for {
+			err := libavutil.ErrorFromCode(dec.AvcodecReceiveFrame(frame))
+			if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("captions: decode: %w", err)
+			}
+			if cc, ok := sidedata.OfFrame(frame).A53CC(); ok && frame.BestEffortTimestamp != libavutil.AV_NOPTS_VALUE {
+				pts := libavutil.AvRescaleQ(frame.BestEffortTimestamp, st.TimeBase, microsecond)
+				frames = append(frames, Frame{PTS: time.Duration(pts) * time.Microsecond, Data: cc})
+			}
+			frame.AvFrameUnref()
+		}
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		err = libavutil.ErrorFromCode(ic.AvReadFrame(pkt))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("captions: read packet: %w", err)
+		}
+		if int(pkt.StreamIndex) != stream {
+			pkt.AvPacketUnref()
+			continue
+		}
+		ret := dec.AvcodecSendPacket(pkt)
+		pkt.AvPacketUnref()
+		if ret < 0 && ret != libavutil.AVERROR_INVALIDDATA {
+			return nil, fmt.Errorf("captions: decode: %w", libavutil.ErrorFromCode(ret))
+		}
+		if err = receive(); err != nil {
+			return nil, err
+		}
+	}
+	if ret := dec.AvcodecSendPacket(nil); ret < 0 {
+		return nil, fmt.Errorf("captions: decode: %w", libavutil.ErrorFromCode(ret))
+	}
+	if err = receive(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].PTS < frames[j].PTS })
+	return frames, nil
+}
+
+// Config selects what Extract returns.
+type Config struct {
+	// Stream is the index of the video stream, -1 for the best one.
+	Stream int
+	// Channels are the CEA-608 channels to decode, all four if empty.
+	Channels []Channel
+	// Services makes Extract return the raw CEA-708 service blocks too.
+	Services bool
+}
+
+// Result holds the captions of a file.
+type Result struct {
+	// Tracks holds the cues of every selected channel that has some.
+	Tracks   map[Channel][]subs.Cue
+	Services []ServiceBlock
+}
+
+// Extract collects the captions of the file at path and decodes them as
+// selected by c.
+func Extract(ctx context.Context, path string, c Config) (*Result, error) {
+	frames, err := Collect(ctx, path, c.Stream)
+	if err != nil {
+		return nil, err
+	}
+	channels := c.Channels
+	if len(channels) == 0 {
+		channels = []Channel{CC1, CC2, CC3, CC4}
+	}
+	r := &Result{Tracks: map[Channel][]subs.Cue{}}
+	for _, ch := range channels {
+		cues, err := Decode608(frames, ch)
+		if err != nil {
+			return nil, err
+		}
+		if len(cues) > 0 {
+			r.Tracks[ch] = cues
+		}
+	}
+	if c.Services {
+		r.Services = Services(frames)
+	}
+	return r, nil
+}
+
+// Write writes cues to a sidecar file at path, SRT or WebVTT after its
+// extension.
+func Write(path string, cues []subs.Cue) error {
+	f, err := subs.FormatFor(path)
+	if err != nil {
+		return err
+	}
+	if f != subs.SRT && f != subs.WebVTT {
+		return fmt.Errorf("captions: %s: captions are written as SRT or WebVTT", path)
+	}
+	return subs.Write(path, f, "", cues)
+}
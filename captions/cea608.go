@@ -0,0 +1,172 @@
+package captions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/subs"
+)
+
+// Channel is a CEA-608 caption channel. CC1 and CC2 are carried in the
+// first field, CC3 and CC4 in the second.
+type Channel int
+
+const (
+	CC1 Channel = 1 + iota
+	CC2
+	CC3
+	CC4
+)
+
+func (c Channel) String() string {
+	return fmt.Sprintf("CC%d", int(c))
+}
+
+func (c Channel) field() int { return (int(c) - 1) / 2 }
+
+// cc_data triplet types
+const (
+	ntscField1 = 0
+	ntscField2 = 1
+	dtvccData  = 2
+	dtvccStart = 3
+)
+
+// padding is a valid field 1 triplet holding no data.
+var padding = [3]byte{0xfc, 0x80, 0x80}
+
+// withParity sets the odd parity bit of the 7-bit value b.
+func withParity(b byte) byte {
+	b &= 0x7f
+	n := 0
+	for v := b; v != 0; v >>= 1 {
+		n += int(v & 1)
+	}
+	if n%2 == 0 {
+		b |= 0x80
+	}
+	return b
+}
+
+// demuxer extracts one channel from the byte pairs of its field and
+// rewrites it as CC1, the only channel the ccaption decoder reads.
+type demuxer struct {
+	ch Channel
+	// current is the channel of the text pairs, set by the last control
+	// code
+	current Channel
+	xds     bool
+}
+
+func newDemuxer(ch Channel) *demuxer {
+	return &demuxer{ch: ch, current: Channel(2*ch.field() + 1)}
+}
+
+// pair returns the CC1 triplet for the pair b1, b2 of the field of the
+// channel, padding if it belongs to another channel.
+func (d *demuxer) pair(b1, b2 byte) [3]byte {
+	hi, lo := b1&0x7f, b2&0x7f
+	switch {
+	case hi == 0 && lo == 0:
+		return padding
+	case d.ch.field() == 1 && hi >= 0x01 && hi <= 0x0f:
+		// extended data services of the second field, ended by 0x0f
+		d.xds = hi != 0x0f
+		return padding
+	case hi >= 0x10 && hi <= 0x1f:
+		d.xds = false
+		d.current = Channel(2*d.ch.field() + 1 + int(hi&0x08)>>3)
+		if d.current != d.ch {
+			return padding
+		}
+		hi &^= 0x08
+		if hi == 0x15 && lo >= 0x20 && lo <= 0x2f {
+			// the second field sends the miscellaneous control codes
+			// with 0x15 instead of 0x14, which also starts the PACs of
+			// rows 5 and 6
+			hi = 0x14
+		}
+		return [3]byte{padding[0], withParity(hi), withParity(lo)}
+	case d.xds || hi < 0x20 || d.current != d.ch:
+		return padding
+	}
+	return [3]byte{padding[0], b1, b2}
+}
+
+// Decode608 decodes channel ch of the caption data of frames into cues,
+// with the ccaption decoder. Cues end when the next one starts or, for the
+// last one, with the last frame.
+func Decode608(frames []Frame, ch Channel) ([]subs.Cue, error) {
+	if ch < CC1 || ch > CC4 {
+		return nil, fmt.Errorf("captions: invalid channel %d", int(ch))
+	}
+	par := libavcodec.AvcodecParametersAlloc()
+	if par == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvcodecParametersFree(&par)
+	par.CodecType = libavutil.AVMEDIA_TYPE_SUBTITLE
+	par.CodecId = libavcodec.AV_CODEC_ID_EIA_608
+	dec, err := subs.NewDecoder(par, microsecond)
+	if err != nil {
+		return nil, fmt.Errorf("captions: %w", err)
+	}
+	defer dec.Close()
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+
+	d := newDemuxer(ch)
+	var cues []subs.Cue
+	open := -1
+	closeOpen := func(at time.Duration) {
+		if open >= 0 {
+			cues[open].End = max(at, cues[open].Start)
+			open = -1
+		}
+	}
+	for _, f := range frames {
+		var data []byte
+		for t := range triplets(f.Data) {
+			if int(t[0]&0x03) != ch.field() {
+				continue
+			}
+			p := d.pair(t[1], t[2])
+			data = append(data, p[:]...)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if ret := pkt.AvNewPacket(ffcommon.FInt(len(data))); ret < 0 {
+			return nil, libavutil.ErrorFromCode(ret)
+		}
+		copy(ffcommon.ByteSliceFromByteP(pkt.Data, len(data)), data)
+		pkt.Pts = ffcommon.FInt64T(f.PTS / time.Microsecond)
+		pkt.Dts = pkt.Pts
+		cue, err := dec.Decode(pkt)
+		pkt.AvPacketUnref()
+		if err != nil {
+			return nil, fmt.Errorf("captions: %s: %w", ch, err)
+		}
+		if cue == nil {
+			continue
+		}
+		closeOpen(cue.Start)
+		if cue.Text == "" {
+			continue
+		}
+		cues = append(cues, *cue)
+		if cue.End == cue.Start {
+			open = len(cues) - 1
+		}
+	}
+	if len(frames) > 0 {
+		closeOpen(frames[len(frames)-1].PTS)
+	}
+	return cues, nil
+}
@@ -0,0 +1,44 @@
+package captions
+
+import "testing"
+
+func TestDemuxerPair(t *testing.T) {
+	p := withParity
+	ctl := func(hi, lo byte) [3]byte { return [3]byte{0xfc, p(hi), p(lo)} }
+	text := func(a, b byte) [3]byte { return [3]byte{0xfc, p(a), p(b)} }
+	tests := []struct {
+		name string
+		ch   Channel
+		in   [][2]byte
+		want [][3]byte
+	}{
+		{"CC1 PAC row 1", CC1, [][2]byte{{0x11, 0x40}}, [][3]byte{ctl(0x11, 0x40)}},
+		{"CC1 PAC row 5", CC1, [][2]byte{{0x15, 0x40}}, [][3]byte{ctl(0x15, 0x40)}},
+		{"CC2 PAC row 6", CC2, [][2]byte{{0x1d, 0x70}}, [][3]byte{ctl(0x15, 0x70)}},
+		{"CC3 PAC row 5", CC3, [][2]byte{{0x15, 0x52}}, [][3]byte{ctl(0x15, 0x52)}},
+		{"CC4 PAC row 6", CC4, [][2]byte{{0x1d, 0x60}}, [][3]byte{ctl(0x15, 0x60)}},
+		{"CC1 end of caption", CC1, [][2]byte{{0x14, 0x2f}}, [][3]byte{ctl(0x14, 0x2f)}},
+		{"CC2 resume caption loading", CC2, [][2]byte{{0x1c, 0x20}}, [][3]byte{ctl(0x14, 0x20)}},
+		{"CC3 end of caption", CC3, [][2]byte{{0x15, 0x2f}}, [][3]byte{ctl(0x14, 0x2f)}},
+		{"CC4 erase displayed memory", CC4, [][2]byte{{0x1d, 0x2c}}, [][3]byte{ctl(0x14, 0x2c)}},
+		{"CC3 tab offset", CC3, [][2]byte{{0x17, 0x21}}, [][3]byte{ctl(0x17, 0x21)}},
+		{"CC2 extended character", CC2, [][2]byte{{0x1a, 0x30}}, [][3]byte{ctl(0x12, 0x30)}},
+		{"CC3 extended character", CC3, [][2]byte{{0x13, 0x25}}, [][3]byte{ctl(0x13, 0x25)}},
+		{"CC1 skips CC2", CC1, [][2]byte{{0x1c, 0x20}, {p('h'), p('i')}, {0x14, 0x20}, {p('h'), p('i')}},
+			[][3]byte{padding, padding, ctl(0x14, 0x20), text('h', 'i')}},
+		{"CC4 skips CC3", CC4, [][2]byte{{0x15, 0x20}, {p('h'), p('i')}}, [][3]byte{padding, padding}},
+		{"CC3 skips XDS", CC3, [][2]byte{{0x15, 0x20}, {0x01, 0x03}, {p('A'), p('B')}, {0x0f, 0x1d}, {p('h'), p('i')}},
+			[][3]byte{ctl(0x14, 0x20), padding, padding, padding, text('h', 'i')}},
+		{"XDS ended by a control code", CC3, [][2]byte{{0x01, 0x03}, {p('A'), p('B')}, {0x15, 0x2c}, {p('h'), p('i')}},
+			[][3]byte{padding, padding, ctl(0x14, 0x2c), text('h', 'i')}},
+		{"null pair", CC2, [][2]byte{{0x80, 0x80}}, [][3]byte{padding}},
+	}
+	for _, tt := range tests {
+		d := newDemuxer(tt.ch)
+		for i, in := range tt.in {
+			if got := d.pair(in[0], in[1]); got != tt.want[i] {
+				t.Errorf("%s: pair %d (%#02x, %#02x) = % x, want % x", tt.name, i, in[0], in[1], got, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package captions
+
+import "time"
+
+// ServiceBlock is the raw data of one CEA-708 caption service, as carried
+// in a DTVCC packet.
+type ServiceBlock struct {
+	// PTS is the time of the frame the packet ended in.
+	PTS time.Duration
+	// Service is the service number, 1 for the primary caption service.
+	Service int
+	Data    []byte
+}
+
+// Services returns the CEA-708 service blocks of the caption data of
+// frames, in order. Incomplete packets are dropped.
+func Services(frames []Frame) []ServiceBlock {
+	var blocks []ServiceBlock
+	var packet []byte
+	size := 0
+	for _, f := range frames {
+		for t := range triplets(f.Data) {
+			switch t[0] & 0x03 {
+			case dtvccStart:
+				// packet_size_code counts pairs of bytes, 0 standing for 64
+				size = int(t[1]&0x3f) * 2
+				if size == 0 {
+					size = 128
+				}
+				packet = append(packet[:0], t[1], t[2])
+			case dtvccData:
+				if packet == nil {
+					continue
+				}
+				packet = append(packet, t[1], t[2])
+			default:
+				continue
+			}
+			if packet != nil && len(packet) >= size {
+				blocks = appendServiceBlocks(blocks, f.PTS, packet[1:size])
+				packet = nil
+			}
+		}
+	}
+	return blocks
+}
+
+// appendServiceBlocks appends the service blocks of the body of a DTVCC
+// packet.
+func appendServiceBlocks(blocks []ServiceBlock, pts time.Duration, b []byte) []ServiceBlock {
+	for len(b) > 0 {
+		service, n := int(b[0]>>5), int(b[0]&0x1f)
+		b = b[1:]
+		if service == 0 {
+			// null block, the rest is padding
+			break
+		}
+		if service == 7 {
+			// extended service number
+			if len(b) == 0 {
+				break
+			}
+			service = int(b[0] & 0x3f)
+			b = b[1:]
+		}
+		if n > len(b) {
+			break
+		}
+		blocks = append(blocks, ServiceBlock{PTS: pts, Service: service, Data: append([]byte{}, b[:n]...)})
+		b = b[n:]
+	}
+	return blocks
+}
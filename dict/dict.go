@@ -0,0 +1,159 @@
+// Package dict converts between AVDictionary and Go maps and opens FFmpeg
+// objects with options, reporting the ones FFmpeg did not consume.
+package dict
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Entry is a single key/value pair of a dictionary.
+type Entry struct {
+	Key, Value string
+}
+
+// FromMap returns a new dictionary holding m, with keys inserted in sorted
+// order. An empty map gives a nil dictionary. Free the result with
+// libavutil.AvDictFree.
+func FromMap(m map[string]string) (*libavutil.AVDictionary, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]Entry, len(keys))
+	for i, k := range keys {
+		entries[i] = Entry{k, m[k]}
+	}
+	return FromEntries(entries)
+}
+
+// FromEntries returns a new dictionary holding entries in their order.
+// Free the result with libavutil.AvDictFree.
+func FromEntries(entries []Entry) (*libavutil.AVDictionary, error) {
+	var d *libavutil.AVDictionary
+	for _, e := range entries {
+		if ret := libavutil.AvDictSet(&d, e.Key, e.Value, 0); ret < 0 {
+			libavutil.AvDictFree(&d)
+			return nil, fmt.Errorf("dict: set %s: %w", e.Key, libavutil.ErrorFromCode(ret))
+		}
+	}
+	return d, nil
+}
+
+// Entries returns the entries of d in dictionary order.
+func Entries(d *libavutil.AVDictionary) []Entry {
+	if d == nil {
+		return nil
+	}
+	var entries []Entry
+	for e := d.AvDictGet("", nil, libavutil.AV_DICT_IGNORE_SUFFIX); e != nil; e = d.AvDictGet("", e, libavutil.AV_DICT_IGNORE_SUFFIX) {
+		entries = append(entries, Entry{ffcommon.GoString(e.Key), ffcommon.GoString(e.Value)})
+	}
+	return entries
+}
+
+// ToMap returns the entries of d as a map. Use Entries when the order
+// matters.
+func ToMap(d *libavutil.AVDictionary) map[string]string {
+	entries := Entries(d)
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		m[e.Key] = e.Value
+	}
+	return m
+}
+
+// Keys returns the keys of d in dictionary order. After an open call FFmpeg
+// leaves only the options it did not consume in the dictionary, so these
+// are the unknown or misspelled ones.
+func Keys(d *libavutil.AVDictionary) []string {
+	var keys []string
+	for _, e := range Entries(d) {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+// UnusedError lists options FFmpeg did not consume.
+type UnusedError struct {
+	Keys []string
+}
+
+func (e *UnusedError) Error() string {
+	return "Option not found: " + strings.Join(e.Keys, ", ")
+}
+
+// Check turns a list of unused options into an *UnusedError, or nil if it
+// is empty. Use it with the open functions of this package for the same
+// strictness as the ffmpeg command line tool.
+func Check(unused []string) error {
+	if len(unused) == 0 {
+		return nil
+	}
+	return &UnusedError{Keys: unused}
+}
+
+// call passes options to fn as a dictionary and returns the keys left in it.
+func call(options map[string]string, fn func(d **libavutil.AVDictionary) ffcommon.FInt) ([]string, ffcommon.FInt, error) {
+	d, err := FromMap(options)
+	if err != nil {
+		return nil, 0, err
+	}
+	ret := fn(&d)
+	unused := Keys(d)
+	libavutil.AvDictFree(&d)
+	return unused, ret, nil
+}
+
+// OpenInput opens url with avformat_open_input. It returns the options the
+// demuxer and protocol did not consume.
+func OpenInput(url string, format *libavformat.AVInputFormat, options map[string]string) (*libavformat.AVFormatContext, []string, error) {
+	var ctx *libavformat.AVFormatContext
+	unused, ret, err := call(options, func(d **libavutil.AVDictionary) ffcommon.FInt {
+		return libavformat.AvformatOpenInput(&ctx, url, format, d)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if ret < 0 {
+		return nil, unused, fmt.Errorf("dict: open %s: %w", url, libavutil.ErrorFromCode(ret))
+	}
+	return ctx, unused, nil
+}
+
+// OpenCodec opens ctx with avcodec_open2. It returns the options the codec
+// did not consume.
+func OpenCodec(ctx *libavcodec.AVCodecContext, codec *libavcodec.AVCodec, options map[string]string) ([]string, error) {
+	unused, ret, err := call(options, func(d **libavutil.AVDictionary) ffcommon.FInt {
+		return ctx.AvcodecOpen2(codec, d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret < 0 {
+		return unused, fmt.Errorf("dict: open codec: %w", libavutil.ErrorFromCode(ret))
+	}
+	return unused, nil
+}
+
+// WriteHeader writes the header of ctx with avformat_write_header. It
+// returns the options the muxer did not consume.
+func WriteHeader(ctx *libavformat.AVFormatContext, options map[string]string) ([]string, error) {
+	unused, ret, err := call(options, func(d **libavutil.AVDictionary) ffcommon.FInt {
+		return ctx.AvformatWriteHeader(d)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ret < 0 {
+		return unused, fmt.Errorf("dict: write header: %w", libavutil.ErrorFromCode(ret))
+	}
+	return unused, nil
+}
@@ -0,0 +1,308 @@
+package edit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// IncompatibleError reports why an input cannot be joined to the first one
+// by stream copy.
+type IncompatibleError struct {
+	Input  string
+	Stream int
+	Field  string
+	Want   interface{}
+	Got    interface{}
+}
+
+func (e *IncompatibleError) Error() string {
+	if e.Stream < 0 {
+		return fmt.Sprintf("edit: %s: %s is %v, want %v", e.Input, e.Field, e.Got, e.Want)
+	}
+	return fmt.Sprintf("edit: %s stream %d: %s is %v, want %v", e.Input, e.Stream, e.Field, e.Got, e.Want)
+}
+
+// Concat joins inputs into out by stream copy. All inputs must have the
+// same streams with the same codec parameters and extradata as the first
+// one, otherwise an *IncompatibleError is returned. Timestamps of every
+// input are offset by the end of the previous ones so the output stays
+// monotonic, and bitstream filters are inserted where the output container
+// needs a different bitstream format than the inputs, e.g.
+// h264_mp4toannexb for MPEG-TS.
+func Concat(ctx context.Context, inputs []string, out string) error {
+	if len(inputs) == 0 {
+		return errors.New("edit: no inputs to concatenate")
+	}
+	ics := make([]*libavformat.AVFormatContext, 0, len(inputs))
+	defer func() {
+		for i := range ics {
+			libavformat.AvformatCloseInput(&ics[i])
+		}
+	}()
+	for i, path := range inputs {
+		ic, err := remux.OpenInput(path)
+		if err != nil {
+			return fmt.Errorf("edit: %w", err)
+		}
+		ics = append(ics, ic)
+		if i > 0 {
+			if err = compatible(ics[0], ic, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	o, err := remux.NewOutput(ics[0], out, "")
+	if err != nil {
+		return fmt.Errorf("edit: %w", err)
+	}
+	defer o.Close()
+	bsfs, err := insertFilters(o, ics[0])
+	defer func() {
+		for i := range bsfs {
+			libavcodec.AvBsfFree(&bsfs[i])
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	if err = o.WriteHeader(nil); err != nil {
+		return fmt.Errorf("edit: %w", err)
+	}
+
+	j := &joiner{out: o, ref: ics[0], bsfs: bsfs, last: make([]ffcommon.FInt64T, len(o.Map))}
+	for i := range j.last {
+		j.last[i] = math.MinInt64
+	}
+	for _, ic := range ics {
+		if err = j.append(ctx, ic); err != nil {
+			return err
+		}
+	}
+	for i, bsf := range bsfs {
+		if bsf == nil {
+			continue
+		}
+		if err = j.filter(i, nil); err != nil {
+			return err
+		}
+	}
+	if err = o.Finish(); err != nil {
+		return fmt.Errorf("edit: %w", err)
+	}
+	return nil
+}
+
+// compatible checks that the streams of ic can continue those of first.
+func compatible(first, ic *libavformat.AVFormatContext, path string) error {
+	if ic.NbStreams != first.NbStreams {
+		return &IncompatibleError{Input: path, Stream: -1, Field: "stream count", Want: first.NbStreams, Got: ic.NbStreams}
+	}
+	for i := ffcommon.FUnsignedInt(0); i < ic.NbStreams; i++ {
+		a, b := first.GetStream(i).Codecpar, ic.GetStream(i).Codecpar
+		mismatch := func(field string, want, got interface{}) error {
+			return &IncompatibleError{Input: path, Stream: int(i), Field: field, Want: want, Got: got}
+		}
+		if a.CodecType != b.CodecType {
+			return mismatch("media type", libavutil.AvGetMediaTypeString(a.CodecType), libavutil.AvGetMediaTypeString(b.CodecType))
+		}
+		if a.CodecId != b.CodecId {
+			return mismatch("codec", libavcodec.AvcodecGetName(a.CodecId), libavcodec.AvcodecGetName(b.CodecId))
+		}
+		switch a.CodecType {
+		case libavutil.AVMEDIA_TYPE_VIDEO:
+			if a.Width != b.Width || a.Height != b.Height {
+				return mismatch("size", fmt.Sprintf("%dx%d", a.Width, a.Height), fmt.Sprintf("%dx%d", b.Width, b.Height))
+			}
+			if a.Format != b.Format {
+				return mismatch("pixel format", libavutil.AvGetPixFmtName(a.Format), libavutil.AvGetPixFmtName(b.Format))
+			}
+		case libavutil.AVMEDIA_TYPE_AUDIO:
+			if a.SampleRate != b.SampleRate {
+				return mismatch("sample rate", a.SampleRate, b.SampleRate)
+			}
+			if a.Channels != b.Channels || a.ChannelLayout != b.ChannelLayout {
+				return mismatch("channel layout", a.ChannelLayout, b.ChannelLayout)
+			}
+		}
+		if a.Profile != b.Profile {
+			return mismatch("profile", a.Profile, b.Profile)
+		}
+		ea := ffcommon.ByteSliceFromByteP(a.Extradata, int(a.ExtradataSize))
+		eb := ffcommon.ByteSliceFromByteP(b.Extradata, int(b.ExtradataSize))
+		if !bytes.Equal(ea, eb) {
+			return mismatch("extradata", fmt.Sprintf("%d bytes", len(ea)), fmt.Sprintf("%d different bytes", len(eb)))
+		}
+	}
+	return nil
+}
+
+// bitstreamFilter returns the filter converting par to what the muxer
+// format expects, or "".
+func bitstreamFilter(format string, par *libavcodec.AVCodecParameters) string {
+	extradata := ffcommon.ByteSliceFromByteP(par.Extradata, int(par.ExtradataSize))
+	// avcC/hvcC extradata starts with version 1, Annex B with a start code
+	lengthPrefixed := len(extradata) > 0 && extradata[0] == 1
+	switch format {
+	case "mpegts", "h264", "hevc":
+		if lengthPrefixed && par.CodecId == libavcodec.AV_CODEC_ID_H264 {
+			return "h264_mp4toannexb"
+		}
+		if lengthPrefixed && par.CodecId == libavcodec.AV_CODEC_ID_HEVC {
+			return "hevc_mp4toannexb"
+		}
+	case "mp4", "mov", "ipod", "3gp", "3g2", "ismv", "f4v", "matroska", "webm", "flv":
+		if par.CodecId == libavcodec.AV_CODEC_ID_AAC && len(extradata) == 0 {
+			// ADTS headers from MPEG-TS or raw AAC input
+			return "aac_adtstoasc"
+		}
+	}
+	return ""
+}
+
+// insertFilters creates the bitstream filters needed by the output, indexed
+// like the input streams, and updates the output codec parameters.
+func insertFilters(o *remux.Output, ic *libavformat.AVFormatContext) ([]*libavcodec.AVBSFContext, error) {
+	bsfs := make([]*libavcodec.AVBSFContext, len(o.Map))
+	format := ffcommon.GoString(o.Ctx.Oformat.Name)
+	for i, m := range o.Map {
+		if m < 0 {
+			continue
+		}
+		ist := ic.GetStream(ffcommon.FUnsignedInt(i))
+		name := bitstreamFilter(format, ist.Codecpar)
+		if name == "" {
+			continue
+		}
+		f := libavcodec.AvBsfGetByName(name)
+		if f == nil {
+			return bsfs, fmt.Errorf("edit: bitstream filter %s not available", name)
+		}
+		if ret := f.AvBsfAlloc(&bsfs[i]); ret < 0 {
+			return bsfs, fmt.Errorf("edit: %s: %w", name, libavutil.ErrorFromCode(ret))
+		}
+		bsf := bsfs[i]
+		libavcodec.AvcodecParametersCopy(bsf.ParIn, ist.Codecpar)
+		bsf.TimeBaseIn = ist.TimeBase
+		if ret := bsf.AvBsfInit(); ret < 0 {
+			return bsfs, fmt.Errorf("edit: %s: %w", name, libavutil.ErrorFromCode(ret))
+		}
+		ost := o.Ctx.GetStream(ffcommon.FUnsignedInt(m))
+		libavcodec.AvcodecParametersCopy(ost.Codecpar, bsf.ParOut)
+		ost.Codecpar.CodecTag = 0
+	}
+	return bsfs, nil
+}
+
+type joiner struct {
+	out *remux.Output
+	// ref is the first input, whose stream time bases all packets are
+	// converted to
+	ref  *libavformat.AVFormatContext
+	bsfs []*libavcodec.AVBSFContext
+	// offset is the end of the inputs written so far, in AV_TIME_BASE units
+	offset int64
+	// last is the last decoding timestamp written per stream
+	last []ffcommon.FInt64T
+}
+
+// append copies all packets of ic after the previous inputs.
+func (j *joiner) append(ctx context.Context, ic *libavformat.AVFormatContext) error {
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+
+	var start int64
+	if ic.StartTime != libavutil.AV_NOPTS_VALUE {
+		start = int64(ic.StartTime)
+	}
+	end := j.offset
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := libavutil.ErrorFromCode(ic.AvReadFrame(pkt))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("edit: read packet: %w", err)
+		}
+		idx := int(pkt.StreamIndex)
+		if idx >= len(j.out.Map) || j.out.Map[idx] < 0 {
+			pkt.AvPacketUnref()
+			continue
+		}
+		tb := j.ref.GetStream(pkt.StreamIndex).TimeBase
+		pkt.AvPacketRescaleTs(ic.GetStream(pkt.StreamIndex).TimeBase, tb)
+		shift(pkt, start-j.offset, tb)
+
+		// overlapping segment ends must not make timestamps go backwards
+		if pkt.Dts != libavutil.AV_NOPTS_VALUE && pkt.Dts <= j.last[idx] {
+			d := j.last[idx] + 1 - pkt.Dts
+			pkt.Dts += d
+			if pkt.Pts != libavutil.AV_NOPTS_VALUE {
+				pkt.Pts += d
+			}
+		}
+		if pkt.Dts != libavutil.AV_NOPTS_VALUE {
+			j.last[idx] = pkt.Dts
+		}
+		d := toUs(pkt.Duration, tb)
+		if d == 0 {
+			// without it the next input would start on this packet
+			d = frameDuration(ic.GetStream(pkt.StreamIndex))
+		}
+		if e := packetTime(pkt, tb) + d; e > end {
+			end = e
+		}
+
+		if err = j.filter(idx, pkt); err != nil {
+			return err
+		}
+	}
+	j.offset = end
+	return nil
+}
+
+// filter writes pkt through the bitstream filter of its stream, if any. A
+// nil pkt flushes the filter.
+func (j *joiner) filter(idx int, pkt *libavcodec.AVPacket) error {
+	bsf := j.bsfs[idx]
+	if bsf == nil {
+		if err := j.out.WritePacket(pkt); err != nil {
+			return fmt.Errorf("edit: %w", err)
+		}
+		return nil
+	}
+	if ret := bsf.AvBsfSendPacket(pkt); ret < 0 {
+		return fmt.Errorf("edit: bitstream filter: %w", libavutil.ErrorFromCode(ret))
+	}
+	out := libavcodec.AvPacketAlloc()
+	defer libavcodec.AvPacketFree(&out)
+	for {
+		err := libavutil.ErrorFromCode(bsf.AvBsfReceivePacket(out))
+		if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("edit: bitstream filter: %w", err)
+		}
+		out.StreamIndex = ffcommon.FUnsignedInt(idx)
+		if err = j.out.WritePacket(out); err != nil {
+			return fmt.Errorf("edit: %w", err)
+		}
+	}
+}
@@ -0,0 +1,570 @@
+package edit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Mode selects how Cut handles a start point between two keyframes.
+type Mode int
+
+const (
+	// Keyframe starts the output at the last video keyframe at or before
+	// start, so it may begin slightly early. Nothing is re-encoded.
+	Keyframe Mode = iota
+	// Smart starts the output exactly at start by re-encoding the video
+	// from start up to the next keyframe and copying everything after it,
+	// or up to end when start is in the last GOP. H.264 and HEVC heads
+	// carry their parameter sets in band and are written in the NAL unit
+	// format of the source, and the first copied keyframe repeats those of
+	// the source. Other codecs in containers taking global headers need an
+	// encoder reproducing the extradata of the source. Cut falls back to
+	// Keyframe when the head cannot be re-encoded so.
+	Smart
+)
+
+// CutResult describes a cut.
+type CutResult struct {
+	// Mode is the mode of the cut, Keyframe when Smart was not possible.
+	Mode Mode
+	// Start is where the output starts in the input, before the requested
+	// start in Keyframe mode.
+	Start time.Duration
+	// Warning tells why Smart fell back to Keyframe, "" if it did not.
+	Warning string
+}
+
+// Cut copies the range [start, end) of in to out. A non-positive end cuts
+// to the end of the file. Timestamps are shifted so the output starts near
+// zero, and the first audio packet of every stream is trimmed with
+// skip-samples side data so audio starts exactly at the cut point.
+func Cut(ctx context.Context, in, out string, start, end time.Duration, mode Mode) (*CutResult, error) {
+	ic, err := remux.OpenInput(in)
+	if err != nil {
+		return nil, fmt.Errorf("edit: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+
+	c := &cutter{
+		ic:    ic,
+		start: int64(start / time.Microsecond),
+		end:   math.MaxInt64,
+		video: int(ic.AvFindBestStream(libavutil.AVMEDIA_TYPE_VIDEO, -1, -1, nil, 0)),
+	}
+	if end > 0 {
+		if end <= start {
+			return nil, fmt.Errorf("edit: cut end %v is not after start %v", end, start)
+		}
+		c.end = int64(end / time.Microsecond)
+	}
+	if err = c.findKeyframes(mode == Smart); err != nil {
+		return nil, err
+	}
+
+	c.out, err = remux.NewOutput(ic, out, "")
+	if err != nil {
+		return nil, fmt.Errorf("edit: %w", err)
+	}
+	defer c.out.Close()
+	defer c.closeHead()
+
+	res := &CutResult{Mode: mode}
+	if c.origin < c.start && mode == Smart {
+		warning, err := c.openHead()
+		if err != nil {
+			return nil, err
+		}
+		if warning != "" {
+			c.closeHead()
+			res.Mode, res.Warning = Keyframe, warning
+		} else {
+			c.origin = c.start
+		}
+	}
+	res.Start = time.Duration(c.origin) * time.Microsecond
+	if err = c.seek(c.keyframe); err != nil {
+		return nil, err
+	}
+	if err = c.out.WriteHeader(nil); err != nil {
+		return nil, fmt.Errorf("edit: %w", err)
+	}
+	if err = c.run(ctx); err != nil {
+		return nil, err
+	}
+	if err = c.out.Finish(); err != nil {
+		return nil, fmt.Errorf("edit: %w", err)
+	}
+	return res, nil
+}
+
+type cutter struct {
+	ic  *libavformat.AVFormatContext
+	out *remux.Output
+
+	// all times in AV_TIME_BASE units
+	start, end int64
+	// keyframe is the video keyframe at or before start, next the first one
+	// at or after start or math.MaxInt64 if there is none, origin the time
+	// shifted to zero in the output
+	keyframe, next, origin int64
+	// copyFrom is the time of the first copied video keyframe
+	copyFrom int64
+	video    int
+
+	// nextDelay is pts-dts of the next keyframe packet, used to keep the
+	// decoding timestamps of the re-encoded head below the copied ones
+	nextDelay ffcommon.FInt64T
+	head      *head
+}
+
+func (c *cutter) seek(us int64) error {
+	if ret := c.ic.AvformatSeekFile(-1, math.MinInt64, ffcommon.FInt64T(us), ffcommon.FInt64T(us), 0); ret < 0 {
+		return fmt.Errorf("edit: seek to %v: %w", time.Duration(us)*time.Microsecond, libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// findKeyframes seeks to start and reads ahead to locate the keyframe the
+// copy begins with and, for Smart mode, the one after start.
+func (c *cutter) findKeyframes(needNext bool) error {
+	c.keyframe, c.next, c.origin = c.start, c.start, c.start
+	if c.video < 0 {
+		return nil
+	}
+	if err := c.seek(c.start); err != nil {
+		return err
+	}
+	tb := c.ic.GetStream(ffcommon.FUnsignedInt(c.video)).TimeBase
+	pkt := libavcodec.AvPacketAlloc()
+	defer libavcodec.AvPacketFree(&pkt)
+
+	found, foundNext := false, false
+	for {
+		ret := c.ic.AvReadFrame(pkt)
+		if ret < 0 {
+			if ret == libavutil.AVERROR_EOF {
+				break
+			}
+			return fmt.Errorf("edit: read packet: %w", libavutil.ErrorFromCode(ret))
+		}
+		if int(pkt.StreamIndex) != c.video || pkt.Flags&libavcodec.AV_PKT_FLAG_KEY == 0 {
+			pkt.AvPacketUnref()
+			continue
+		}
+		t := packetTime(pkt, tb)
+		if !found {
+			found = true
+			c.keyframe = t
+			if t > c.start {
+				// the seek overshot, nothing to keep before this keyframe
+				c.start = t
+			}
+		}
+		if t >= c.start {
+			c.next, foundNext = t, true
+			if pkt.Pts != libavutil.AV_NOPTS_VALUE && pkt.Dts != libavutil.AV_NOPTS_VALUE {
+				c.nextDelay = pkt.Pts - pkt.Dts
+			}
+			pkt.AvPacketUnref()
+			break
+		}
+		pkt.AvPacketUnref()
+		if !needNext {
+			break
+		}
+	}
+	if !found {
+		c.keyframe = c.start
+	}
+	if !needNext {
+		c.next = c.keyframe
+	} else if !foundNext {
+		// no keyframe after start, the head runs to the end
+		c.next = math.MaxInt64
+	}
+	c.origin = c.keyframe
+	return nil
+}
+
+func (c *cutter) run(ctx context.Context) error {
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+
+	live := 0
+	for _, m := range c.out.Map {
+		if m >= 0 {
+			live++
+		}
+	}
+	done := make([]bool, len(c.out.Map))
+	started := make([]bool, len(c.out.Map))
+
+	for live > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := libavutil.ErrorFromCode(c.ic.AvReadFrame(pkt))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("edit: read packet: %w", err)
+		}
+
+		idx := int(pkt.StreamIndex)
+		if idx >= len(done) || c.out.Map[idx] < 0 || done[idx] {
+			pkt.AvPacketUnref()
+			continue
+		}
+		ist := c.ic.GetStream(pkt.StreamIndex)
+		t := packetTime(pkt, ist.TimeBase)
+		if pkt.Dts != libavutil.AV_NOPTS_VALUE && toUs(pkt.Dts, ist.TimeBase) >= c.end {
+			done[idx] = true
+			live--
+			pkt.AvPacketUnref()
+			continue
+		}
+		if t >= c.end {
+			pkt.AvPacketUnref()
+			continue
+		}
+
+		if idx == c.video {
+			if c.head != nil {
+				if pkt.Flags&libavcodec.AV_PKT_FLAG_KEY == 0 || t < c.next {
+					err = c.head.decode(pkt, c)
+					pkt.AvPacketUnref()
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				if err = c.head.flush(c); err != nil {
+					return err
+				}
+				resume := c.head.resume
+				c.closeHead()
+				if len(resume) > 0 {
+					if err = setData(pkt, slices.Concat(resume, packetData(pkt))); err != nil {
+						return err
+					}
+				}
+			}
+			key := pkt.Flags&libavcodec.AV_PKT_FLAG_KEY != 0
+			if !started[idx] {
+				if !key || t < c.origin {
+					pkt.AvPacketUnref()
+					continue
+				}
+				started[idx] = true
+				c.copyFrom = t
+			} else if !key && t < c.copyFrom {
+				// leading pictures of an open GOP reference the previous one
+				pkt.AvPacketUnref()
+				continue
+			}
+		} else if !started[idx] {
+			dur := toUs(pkt.Duration, ist.TimeBase)
+			if t+dur <= c.origin {
+				pkt.AvPacketUnref()
+				continue
+			}
+			if t < c.origin && ist.Codecpar.CodecType == libavutil.AVMEDIA_TYPE_AUDIO {
+				if err = skipSamples(pkt, c.origin-t, ist.Codecpar.SampleRate); err != nil {
+					return err
+				}
+			}
+			started[idx] = true
+		}
+		if err = c.write(pkt, ist.TimeBase); err != nil {
+			return err
+		}
+	}
+	if c.head != nil {
+		return c.head.flush(c)
+	}
+	return nil
+}
+
+func (c *cutter) write(pkt *libavcodec.AVPacket, tb libavutil.AVRational) error {
+	shift(pkt, c.origin, tb)
+	if err := c.out.WritePacket(pkt); err != nil {
+		return fmt.Errorf("edit: %w", err)
+	}
+	return nil
+}
+
+// skipSamples marks the first us of an audio packet to be dropped by the
+// decoder, see AV_PKT_DATA_SKIP_SAMPLES.
+func skipSamples(pkt *libavcodec.AVPacket, us int64, sampleRate ffcommon.FInt) error {
+	if sampleRate <= 0 {
+		return nil
+	}
+	n := libavutil.AvRescaleQ(ffcommon.FInt64T(us), timeBaseQ, libavutil.AVRational{Num: 1, Den: sampleRate})
+	if n <= 0 {
+		return nil
+	}
+	const size = 10 // u32le skip start, u32le skip end, u8 reason start, u8 reason end
+	data := pkt.AvPacketNewSideData(libavcodec.AV_PKT_DATA_SKIP_SAMPLES, size)
+	if data == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	buf := ffcommon.ByteSliceFromByteP(data, size)
+	binary.LittleEndian.PutUint32(buf[0:], uint32(n))
+	binary.LittleEndian.PutUint32(buf[4:], 0)
+	buf[8], buf[9] = 0, 0
+	return nil
+}
+
+// head re-encodes the video between the cut point and the next keyframe.
+type head struct {
+	stream   int
+	tb       libavutil.AVRational
+	dec, enc *libavcodec.AVCodecContext
+	frame    *libavutil.AVFrame
+	pkt      *libavcodec.AVPacket
+	// from and to bound the kept frames in stream time base
+	from, to ffcommon.FInt64T
+	// nalSize is the NAL unit length size of the source, 0 for start
+	// codes, and resume the parameter sets of the source in its format,
+	// put back in front of the first copied keyframe
+	nalSize int
+	resume  []byte
+}
+
+// openHead prepares the re-encoding of the head. It returns why when the
+// head cannot be spliced into the copied stream.
+func (c *cutter) openHead() (warning string, err error) {
+	ist := c.ic.GetStream(ffcommon.FUnsignedInt(c.video))
+	par := ist.Codecpar
+	name := libavcodec.AvcodecGetName(par.CodecId)
+	h := &head{
+		stream: c.video,
+		tb:     ist.TimeBase,
+		from:   fromUs(c.start, ist.TimeBase),
+		to:     math.MaxInt64,
+	}
+	if to := min(c.next, c.end); to != math.MaxInt64 {
+		h.to = fromUs(to, ist.TimeBase)
+	}
+	c.head = h
+
+	src := extradata(par.Extradata, par.ExtradataSize)
+	nal := par.CodecId == libavcodec.AV_CODEC_ID_H264 || par.CodecId == libavcodec.AV_CODEC_ID_HEVC
+	if nal {
+		// the decoder keeps the parameter sets of the head until the copied
+		// stream sends its own again
+		switch h.nalSize = nalLengthSize(par.CodecId, src); {
+		case h.nalSize > 0:
+			ps := parameterSets(par.CodecId, src)
+			if len(ps) == 0 {
+				return fmt.Sprintf("no parameter sets in the %s extradata", name), nil
+			}
+			if h.resume, err = lengthPrefixed(ps, h.nalSize); err != nil {
+				return err.Error(), nil
+			}
+		case splitAnnexB(src) != nil:
+			h.resume = bytes.Clone(src)
+		}
+	}
+
+	dec := libavcodec.AvcodecFindDecoder(par.CodecId)
+	enc := libavcodec.AvcodecFindEncoder(par.CodecId)
+	if dec == nil || enc == nil {
+		return fmt.Sprintf("no decoder and encoder for %s", name), nil
+	}
+	h.dec = dec.AvcodecAllocContext3()
+	h.enc = enc.AvcodecAllocContext3()
+	h.frame = libavutil.AvFrameAlloc()
+	h.pkt = libavcodec.AvPacketAlloc()
+	if h.dec == nil || h.enc == nil || h.frame == nil || h.pkt == nil {
+		return "", libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := h.dec.AvcodecParametersToContext(par); ret < 0 {
+		return "", fmt.Errorf("edit: decoder parameters: %w", libavutil.ErrorFromCode(ret))
+	}
+	h.dec.PktTimebase = ist.TimeBase
+	if ret := h.dec.AvcodecOpen2(dec, nil); ret < 0 {
+		return fmt.Sprintf("cannot open the %s decoder: %v", name, libavutil.ErrorFromCode(ret)), nil
+	}
+
+	e := h.enc
+	e.Width, e.Height = par.Width, par.Height
+	e.PixFmt = libavutil.AVPixelFormat(par.Format)
+	e.SampleAspectRatio = par.SampleAspectRatio
+	e.ColorRange, e.ColorPrimaries, e.ColorTrc = par.ColorRange, par.ColorPrimaries, par.ColorTrc
+	e.Colorspace, e.ChromaSampleLocation = par.ColorSpace, par.ChromaLocation
+	e.TimeBase = ist.TimeBase
+	e.Framerate = ist.AvgFrameRate
+	e.BitRate = par.BitRate
+	e.Profile = par.Profile
+	// without B-frames decoding timestamps follow presentation order
+	e.MaxBFrames = 0
+	// H.264 and HEVC encoders repeat their parameter sets in band without
+	// a global header
+	globalHeader := !nal && c.out.Ctx.Oformat.Flags&libavformat.AVFMT_GLOBALHEADER != 0
+	if globalHeader {
+		e.Flags |= libavcodec.AV_CODEC_FLAG_GLOBAL_HEADER
+	}
+	if ret := e.AvcodecOpen2(enc, nil); ret < 0 {
+		return fmt.Sprintf("cannot open the %s encoder: %v", name, libavutil.ErrorFromCode(ret)), nil
+	}
+	// the muxer only writes the extradata of the copied stream, so the
+	// head must not depend on its own
+	if globalHeader && len(src) > 0 && !bytes.Equal(src, extradata(e.Extradata, e.ExtradataSize)) {
+		return fmt.Sprintf("the %s encoder does not reproduce the extradata of the source", name), nil
+	}
+	return "", nil
+}
+
+func extradata(data *ffcommon.FUint8T, size ffcommon.FInt) []byte {
+	if data == nil || size <= 0 {
+		return nil
+	}
+	return ffcommon.ByteSliceFromByteP(data, int(size))
+}
+
+func packetData(pkt *libavcodec.AVPacket) []byte {
+	if pkt.Data == nil || pkt.Size <= 0 {
+		return nil
+	}
+	return ffcommon.ByteSliceFromByteP(pkt.Data, int(pkt.Size))
+}
+
+// setData replaces the data of pkt, keeping its timestamps, flags and side
+// data.
+func setData(pkt *libavcodec.AVPacket, data []byte) error {
+	p := libavcodec.AvPacketAlloc()
+	if p == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&p)
+	if ret := p.AvNewPacket(ffcommon.FInt(len(data))); ret < 0 {
+		return fmt.Errorf("edit: %w", libavutil.ErrorFromCode(ret))
+	}
+	copy(ffcommon.ByteSliceFromByteP(p.Data, len(data)), data)
+	if ret := libavcodec.AvPacketCopyProps(p, pkt); ret < 0 {
+		return fmt.Errorf("edit: copy packet properties: %w", libavutil.ErrorFromCode(ret))
+	}
+	pkt.AvPacketUnref()
+	libavcodec.AvPacketMoveRef(pkt, p)
+	return nil
+}
+
+func (c *cutter) closeHead() {
+	h := c.head
+	if h == nil {
+		return
+	}
+	libavcodec.AvcodecFreeContext(&h.dec)
+	libavcodec.AvcodecFreeContext(&h.enc)
+	libavutil.AvFrameFree(&h.frame)
+	libavcodec.AvPacketFree(&h.pkt)
+	c.head = nil
+}
+
+// decode feeds a packet before the next keyframe to the decoder and
+// encodes the frames that fall inside the cut.
+func (h *head) decode(pkt *libavcodec.AVPacket, c *cutter) error {
+	if ret := h.dec.AvcodecSendPacket(pkt); ret < 0 {
+		return fmt.Errorf("edit: decode: %w", libavutil.ErrorFromCode(ret))
+	}
+	return h.drainDecoder(c)
+}
+
+func (h *head) drainDecoder(c *cutter) error {
+	for {
+		err := libavutil.ErrorFromCode(h.dec.AvcodecReceiveFrame(h.frame))
+		if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("edit: decode: %w", err)
+		}
+		pts := h.frame.BestEffortTimestamp
+		if pts != libavutil.AV_NOPTS_VALUE && pts >= h.from && pts < h.to {
+			h.frame.Pts = pts
+			h.frame.PictType = libavutil.AV_PICTURE_TYPE_NONE
+			ret := h.enc.AvcodecSendFrame(h.frame)
+			h.frame.AvFrameUnref()
+			if ret < 0 {
+				return fmt.Errorf("edit: encode: %w", libavutil.ErrorFromCode(ret))
+			}
+			if err = h.drainEncoder(c); err != nil {
+				return err
+			}
+			continue
+		}
+		h.frame.AvFrameUnref()
+	}
+}
+
+func (h *head) drainEncoder(c *cutter) error {
+	for {
+		err := libavutil.ErrorFromCode(h.enc.AvcodecReceivePacket(h.pkt))
+		if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("edit: encode: %w", err)
+		}
+		if h.nalSize > 0 {
+			if err = h.lengthPrefix(); err != nil {
+				return err
+			}
+		}
+		h.pkt.StreamIndex = ffcommon.FUnsignedInt(h.stream)
+		h.pkt.AvPacketRescaleTs(h.enc.TimeBase, h.tb)
+		if h.pkt.Pts != libavutil.AV_NOPTS_VALUE {
+			h.pkt.Dts = h.pkt.Pts - c.nextDelay
+		}
+		if err = c.write(h.pkt, h.tb); err != nil {
+			return err
+		}
+	}
+}
+
+// lengthPrefix rewrites the start codes of the encoded packet as NAL unit
+// lengths of the size the source uses.
+func (h *head) lengthPrefix() error {
+	nals := splitAnnexB(packetData(h.pkt))
+	if nals == nil {
+		return nil
+	}
+	data, err := lengthPrefixed(nals, h.nalSize)
+	if err != nil {
+		return err
+	}
+	return setData(h.pkt, data)
+}
+
+// flush drains the decoder and the encoder once the next keyframe is
+// reached.
+func (h *head) flush(c *cutter) error {
+	if ret := h.dec.AvcodecSendPacket(nil); ret < 0 && ret != libavutil.AVERROR_EOF {
+		return fmt.Errorf("edit: decode: %w", libavutil.ErrorFromCode(ret))
+	}
+	if err := h.drainDecoder(c); err != nil {
+		return err
+	}
+	if ret := h.enc.AvcodecSendFrame(nil); ret < 0 && ret != libavutil.AVERROR_EOF {
+		return fmt.Errorf("edit: encode: %w", libavutil.ErrorFromCode(ret))
+	}
+	return h.drainEncoder(c)
+}
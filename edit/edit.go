@@ -0,0 +1,60 @@
+// Package edit cuts and joins media files by stream copy.
+package edit
+
+import (
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+var timeBaseQ = libavutil.AVRational{Num: 1, Den: libavutil.AV_TIME_BASE}
+
+// toUs converts ts from tb to AV_TIME_BASE units.
+func toUs(ts ffcommon.FInt64T, tb libavutil.AVRational) int64 {
+	return int64(libavutil.AvRescaleQ(ts, tb, timeBaseQ))
+}
+
+// fromUs converts us from AV_TIME_BASE units to tb.
+func fromUs(us int64, tb libavutil.AVRational) ffcommon.FInt64T {
+	return libavutil.AvRescaleQ(ffcommon.FInt64T(us), timeBaseQ, tb)
+}
+
+// packetTime returns the presentation time of pkt in AV_TIME_BASE units,
+// falling back to the decoding time.
+func packetTime(pkt *libavcodec.AVPacket, tb libavutil.AVRational) int64 {
+	if pkt.Pts != libavutil.AV_NOPTS_VALUE {
+		return toUs(pkt.Pts, tb)
+	}
+	return toUs(pkt.Dts, tb)
+}
+
+// shift moves the timestamps of pkt back by us.
+func shift(pkt *libavcodec.AVPacket, us int64, tb libavutil.AVRational) {
+	off := fromUs(us, tb)
+	if pkt.Pts != libavutil.AV_NOPTS_VALUE {
+		pkt.Pts -= off
+	}
+	if pkt.Dts != libavutil.AV_NOPTS_VALUE {
+		pkt.Dts -= off
+	}
+}
+
+// frameDuration returns the duration of one frame of st in AV_TIME_BASE
+// units, from the frame size of audio or the frame rate of video, 0 if
+// unknown.
+func frameDuration(st *libavformat.AVStream) int64 {
+	par := st.Codecpar
+	if par.CodecType == libavutil.AVMEDIA_TYPE_AUDIO {
+		if par.FrameSize > 0 && par.SampleRate > 0 {
+			return toUs(ffcommon.FInt64T(par.FrameSize), libavutil.AVRational{Num: 1, Den: par.SampleRate})
+		}
+		return 0
+	}
+	for _, r := range []libavutil.AVRational{st.AvgFrameRate, st.RFrameRate} {
+		if r.Num > 0 && r.Den > 0 {
+			return toUs(1, libavutil.AVRational{Num: r.Den, Den: r.Num})
+		}
+	}
+	return 0
+}
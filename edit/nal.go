@@ -0,0 +1,129 @@
+package edit
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+)
+
+// nalLengthSize returns the size of the NAL unit lengths of H.264 or HEVC
+// with avcC or hvcC extradata, 0 for start codes and other codecs.
+func nalLengthSize(codec libavcodec.AVCodecID, extra []byte) int {
+	if len(extra) == 0 || extra[0] != 1 {
+		return 0
+	}
+	switch codec {
+	case libavcodec.AV_CODEC_ID_H264:
+		if len(extra) >= 7 {
+			return int(extra[4]&3) + 1
+		}
+	case libavcodec.AV_CODEC_ID_HEVC:
+		if len(extra) >= 23 {
+			return int(extra[21]&3) + 1
+		}
+	}
+	return 0
+}
+
+// parameterSets returns the NAL units of avcC or hvcC extradata, nil if it
+// is malformed.
+func parameterSets(codec libavcodec.AVCodecID, extra []byte) [][]byte {
+	var nals [][]byte
+	// next reads a NAL unit with a 16-bit length
+	next := func() bool {
+		if len(extra) < 2 {
+			return false
+		}
+		n := int(extra[0])<<8 | int(extra[1])
+		if len(extra) < 2+n {
+			return false
+		}
+		nals = append(nals, extra[2:2+n])
+		extra = extra[2+n:]
+		return true
+	}
+	switch codec {
+	case libavcodec.AV_CODEC_ID_H264:
+		if len(extra) < 6 {
+			return nil
+		}
+		sps := int(extra[5] & 0x1f)
+		extra = extra[6:]
+		for range sps {
+			if !next() {
+				return nil
+			}
+		}
+		if len(extra) < 1 {
+			return nil
+		}
+		pps := int(extra[0])
+		extra = extra[1:]
+		for range pps {
+			if !next() {
+				return nil
+			}
+		}
+	case libavcodec.AV_CODEC_ID_HEVC:
+		if len(extra) < 23 {
+			return nil
+		}
+		arrays := int(extra[22])
+		extra = extra[23:]
+		for range arrays {
+			if len(extra) < 3 {
+				return nil
+			}
+			n := int(extra[1])<<8 | int(extra[2])
+			extra = extra[3:]
+			for range n {
+				if !next() {
+					return nil
+				}
+			}
+		}
+	default:
+		return nil
+	}
+	return nals
+}
+
+// splitAnnexB returns the NAL units of data in the Annex B byte stream
+// format, nil if it does not start with a start code.
+func splitAnnexB(data []byte) [][]byte {
+	startCode := []byte{0, 0, 1}
+	i := bytes.Index(data, startCode)
+	if i < 0 || len(bytes.Trim(data[:i], "\x00")) > 0 {
+		return nil
+	}
+	var nals [][]byte
+	data = data[i+3:]
+	for len(data) > 0 {
+		end := bytes.Index(data, startCode)
+		if end < 0 {
+			end = len(data)
+		}
+		// drop the leading zero of 4-byte start codes and trailing_zero_8bits
+		if nal := bytes.TrimRight(data[:end], "\x00"); len(nal) > 0 {
+			nals = append(nals, nal)
+		}
+		data = data[min(end+3, len(data)):]
+	}
+	return nals
+}
+
+// lengthPrefixed writes nals each preceded by its size in size bytes.
+func lengthPrefixed(nals [][]byte, size int) ([]byte, error) {
+	var b []byte
+	for _, nal := range nals {
+		if size < 4 && len(nal) >= 1<<(8*size) {
+			return nil, fmt.Errorf("edit: NAL unit of %d bytes does not fit %d byte lengths", len(nal), size)
+		}
+		for i := size - 1; i >= 0; i-- {
+			b = append(b, byte(len(nal)>>(8*i)))
+		}
+		b = append(b, nal...)
+	}
+	return b, nil
+}
@@ -0,0 +1,117 @@
+package edit
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+)
+
+var (
+	// High profile avcC with one SPS and one PPS
+	avcC = []byte{
+		0x01, 0x64, 0x00, 0x1f, 0xff, 0xe1,
+		0x00, 0x04, 0x67, 0x64, 0x00, 0x1f,
+		0x01, 0x00, 0x02, 0x68, 0xee,
+	}
+	// hvcC with a VPS, an SPS and a PPS array
+	hvcC = append([]byte{
+		0x01, 0x01, 0x60, 0x00, 0x00, 0x00, 0x90, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x5d, 0xf0, 0x00, 0xfc, 0xfd, 0xf8, 0xf8, 0x00, 0x00, 0x0f, 0x03,
+	},
+		0x20, 0x00, 0x01, 0x00, 0x02, 0x40, 0x01,
+		0x21, 0x00, 0x01, 0x00, 0x02, 0x42, 0x01,
+		0x22, 0x00, 0x01, 0x00, 0x02, 0x44, 0x01,
+	)
+	annexB = []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x64, 0x00, 0x1f, 0x00, 0x00, 0x01, 0x68, 0xee}
+)
+
+func TestNALLengthSize(t *testing.T) {
+	twoBytes := bytes.Clone(avcC)
+	twoBytes[4] = 0xfd
+	for _, tc := range []struct {
+		name  string
+		codec libavcodec.AVCodecID
+		extra []byte
+		want  int
+	}{
+		{"avcC", libavcodec.AV_CODEC_ID_H264, avcC, 4},
+		{"avcC with 2 byte lengths", libavcodec.AV_CODEC_ID_H264, twoBytes, 2},
+		{"hvcC", libavcodec.AV_CODEC_ID_HEVC, hvcC, 4},
+		{"annex b", libavcodec.AV_CODEC_ID_H264, annexB, 0},
+		{"no extradata", libavcodec.AV_CODEC_ID_H264, nil, 0},
+		{"short hvcC", libavcodec.AV_CODEC_ID_HEVC, hvcC[:20], 0},
+		{"other codec", libavcodec.AV_CODEC_ID_MPEG4, avcC, 0},
+	} {
+		if got := nalLengthSize(tc.codec, tc.extra); got != tc.want {
+			t.Errorf("%s: nalLengthSize() = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParameterSets(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		codec libavcodec.AVCodecID
+		extra []byte
+		want  [][]byte
+	}{
+		{"avcC", libavcodec.AV_CODEC_ID_H264, avcC, [][]byte{{0x67, 0x64, 0x00, 0x1f}, {0x68, 0xee}}},
+		{"hvcC", libavcodec.AV_CODEC_ID_HEVC, hvcC, [][]byte{{0x40, 0x01}, {0x42, 0x01}, {0x44, 0x01}}},
+		{"truncated avcC", libavcodec.AV_CODEC_ID_H264, avcC[:len(avcC)-1], nil},
+		{"avcC without PPS count", libavcodec.AV_CODEC_ID_H264, avcC[:12], nil},
+		{"truncated hvcC", libavcodec.AV_CODEC_ID_HEVC, hvcC[:len(hvcC)-3], nil},
+		{"other codec", libavcodec.AV_CODEC_ID_VP9, avcC, nil},
+	} {
+		if got := parameterSets(tc.codec, tc.extra); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: parameterSets() = % x, want % x", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSplitAnnexB(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want [][]byte
+	}{
+		{"parameter sets", annexB, [][]byte{{0x67, 0x64, 0x00, 0x1f}, {0x68, 0xee}}},
+		{
+			"trailing zeros",
+			[]byte{0x00, 0x00, 0x01, 0x65, 0x88, 0x00, 0x00, 0x00, 0x00, 0x01, 0x06, 0x05, 0x00, 0x00},
+			[][]byte{{0x65, 0x88}, {0x06, 0x05}},
+		},
+		{"empty NAL unit", []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x09, 0xf0}, [][]byte{{0x09, 0xf0}}},
+		{"length-prefixed", []byte{0x00, 0x00, 0x00, 0x02, 0x09, 0xf0}, nil},
+		{"data before the start code", []byte{0x09, 0x00, 0x00, 0x01, 0x09, 0xf0}, nil},
+		{"empty", nil, nil},
+	} {
+		if got := splitAnnexB(tc.data); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: splitAnnexB() = % x, want % x", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLengthPrefixed(t *testing.T) {
+	nals := [][]byte{{0x67, 0x64}, {0x68}}
+	for _, tc := range []struct {
+		size int
+		want []byte
+	}{
+		{4, []byte{0x00, 0x00, 0x00, 0x02, 0x67, 0x64, 0x00, 0x00, 0x00, 0x01, 0x68}},
+		{2, []byte{0x00, 0x02, 0x67, 0x64, 0x00, 0x01, 0x68}},
+		{1, []byte{0x02, 0x67, 0x64, 0x01, 0x68}},
+	} {
+		got, err := lengthPrefixed(nals, tc.size)
+		if err != nil || !bytes.Equal(got, tc.want) {
+			t.Errorf("lengthPrefixed(%d) = % x, %v, want % x", tc.size, got, err, tc.want)
+		}
+	}
+	if _, err := lengthPrefixed([][]byte{make([]byte, 256)}, 1); err == nil {
+		t.Error("a 256 byte NAL unit fits 1 byte lengths")
+	}
+	if got, err := lengthPrefixed([][]byte{make([]byte, 256)}, 2); err != nil || len(got) != 258 {
+		t.Errorf("lengthPrefixed of 256 bytes with 2 byte lengths = %d bytes, %v", len(got), err)
+	}
+}
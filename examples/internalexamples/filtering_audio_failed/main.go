@@ -1,112 +1,25 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"unsafe"
 
+	"github.com/dwdcth/ffmpeg-go/v7/examples"
 	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/filter"
 	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
-	"github.com/dwdcth/ffmpeg-go/v7/libavfilter"
 	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
 	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
 )
 
-func main0() (ret ffcommon.FInt) {
-	var packet libavcodec.AVPacket
-	frame := libavutil.AvFrameAlloc()
-	filt_frame := libavutil.AvFrameAlloc()
-
-	if frame == nil || filt_frame == nil {
-		fmt.Println("Could not allocate frame")
-		os.Exit(1)
-	}
-	if len(os.Args) != 2 {
-		fmt.Printf("Usage: %s file | %s\n", os.Args[0], player)
-		os.Exit(1)
-	}
-
-	ret = open_input_file(os.Args[1])
-	if ret < 0 {
-		goto end
-	}
-
-	ret = init_filters(filter_descr)
-	if ret < 0 {
-		goto end
-	}
-
-	/* read all packets */
-	for {
-		ret = fmt_ctx.AvReadFrame(&packet)
-		if ret < 0 {
-			break
-		}
-
-		if int32(packet.StreamIndex) == audio_stream_index {
-			ret = dec_ctx.AvcodecSendPacket(&packet)
-			if ret < 0 {
-				libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while sending a packet to the decoder\n")
-				break
-			}
-
-			for ret >= 0 {
-				ret = dec_ctx.AvcodecReceiveFrame(frame)
-				if ret == -libavutil.EAGAIN || ret == libavutil.AVERROR_EOF {
-					break
-				} else if ret < 0 {
-					libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while receiving a frame from the decoder\n")
-					goto end
-				}
-
-				if ret >= 0 {
-					/* push the audio data from decoded frame into the filtergraph */
-					if buffersrc_ctx.AvBuffersrcAddFrameFlags(frame, libavfilter.AV_BUFFERSRC_FLAG_KEEP_REF) < 0 {
-						libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while feeding the audio filtergraph\n")
-						break
-					}
-
-					/* pull filtered audio from the filtergraph */
-					for {
-						ret = buffersink_ctx.AvBuffersinkGetFrame(filt_frame)
-						if ret == -libavutil.EAGAIN || ret == libavutil.AVERROR_EOF {
-							break
-						}
-						if ret < 0 {
-							goto end
-						}
-						print_frame(filt_frame)
-						filt_frame.AvFrameUnref()
-					}
-					frame.AvFrameUnref()
-				}
-			}
-		}
-		packet.AvPacketUnref()
-	}
-end:
-	libavfilter.AvfilterGraphFree(&filter_graph)
-	libavcodec.AvcodecFreeContext(&dec_ctx)
-	libavformat.AvformatCloseInput(&fmt_ctx)
-	libavutil.AvFrameFree(&frame)
-	libavutil.AvFrameFree(&filt_frame)
-	if ret < 0 && ret != libavutil.AVERROR_EOF {
-		fmt.Printf("Error occurred: %s\n", libavutil.AvErr2str(ret))
-		os.Exit(1)
-	}
-
-	os.Exit(0)
-	return 0
-}
-
+// go run ./examples/internalexamples/filtering_audio_failed -file ./resources/big_buck_bunny.mp4 | ffplay -f s16le -ar 8000 -ac 1 -
 var filter_descr = "aresample=8000,aformat=sample_fmts=s16:channel_layouts=mono"
-var player = "ffplay -f s16le -ar 8000 -ac 1 -"
 
 var fmt_ctx *libavformat.AVFormatContext
 var dec_ctx *libavcodec.AVCodecContext
-var buffersink_ctx *libavfilter.AVFilterContext
-var buffersrc_ctx *libavfilter.AVFilterContext
-var filter_graph *libavfilter.AVFilterGraph
 var audio_stream_index ffcommon.FInt = -1
 
 func open_input_file(filename string) ffcommon.FInt {
@@ -150,165 +63,154 @@ func open_input_file(filename string) ffcommon.FInt {
 	return 0
 }
 
-func init_filters(filters_descr string) ffcommon.FInt {
-	var args string
-	var args0 [512]byte
-	var ret ffcommon.FInt = 0
-	abuffersrc := libavfilter.AvfilterGetByName("abuffer")
-	abuffersink := libavfilter.AvfilterGetByName("abuffersink")
-	outputs := libavfilter.AvfilterInoutAlloc()
-	inputs := libavfilter.AvfilterInoutAlloc()
-	out_sample_fmts := [...]libavutil.AVSampleFormat{libavutil.AV_SAMPLE_FMT_S16, -1}
-	out_channel_layouts := [...]ffcommon.FInt64T{libavutil.AV_CH_LAYOUT_MONO, -1}
-	out_sample_rates := [...]ffcommon.FInt{8000, -1}
-	var outlink *libavfilter.AVFilterLink
-	time_base := fmt_ctx.GetStream(uint32(audio_stream_index)).TimeBase
-	f := ""
-	ii := int64(-1)
-
-	filter_graph = libavfilter.AvfilterGraphAlloc()
-	if outputs == nil || inputs == nil || filter_graph == nil {
-		ret = -libavutil.ENOMEM
-		goto end
-	}
-
-	/* buffer audio source: the decoded frames from the decoder will be inserted here. */
+func init_filters(filters_descr string) (*filter.Graph, error) {
+	st := fmt_ctx.GetStream(uint32(audio_stream_index))
 	if dec_ctx.ChannelLayout == 0 {
 		dec_ctx.ChannelLayout = uint64(libavutil.AvGetDefaultChannelLayout(dec_ctx.Channels))
 	}
-	args = fmt.Sprintf("time_base=%d/%d:sample_rate=%d:sample_fmt=%s:channel_layout=0x0%x",
-		time_base.Num, time_base.Den, dec_ctx.SampleRate,
-		libavutil.AvGetSampleFmtName(dec_ctx.SampleFmt), dec_ctx.ChannelLayout)
-	//fmt.Println("args = ", args)
-	copy(args0[:], args[:])
-	ret = libavfilter.AvfilterGraphCreateFilter(&buffersrc_ctx, abuffersrc, "in",
-		args, uintptr(0), filter_graph)
-	if ret < 0 {
-		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot create audio buffer source\n")
-		goto end
+	graph, err := filter.NewGraph(filters_descr,
+		[]filter.Input{{
+			MediaType:     libavutil.AVMEDIA_TYPE_AUDIO,
+			TimeBase:      st.TimeBase,
+			SampleRate:    dec_ctx.SampleRate,
+			SampleFmt:     dec_ctx.SampleFmt,
+			ChannelLayout: dec_ctx.ChannelLayout,
+		}},
+		[]filter.Output{{
+			MediaType:      libavutil.AVMEDIA_TYPE_AUDIO,
+			SampleFmts:     []libavutil.AVSampleFormat{libavutil.AV_SAMPLE_FMT_S16},
+			SampleRates:    []ffcommon.FInt{8000},
+			ChannelLayouts: []ffcommon.FInt64T{libavutil.AV_CH_LAYOUT_MONO},
+		}})
+	if err != nil {
+		return nil, err
 	}
 
-	/* buffer audio sink: to terminate the filter chain. */
-	ret = libavfilter.AvfilterGraphCreateFilter(&buffersink_ctx, abuffersink, "out",
-		"", uintptr(0), filter_graph)
-	if ret < 0 {
-		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot create audio buffer sink\n")
-		goto end
+	/* Print summary of the sink buffer */
+	out, err := graph.OutputFormat("out")
+	if err != nil {
+		graph.Close()
+		return nil, err
 	}
-
-	ret = libavutil.AvOptSetIntList(uintptr(unsafe.Pointer(buffersink_ctx)), "sample_fmts", uintptr(unsafe.Pointer(&out_sample_fmts)), 4, uint64(uintptr(ii)),
-		libavutil.AV_OPT_SEARCH_CHILDREN)
-	if ret < 0 {
-		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot set output sample format\n")
-		goto end
+	var layout [64]byte
+	libavutil.AvGetChannelLayoutString((*byte)(unsafe.Pointer(&layout)), int32(len(layout)), -1, out.ChannelLayout)
+	f := libavutil.AvGetSampleFmtName(libavutil.AVSampleFormat(out.Format))
+	if f == "" {
+		f = "?"
 	}
+	libavutil.AvLog(uintptr(0), libavutil.AV_LOG_INFO, "Output: srate:%sHz fmt:%s chlayout:%s\n",
+		fmt.Sprint(out.SampleRate),
+		f,
+		ffcommon.StringFromPtr(uintptr(unsafe.Pointer(&layout))))
+	return graph, nil
+}
 
-	ret = libavutil.AvOptSetIntList(uintptr(unsafe.Pointer(buffersink_ctx)), "channel_layouts", uintptr(unsafe.Pointer(&out_channel_layouts)), 8, uint64(uintptr(ii)),
-		libavutil.AV_OPT_SEARCH_CHILDREN)
-	if ret < 0 {
-		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot set output channel layout\n")
-		goto end
-	}
+func print_frame(frame *libavutil.AVFrame) {
+	n := frame.NbSamples * libavutil.AvGetChannelLayoutNbChannels(frame.ChannelLayout)
+	os.Stdout.Write(ffcommon.ByteSliceFromByteP(frame.Data[0], int(2*n)))
+}
 
-	ret = libavutil.AvOptSetIntList(uintptr(unsafe.Pointer(buffersink_ctx)), "sample_rates", uintptr(unsafe.Pointer(&out_sample_rates)), 4, uint64(uintptr(ii)),
-		libavutil.AV_OPT_SEARCH_CHILDREN)
-	if ret < 0 {
-		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot set output sample rate\n")
-		goto end
+// drain pulls every frame the graph can produce right now.
+func drain(graph *filter.Graph, filt_frame *libavutil.AVFrame) error {
+	for {
+		err := graph.Pull("out", filt_frame)
+		if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		print_frame(filt_frame)
+		filt_frame.AvFrameUnref()
 	}
+}
 
-	/*
-	 * Set the endpoints for the filter graph. The filter_graph will
-	 * be linked to the graph described by filters_descr.
-	 */
-
-	/*
-	 * The buffer source output must be connected to the input pad of
-	 * the first filter described by filters_descr; since the first
-	 * filter input label is not specified, it is set to "in" by
-	 * default.
-	 */
-	outputs.Name = ffcommon.UintPtrFromString(libavutil.AvStrdup("in"))
-	// outputs.Name = ffcommon.UintPtrFromString("in")
-	outputs.FilterCtx = buffersrc_ctx
-	outputs.PadIdx = 0
-	outputs.Next = nil
+func main0(filename string) (ret ffcommon.FInt) {
+	packet := libavcodec.AvPacketAlloc()
+	frame := libavutil.AvFrameAlloc()
+	filt_frame := libavutil.AvFrameAlloc()
+	var graph *filter.Graph
+	var err error
 
-	/*
-	 * The buffer sink input must be connected to the output pad of
-	 * the last filter described by filters_descr; since the last
-	 * filter output label is not specified, it is set to "out" by
-	 * default.
-	 */
-	inputs.Name = ffcommon.UintPtrFromString(libavutil.AvStrdup("out"))
-	// inputs.Name = ffcommon.UintPtrFromString("out")
-	inputs.FilterCtx = buffersink_ctx
-	inputs.PadIdx = 0
-	inputs.Next = nil
+	if frame == nil || filt_frame == nil || packet == nil {
+		fmt.Fprintln(os.Stderr, "Could not allocate frame or packet")
+		os.Exit(1)
+	}
 
-	// ret = filter_graph.AvfilterGraphParsePtr(filters_descr,
-	// 	&inputs, &outputs, uintptr(0))
-	fmt.Println("filters_descr = ", filters_descr)
-	ret = filter_graph.AvfilterGraphParsePtr(filters_descr,
-		&inputs, &outputs, uintptr(0))
+	ret = open_input_file(filename)
 	if ret < 0 {
 		goto end
 	}
-	ret = filter_graph.AvfilterGraphConfig(uintptr(0))
-	if ret < 0 {
+	graph, err = init_filters(filter_descr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		ret = -1
 		goto end
 	}
 
-	/* Print summary of the sink buffer
-	 * Note: args buffer is reused to store channel layout string */
-	outlink = buffersink_ctx.GetInput(0)
-	libavutil.AvGetChannelLayoutString((*byte)(unsafe.Pointer(&args0)), int32(len(args0)), -1, outlink.ChannelLayout)
-	f = libavutil.AvGetSampleFmtName(libavutil.AVSampleFormat(outlink.Format))
-	if f == "" {
-		f = "?"
-	}
-	libavutil.AvLog(uintptr(0), libavutil.AV_LOG_INFO, "Output: srate:%sHz fmt:%s chlayout:%s\n",
-		fmt.Sprint(outlink.SampleRate),
-		f,
-		ffcommon.StringFromPtr(uintptr(unsafe.Pointer(&args0))))
+	/* read all packets */
+	for {
+		ret = fmt_ctx.AvReadFrame(packet)
+		if ret < 0 {
+			break
+		}
 
-end:
-	libavfilter.AvfilterInoutFree(&inputs)
-	libavfilter.AvfilterInoutFree(&outputs)
+		if packet.StreamIndex == uint32(audio_stream_index) {
+			ret = dec_ctx.AvcodecSendPacket(packet)
+			if ret < 0 {
+				libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while sending a packet to the decoder\n")
+				break
+			}
 
-	return ret
-}
+			for ret >= 0 {
+				ret = dec_ctx.AvcodecReceiveFrame(frame)
+				if ret == -libavutil.EAGAIN || ret == libavutil.AVERROR_EOF {
+					break
+				} else if ret < 0 {
+					libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while receiving a frame from the decoder\n")
+					goto end
+				}
 
-func print_frame(frame *libavutil.AVFrame) {
-	n := frame.NbSamples * libavutil.AvGetChannelLayoutNbChannels(frame.ChannelLayout)
-	p := uintptr(unsafe.Pointer(frame.Data[0]))
-	p_end := p + uintptr(2*n)
+				/* push the audio data from decoded frame into the filtergraph */
+				if err = graph.Push("in", frame); err != nil {
+					libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while feeding the audio filtergraph\n")
+					break
+				}
 
-	for p < p_end {
-		fmt.Print(string([]byte{byte(*(*ffcommon.FInt16T)(unsafe.Pointer(p)) & 0xff), byte(*(*ffcommon.FInt16T)(unsafe.Pointer(p)) >> 8 & 0xff)}))
-		p += 2
+				/* pull filtered audio from the filtergraph */
+				if err = drain(graph, filt_frame); err != nil {
+					ret = -1
+					goto end
+				}
+				frame.AvFrameUnref()
+			}
+		}
+		packet.AvPacketUnref()
 	}
-	// fflush(stdout);
-}
 
-func main() {
-	os.Setenv("Path", os.Getenv("Path")+";./lib")
-	ffcommon.SetAvutilPath("avutil-56.dll")
-	ffcommon.SetAvcodecPath("avcodec-58.dll")
-	ffcommon.SetAvdevicePath("avdevice-58.dll")
-	ffcommon.SetAvfilterPath("avfilter-7.dll")
-	ffcommon.SetAvformatPath("avformat-58.dll")
-	ffcommon.SetAvpostprocPath("postproc-55.dll")
-	ffcommon.SetAvswresamplePath("swresample-3.dll")
-	ffcommon.SetAvswscalePath("swscale-5.dll")
+	/* flush the filtergraph */
+	if graph.Push("in", nil) == nil {
+		drain(graph, filt_frame)
+	}
 
-	genDir := "./out"
-	_, err := os.Stat(genDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			os.Mkdir(genDir, 0777) //  Everyone can read write and execute
-		}
+end:
+	if graph != nil {
+		graph.Close()
 	}
+	libavcodec.AvcodecFreeContext(&dec_ctx)
+	libavformat.AvformatCloseInput(&fmt_ctx)
+	libavutil.AvFrameFree(&frame)
+	libavutil.AvFrameFree(&filt_frame)
+	libavcodec.AvPacketFree(&packet)
+
+	if ret < 0 && ret != libavutil.AVERROR_EOF {
+		fmt.Fprintf(os.Stderr, "Error occurred: %s\n", libavutil.AvErr2str(ret))
+		os.Exit(1)
+	}
+	return 0
+}
 
-	main0()
+func main() {
+	fileName := examples.Setup()
+	main0(*fileName)
 }
@@ -1,5 +1,202 @@
 package main
 
-func main() {
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/examples"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/filter"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// go run ./examples/internalexamples/filtering_video_failed -file ./resources/big_buck_bunny.mp4
+var filter_descr = "scale=78:24,transpose=cclock"
+
+var fmt_ctx *libavformat.AVFormatContext
+var dec_ctx *libavcodec.AVCodecContext
+var video_stream_index ffcommon.FInt = -1
+
+func open_input_file(filename string) ffcommon.FInt {
+	var ret ffcommon.FInt
+	var dec *libavcodec.AVCodec
+
+	ret = libavformat.AvformatOpenInput(&fmt_ctx, filename, nil, nil)
+	if ret < 0 {
+		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot open input file\n")
+		return ret
+	}
+
+	ret = fmt_ctx.AvformatFindStreamInfo(nil)
+	if ret < 0 {
+		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot find stream information\n")
+		return ret
+	}
+
+	/* select the video stream */
+	ret = fmt_ctx.AvFindBestStream(libavutil.AVMEDIA_TYPE_VIDEO, -1, -1, &dec, 0)
+	if ret < 0 {
+		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot find a video stream in the input file\n")
+		return ret
+	}
+	video_stream_index = ret
+
+	/* create decoding context */
+	dec_ctx = dec.AvcodecAllocContext3()
+	if dec_ctx == nil {
+		return -libavutil.ENOMEM
+	}
+	dec_ctx.AvcodecParametersToContext(fmt_ctx.GetStream(uint32(video_stream_index)).Codecpar)
+
+	/* init the video decoder */
+	ret = dec_ctx.AvcodecOpen2(dec, nil)
+	if ret < 0 {
+		libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Cannot open video decoder\n")
+		return ret
+	}
+
+	return 0
+}
+
+func init_filters(filters_descr string) (*filter.Graph, error) {
+	st := fmt_ctx.GetStream(uint32(video_stream_index))
+	return filter.NewGraph(filters_descr,
+		[]filter.Input{{
+			MediaType:         libavutil.AVMEDIA_TYPE_VIDEO,
+			TimeBase:          st.TimeBase,
+			Width:             dec_ctx.Width,
+			Height:            dec_ctx.Height,
+			PixFmt:            dec_ctx.PixFmt,
+			SampleAspectRatio: dec_ctx.SampleAspectRatio,
+		}},
+		[]filter.Output{{
+			MediaType: libavutil.AVMEDIA_TYPE_VIDEO,
+			PixFmts:   []libavutil.AVPixelFormat{libavutil.AV_PIX_FMT_GRAY8},
+		}})
+}
+
+func display_frame(frame *libavutil.AVFrame) {
+	p := unsafe.Pointer(frame.Data[0])
+	for y := ffcommon.FInt(0); y < frame.Height; y++ {
+		line := ffcommon.ByteSliceFromByteP((*byte)(p), int(frame.Width))
+		buf := make([]byte, len(line))
+		for x, v := range line {
+			buf[x] = " .-+#"[v/52]
+		}
+		fmt.Println(string(buf))
+		p = unsafe.Add(p, frame.Linesize[0])
+	}
+	fmt.Println()
+}
 
+// drain pulls every frame the graph can produce right now.
+func drain(graph *filter.Graph, filt_frame *libavutil.AVFrame) error {
+	for {
+		err := graph.Pull("out", filt_frame)
+		if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		display_frame(filt_frame)
+		filt_frame.AvFrameUnref()
+	}
+}
+
+func main0(filename string) (ret ffcommon.FInt) {
+	packet := libavcodec.AvPacketAlloc()
+	frame := libavutil.AvFrameAlloc()
+	filt_frame := libavutil.AvFrameAlloc()
+	var graph *filter.Graph
+	var err error
+
+	if frame == nil || filt_frame == nil || packet == nil {
+		fmt.Println("Could not allocate frame or packet")
+		os.Exit(1)
+	}
+
+	ret = open_input_file(filename)
+	if ret < 0 {
+		goto end
+	}
+	graph, err = init_filters(filter_descr)
+	if err != nil {
+		fmt.Println(err)
+		ret = -1
+		goto end
+	}
+
+	/* read all packets */
+	for {
+		ret = fmt_ctx.AvReadFrame(packet)
+		if ret < 0 {
+			break
+		}
+
+		if packet.StreamIndex == uint32(video_stream_index) {
+			ret = dec_ctx.AvcodecSendPacket(packet)
+			if ret < 0 {
+				libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while sending a packet to the decoder\n")
+				break
+			}
+
+			for ret >= 0 {
+				ret = dec_ctx.AvcodecReceiveFrame(frame)
+				if ret == -libavutil.EAGAIN || ret == libavutil.AVERROR_EOF {
+					break
+				} else if ret < 0 {
+					libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while receiving a frame from the decoder\n")
+					goto end
+				}
+
+				frame.Pts = frame.BestEffortTimestamp
+
+				/* push the decoded frame into the filtergraph */
+				if err = graph.Push("in", frame); err != nil {
+					libavutil.AvLog(uintptr(0), libavutil.AV_LOG_ERROR, "Error while feeding the filtergraph\n")
+					break
+				}
+
+				/* pull filtered frames from the filtergraph */
+				if err = drain(graph, filt_frame); err != nil {
+					ret = -1
+					goto end
+				}
+				frame.AvFrameUnref()
+			}
+		}
+		packet.AvPacketUnref()
+	}
+
+	/* flush the filtergraph */
+	if graph.Push("in", nil) == nil {
+		drain(graph, filt_frame)
+	}
+
+end:
+	if graph != nil {
+		graph.Close()
+	}
+	libavcodec.AvcodecFreeContext(&dec_ctx)
+	libavformat.AvformatCloseInput(&fmt_ctx)
+	libavutil.AvFrameFree(&frame)
+	libavutil.AvFrameFree(&filt_frame)
+	libavcodec.AvPacketFree(&packet)
+
+	if ret < 0 && ret != libavutil.AVERROR_EOF {
+		fmt.Printf("Error occurred: %s\n", libavutil.AvErr2str(ret))
+		os.Exit(1)
+	}
+	return 0
+}
+
+func main() {
+	fileName := examples.Setup()
+	main0(*fileName)
 }
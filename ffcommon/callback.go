@@ -1,13 +1,19 @@
 package ffcommon
 
 import (
+	"reflect"
+
 	"github.com/ebitengine/purego"
 )
 
+// NewCallback returns a C function pointer calling fn, or 0 for a nil fn,
+// including a nil func value of any type.
 func NewCallback(fn interface{}) uintptr {
 	if fn == nil {
 		return uintptr(0)
-	} else {
-		return purego.NewCallback(fn)
 	}
+	if v := reflect.ValueOf(fn); v.Kind() == reflect.Func && v.IsNil() {
+		return uintptr(0)
+	}
+	return purego.NewCallback(fn)
 }
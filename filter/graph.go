@@ -0,0 +1,366 @@
+// Package filter runs libavfilter graphs fed by buffer/abuffer sources and
+// drained through buffersink/abuffersink sinks.
+package filter
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavfilter"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Input describes the frames pushed into one labelled input of the graph.
+// Video inputs use Width, Height, PixFmt, SampleAspectRatio, FrameRate and
+// HwFramesCtx, audio inputs use SampleRate, SampleFmt and ChannelLayout.
+type Input struct {
+	Label     string
+	MediaType libavutil.AVMediaType
+	TimeBase  libavutil.AVRational
+
+	Width, Height     ffcommon.FInt
+	PixFmt            libavutil.AVPixelFormat
+	SampleAspectRatio libavutil.AVRational
+	FrameRate         libavutil.AVRational
+	HwFramesCtx       *libavutil.AVBufferRef
+
+	SampleRate    ffcommon.FInt
+	SampleFmt     libavutil.AVSampleFormat
+	ChannelLayout ffcommon.FUint64T
+}
+
+// Output describes one labelled output of the graph. The format lists are
+// optional constraints for the sink, empty lists accept any format.
+type Output struct {
+	Label     string
+	MediaType libavutil.AVMediaType
+
+	PixFmts []libavutil.AVPixelFormat
+
+	SampleFmts     []libavutil.AVSampleFormat
+	SampleRates    []ffcommon.FInt
+	ChannelLayouts []ffcommon.FInt64T
+	// FrameSize makes an audio sink return frames of exactly this many
+	// samples, the last frame at EOF is padded.
+	FrameSize ffcommon.FUnsigned
+}
+
+// Format is the output format negotiated when the graph was configured.
+type Format struct {
+	MediaType libavutil.AVMediaType
+	// Format is an AVPixelFormat for video and an AVSampleFormat for audio.
+	Format            ffcommon.FInt
+	TimeBase          libavutil.AVRational
+	FrameRate         libavutil.AVRational
+	Width, Height     ffcommon.FInt
+	SampleAspectRatio libavutil.AVRational
+	SampleRate        ffcommon.FInt
+	Channels          ffcommon.FInt
+	ChannelLayout     ffcommon.FUint64T
+}
+
+// Graph is a configured filter graph with named buffer sources and sinks.
+type Graph struct {
+	graph   *libavfilter.AVFilterGraph
+	srcs    map[string]*libavfilter.AVFilterContext
+	sinks   map[string]*libavfilter.AVFilterContext
+	inputs  []string
+	outputs []string
+}
+
+// NewGraph parses desc, connects a buffer source to every input label and a
+// buffer sink to every output label, and configures the graph. A single
+// unlabelled input or output defaults to "in" or "out", the labels the
+// filtergraph syntax uses when none is given.
+func NewGraph(desc string, inputs []Input, outputs []Output) (g *Graph, err error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, fmt.Errorf("filter: graph needs at least one input and one output")
+	}
+	g = &Graph{
+		graph: libavfilter.AvfilterGraphAlloc(),
+		srcs:  make(map[string]*libavfilter.AVFilterContext),
+		sinks: make(map[string]*libavfilter.AVFilterContext),
+	}
+	if g.graph == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer func() {
+		if err != nil {
+			g.Close()
+			g = nil
+		}
+	}()
+
+	var ins, outs *libavfilter.AVFilterInOut
+	defer libavfilter.AvfilterInoutFree(&ins)
+	defer libavfilter.AvfilterInoutFree(&outs)
+
+	// the sources feed the open inputs of desc, so they are its "outputs"
+	for i := len(inputs) - 1; i >= 0; i-- {
+		in := inputs[i]
+		if in.Label == "" && len(inputs) == 1 {
+			in.Label = "in"
+		}
+		if _, ok := g.srcs[in.Label]; ok || in.Label == "" {
+			return nil, fmt.Errorf("filter: invalid or duplicate input label %q", in.Label)
+		}
+		ctx, err := g.newSource(&in)
+		if err != nil {
+			return nil, err
+		}
+		g.srcs[in.Label] = ctx
+		g.inputs = append([]string{in.Label}, g.inputs...)
+		if outs, err = prependInOut(outs, in.Label, ctx); err != nil {
+			return nil, err
+		}
+	}
+	for i := len(outputs) - 1; i >= 0; i-- {
+		out := outputs[i]
+		if out.Label == "" && len(outputs) == 1 {
+			out.Label = "out"
+		}
+		if _, ok := g.sinks[out.Label]; ok || out.Label == "" {
+			return nil, fmt.Errorf("filter: invalid or duplicate output label %q", out.Label)
+		}
+		ctx, err := g.newSink(&out)
+		if err != nil {
+			return nil, err
+		}
+		g.sinks[out.Label] = ctx
+		g.outputs = append([]string{out.Label}, g.outputs...)
+		if ins, err = prependInOut(ins, out.Label, ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if ret := g.graph.AvfilterGraphParsePtr(desc, &ins, &outs, 0); ret < 0 {
+		return nil, fmt.Errorf("filter: parse %q: %w", desc, libavutil.ErrorFromCode(ret))
+	}
+	if ret := g.graph.AvfilterGraphConfig(0); ret < 0 {
+		return nil, fmt.Errorf("filter: configure graph: %w", libavutil.ErrorFromCode(ret))
+	}
+	return g, nil
+}
+
+func (g *Graph) newSource(in *Input) (*libavfilter.AVFilterContext, error) {
+	var name, args string
+	switch in.MediaType {
+	case libavutil.AVMEDIA_TYPE_VIDEO:
+		name = "buffer"
+		sar := in.SampleAspectRatio
+		if sar.Num == 0 || sar.Den == 0 {
+			sar = libavutil.AVRational{Num: 0, Den: 1}
+		}
+		args = fmt.Sprintf("video_size=%dx%d:pix_fmt=%d:time_base=%d/%d:pixel_aspect=%d/%d",
+			in.Width, in.Height, in.PixFmt, in.TimeBase.Num, in.TimeBase.Den, sar.Num, sar.Den)
+		if in.FrameRate.Num > 0 && in.FrameRate.Den > 0 {
+			args += fmt.Sprintf(":frame_rate=%d/%d", in.FrameRate.Num, in.FrameRate.Den)
+		}
+	case libavutil.AVMEDIA_TYPE_AUDIO:
+		name = "abuffer"
+		layout := in.ChannelLayout
+		if layout == 0 {
+			return nil, fmt.Errorf("filter: input %q has no channel layout", in.Label)
+		}
+		args = fmt.Sprintf("time_base=%d/%d:sample_rate=%d:sample_fmt=%s:channel_layout=0x%x",
+			in.TimeBase.Num, in.TimeBase.Den, in.SampleRate,
+			libavutil.AvGetSampleFmtName(in.SampleFmt), layout)
+	default:
+		return nil, fmt.Errorf("filter: input %q has unsupported media type %d", in.Label, in.MediaType)
+	}
+
+	var ctx *libavfilter.AVFilterContext
+	ret := libavfilter.AvfilterGraphCreateFilter(&ctx, libavfilter.AvfilterGetByName(name), in.Label, args, 0, g.graph)
+	if ret < 0 {
+		return nil, fmt.Errorf("filter: create %s for input %q: %w", name, in.Label, libavutil.ErrorFromCode(ret))
+	}
+	if in.HwFramesCtx != nil {
+		par := libavfilter.AvBuffersrcParametersAlloc()
+		if par == nil {
+			return nil, libavutil.AVError(-libavutil.ENOMEM)
+		}
+		par.Format = ffcommon.FInt(in.PixFmt)
+		par.HwFramesCtx = in.HwFramesCtx
+		ret = ctx.AvBuffersrcParametersSet(par)
+		libavutil.AvFree(uintptr(unsafe.Pointer(par)))
+		if ret < 0 {
+			return nil, fmt.Errorf("filter: set hw frames for input %q: %w", in.Label, libavutil.ErrorFromCode(ret))
+		}
+	}
+	return ctx, nil
+}
+
+func (g *Graph) newSink(out *Output) (*libavfilter.AVFilterContext, error) {
+	var name string
+	switch out.MediaType {
+	case libavutil.AVMEDIA_TYPE_VIDEO:
+		name = "buffersink"
+	case libavutil.AVMEDIA_TYPE_AUDIO:
+		name = "abuffersink"
+	default:
+		return nil, fmt.Errorf("filter: output %q has unsupported media type %d", out.Label, out.MediaType)
+	}
+
+	var ctx *libavfilter.AVFilterContext
+	ret := libavfilter.AvfilterGraphCreateFilter(&ctx, libavfilter.AvfilterGetByName(name), out.Label, "", 0, g.graph)
+	if ret < 0 {
+		return nil, fmt.Errorf("filter: create %s for output %q: %w", name, out.Label, libavutil.ErrorFromCode(ret))
+	}
+
+	obj := uintptr(unsafe.Pointer(ctx))
+	if len(out.PixFmts) > 0 {
+		ret = setIntList(obj, "pix_fmts", out.PixFmts)
+	}
+	if ret >= 0 && len(out.SampleFmts) > 0 {
+		ret = setIntList(obj, "sample_fmts", out.SampleFmts)
+	}
+	if ret >= 0 && len(out.SampleRates) > 0 {
+		ret = setIntList(obj, "sample_rates", out.SampleRates)
+	}
+	if ret >= 0 && len(out.ChannelLayouts) > 0 {
+		ret = setIntList(obj, "channel_layouts", out.ChannelLayouts)
+	}
+	if ret < 0 {
+		return nil, fmt.Errorf("filter: constrain output %q: %w", out.Label, libavutil.ErrorFromCode(ret))
+	}
+	if out.FrameSize > 0 {
+		ctx.AvBuffersinkSetFrameSize(out.FrameSize)
+	}
+	return ctx, nil
+}
+
+// setIntList sets a binary int list option, the way av_opt_set_int_list does
+// without the terminator.
+func setIntList[T libavutil.AVSampleFormat | ffcommon.FInt | ffcommon.FInt64T](obj ffcommon.FVoidP, name string, list []T) ffcommon.FInt {
+	size := ffcommon.FInt(unsafe.Sizeof(list[0])) * ffcommon.FInt(len(list))
+	return libavutil.AvOptSetBin(obj, name, (*ffcommon.FUint8T)(unsafe.Pointer(&list[0])), size, libavutil.AV_OPT_SEARCH_CHILDREN)
+}
+
+// prependInOut allocates an AVFilterInOut for ctx in front of list. The name
+// is av_malloc'ed since avfilter_inout_free releases it.
+func prependInOut(list *libavfilter.AVFilterInOut, label string, ctx *libavfilter.AVFilterContext) (*libavfilter.AVFilterInOut, error) {
+	inout := libavfilter.AvfilterInoutAlloc()
+	if inout == nil {
+		return list, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	inout.Name = cstrdup(label)
+	inout.FilterCtx = ctx
+	inout.PadIdx = 0
+	inout.Next = list
+	return inout, nil
+}
+
+func cstrdup(s string) ffcommon.FCharPStruct {
+	p := libavutil.AvMalloc(ffcommon.FSizeT(len(s) + 1))
+	if p == 0 {
+		return 0
+	}
+	buf := unsafe.Slice(*(**byte)(unsafe.Pointer(&p)), len(s)+1)
+	copy(buf, s)
+	buf[len(s)] = 0
+	return p
+}
+
+// Inputs returns the input labels in the order they were given.
+func (g *Graph) Inputs() []string {
+	return g.inputs
+}
+
+// Outputs returns the output labels in the order they were given.
+func (g *Graph) Outputs() []string {
+	return g.outputs
+}
+
+// Push sends frame to the input label. The graph takes its own reference,
+// the caller keeps ownership of frame. A nil frame marks EOF on that input.
+func (g *Graph) Push(label string, frame *libavutil.AVFrame) error {
+	return g.PushFlags(label, frame, libavfilter.AV_BUFFERSRC_FLAG_KEEP_REF)
+}
+
+// PushFlags is Push with explicit AV_BUFFERSRC_FLAG_* flags.
+func (g *Graph) PushFlags(label string, frame *libavutil.AVFrame, flags ffcommon.FInt) error {
+	src, ok := g.srcs[label]
+	if !ok {
+		return fmt.Errorf("filter: unknown input %q", label)
+	}
+	return libavutil.ErrorFromCode(src.AvBuffersrcAddFrameFlags(frame, flags))
+}
+
+// CloseInput marks EOF on the input label, pts being the end timestamp of the
+// last frame in the input time base.
+func (g *Graph) CloseInput(label string, pts ffcommon.FInt64T) error {
+	src, ok := g.srcs[label]
+	if !ok {
+		return fmt.Errorf("filter: unknown input %q", label)
+	}
+	return libavutil.ErrorFromCode(src.AvBuffersrcClose(pts, libavfilter.AV_BUFFERSRC_FLAG_PUSH))
+}
+
+// Pull reads a filtered frame from the output label into frame. It returns
+// libavutil.ErrEAGAIN when more input is needed and io.EOF once the output
+// is drained.
+func (g *Graph) Pull(label string, frame *libavutil.AVFrame) error {
+	return g.PullFlags(label, frame, 0)
+}
+
+// PullFlags is Pull with explicit AV_BUFFERSINK_FLAG_* flags.
+func (g *Graph) PullFlags(label string, frame *libavutil.AVFrame, flags ffcommon.FInt) error {
+	sink, ok := g.sinks[label]
+	if !ok {
+		return fmt.Errorf("filter: unknown output %q", label)
+	}
+	return libavutil.ErrorFromCode(sink.AvBuffersinkGetFrameFlags(frame, flags))
+}
+
+// OutputFormat returns the negotiated format of the output label.
+func (g *Graph) OutputFormat(label string) (f Format, err error) {
+	sink, ok := g.sinks[label]
+	if !ok {
+		return f, fmt.Errorf("filter: unknown output %q", label)
+	}
+	f.MediaType = sink.AvBuffersinkGetType()
+	f.Format = sink.AvBuffersinkGetFormat()
+	f.TimeBase = sink.AvBuffersinkGetTimeBase()
+	if f.MediaType == libavutil.AVMEDIA_TYPE_VIDEO {
+		f.FrameRate = sink.AvBuffersinkGetFrameRate()
+		f.Width = sink.AvBuffersinkGetW()
+		f.Height = sink.AvBuffersinkGetH()
+		f.SampleAspectRatio = sink.AvBuffersinkGetSampleAspectRatio()
+	} else {
+		f.SampleRate = sink.AvBuffersinkGetSampleRate()
+		f.Channels = sink.AvBuffersinkGetChannels()
+		f.ChannelLayout = sink.AvBuffersinkGetChannelLayout()
+	}
+	return f, nil
+}
+
+// SendCommand sends cmd with arg to the filters matching target ("all", a
+// filter name or an instance name) and returns the filters' response.
+func (g *Graph) SendCommand(target, cmd, arg string, flags ffcommon.FInt) (string, error) {
+	res := make([]byte, 256)
+	ret := g.graph.AvfilterGraphSendCommand(target, cmd, arg, &res[0], ffcommon.FInt(len(res)), flags)
+	if ret < 0 {
+		return "", fmt.Errorf("filter: command %s %q on %q: %w", cmd, arg, target, libavutil.ErrorFromCode(ret))
+	}
+	return ffcommon.GoStringFromBytePtr(&res[0]), nil
+}
+
+// QueueCommand queues cmd for the filters matching target, to be run once
+// the graph reaches ts seconds.
+func (g *Graph) QueueCommand(target, cmd, arg string, flags ffcommon.FInt, ts float64) error {
+	ret := g.graph.AvfilterGraphQueueCommand(target, cmd, arg, flags, ts)
+	if ret < 0 {
+		return fmt.Errorf("filter: queue command %s %q on %q: %w", cmd, arg, target, libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// Close frees the graph and all of its filters.
+func (g *Graph) Close() {
+	if g.graph != nil {
+		libavfilter.AvfilterGraphFree(&g.graph)
+	}
+	g.srcs = nil
+	g.sinks = nil
+}
@@ -1,6 +1,6 @@
 module github.com/dwdcth/ffmpeg-go/v7
 
-go 1.20
+go 1.23
 
 require (
 	github.com/ebitengine/purego v0.7.1
@@ -0,0 +1,289 @@
+// Package hdr carries the color properties and the HDR static metadata of
+// a video stream through a transcode: from the demuxer and the decoded
+// frames to the encoder, its frames and the muxer stream.
+package hdr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+// Color holds the color properties of a video stream. Zero values are
+// unspecified, except for Primaries, Transfer and Space whose unspecified
+// value is 2; both count as unset.
+type Color struct {
+	Primaries      libavutil.AVColorPrimaries
+	Transfer       libavutil.AVColorTransferCharacteristic
+	Space          libavutil.AVColorSpace
+	Range          libavutil.AVColorRange
+	ChromaLocation libavutil.AVChromaLocation
+}
+
+func (c Color) primariesSet() bool {
+	return c.Primaries != 0 && c.Primaries != libavutil.AVCOL_PRI_UNSPECIFIED
+}
+
+func (c Color) transferSet() bool {
+	return c.Transfer != 0 && c.Transfer != libavutil.AVCOL_TRC_UNSPECIFIED
+}
+
+func (c Color) spaceSet() bool {
+	return c.Space != 0 && c.Space != libavutil.AVCOL_SPC_UNSPECIFIED
+}
+
+// Merge returns c with the set fields of o replacing its own.
+func (c Color) Merge(o Color) Color {
+	if o.primariesSet() {
+		c.Primaries = o.Primaries
+	}
+	if o.transferSet() {
+		c.Transfer = o.Transfer
+	}
+	if o.spaceSet() {
+		c.Space = o.Space
+	}
+	if o.Range != libavutil.AVCOL_RANGE_UNSPECIFIED {
+		c.Range = o.Range
+	}
+	if o.ChromaLocation != libavutil.AVCHROMA_LOC_UNSPECIFIED {
+		c.ChromaLocation = o.ChromaLocation
+	}
+	return c
+}
+
+// fill returns c with its unset fields taken from o.
+func (c Color) fill(o Color) Color {
+	return o.Merge(c)
+}
+
+// HDR tells whether the transfer function is PQ (HDR10) or HLG.
+func (c Color) HDR() bool {
+	return c.Transfer == libavutil.AVCOL_TRC_SMPTE2084 || c.Transfer == libavutil.AVCOL_TRC_ARIB_STD_B67
+}
+
+func (c Color) String() string {
+	return fmt.Sprintf("%s/%s/%s/%s", libavutil.AvColorSpaceName(c.Space), libavutil.AvColorPrimariesName(c.Primaries),
+		libavutil.AvColorTransferName(c.Transfer), libavutil.AvColorRangeName(c.Range))
+}
+
+// ColorOfParams returns the color properties of codec parameters.
+func ColorOfParams(par *libavcodec.AVCodecParameters) Color {
+	return Color{
+		Primaries:      par.ColorPrimaries,
+		Transfer:       par.ColorTrc,
+		Space:          par.ColorSpace,
+		Range:          par.ColorRange,
+		ChromaLocation: par.ChromaLocation,
+	}
+}
+
+// ColorOfFrame returns the color properties of a decoded frame.
+func ColorOfFrame(f *libavutil.AVFrame) Color {
+	return Color{
+		Primaries:      f.ColorPrimaries,
+		Transfer:       f.ColorTrc,
+		Space:          f.Colorspace,
+		Range:          f.ColorRange,
+		ChromaLocation: f.ChromaLocation,
+	}
+}
+
+// Metadata is what the package carries: the color properties and the
+// HDR10 static metadata. Nil fields are absent.
+type Metadata struct {
+	Color             Color
+	MasteringDisplay  *sidedata.MasteringDisplay
+	ContentLightLevel *sidedata.ContentLightLevel
+}
+
+// FromStream returns the metadata of a demuxed or muxed stream, from its
+// codec parameters and side data.
+func FromStream(st *libavformat.AVStream) Metadata {
+	m := Metadata{Color: ColorOfParams(st.Codecpar)}
+	sd := sidedata.OfStream(st)
+	if md, ok := sd.MasteringDisplay(); ok {
+		m.MasteringDisplay = &md
+	}
+	if c, ok := sd.ContentLightLevel(); ok {
+		m.ContentLightLevel = &c
+	}
+	return m
+}
+
+// FromFrame returns the metadata of a decoded frame.
+func FromFrame(f *libavutil.AVFrame) Metadata {
+	m := Metadata{Color: ColorOfFrame(f)}
+	sd := sidedata.OfFrame(f)
+	if md, ok := sd.MasteringDisplay(); ok {
+		m.MasteringDisplay = &md
+	}
+	if c, ok := sd.ContentLightLevel(); ok {
+		m.ContentLightLevel = &c
+	}
+	return m
+}
+
+// fill returns m with its absent values taken from o.
+func (m Metadata) fill(o Metadata) Metadata {
+	m.Color = m.Color.fill(o.Color)
+	if m.MasteringDisplay == nil {
+		m.MasteringDisplay = o.MasteringDisplay
+	}
+	if m.ContentLightLevel == nil {
+		m.ContentLightLevel = o.ContentLightLevel
+	}
+	return m
+}
+
+// Overrides replaces source values. Unset color fields and nil metadata
+// keep the source values.
+type Overrides struct {
+	Color             Color
+	MasteringDisplay  *sidedata.MasteringDisplay
+	ContentLightLevel *sidedata.ContentLightLevel
+	// DropMastering and DropContentLight remove the metadata instead.
+	DropMastering    bool
+	DropContentLight bool
+}
+
+func (o Overrides) apply(m Metadata) Metadata {
+	m.Color = m.Color.Merge(o.Color)
+	if o.MasteringDisplay != nil {
+		m.MasteringDisplay = o.MasteringDisplay
+	}
+	if o.ContentLightLevel != nil {
+		m.ContentLightLevel = o.ContentLightLevel
+	}
+	if o.DropMastering {
+		m.MasteringDisplay = nil
+	}
+	if o.DropContentLight {
+		m.ContentLightLevel = nil
+	}
+	return m
+}
+
+// Passthrough carries the metadata of one video stream through a
+// transcode. Call Frame on every decoded frame, ConfigureEncoder before
+// opening the encoder and ConfigureStream before writing the header.
+type Passthrough struct {
+	src Metadata
+	ov  Overrides
+}
+
+// New returns a Passthrough for the stream whose metadata src was read
+// with FromStream, applying ov.
+func New(src Metadata, ov Overrides) *Passthrough {
+	return &Passthrough{src: src, ov: ov}
+}
+
+// ForStream returns a Passthrough for the demuxed stream st.
+func ForStream(st *libavformat.AVStream, ov Overrides) *Passthrough {
+	return New(FromStream(st), ov)
+}
+
+// Source returns the metadata found in the source so far.
+func (p *Passthrough) Source() Metadata {
+	return p.src
+}
+
+// Output returns the metadata the output gets.
+func (p *Passthrough) Output() Metadata {
+	return p.ov.apply(p.src)
+}
+
+// Frame completes the source metadata with that of the decoded frame f,
+// since many demuxers only find it in the bitstream, and stamps f with the
+// output metadata for the encoder and filters reading it from frames.
+func (p *Passthrough) Frame(f *libavutil.AVFrame) error {
+	p.src = p.src.fill(FromFrame(f))
+	out := p.Output()
+	f.ColorPrimaries = out.Color.Primaries
+	f.ColorTrc = out.Color.Transfer
+	f.Colorspace = out.Color.Space
+	f.ColorRange = out.Color.Range
+	f.ChromaLocation = out.Color.ChromaLocation
+	sd := sidedata.OfFrame(f)
+	if out.MasteringDisplay != nil {
+		if err := sd.SetMasteringDisplay(*out.MasteringDisplay); err != nil {
+			return fmt.Errorf("hdr: %w", err)
+		}
+	} else {
+		sd.Remove(libavutil.AV_FRAME_DATA_MASTERING_DISPLAY_METADATA)
+	}
+	if out.ContentLightLevel != nil {
+		if err := sd.SetContentLightLevel(*out.ContentLightLevel); err != nil {
+			return fmt.Errorf("hdr: %w", err)
+		}
+	} else {
+		sd.Remove(libavutil.AV_FRAME_DATA_CONTENT_LIGHT_LEVEL)
+	}
+	return nil
+}
+
+// ConfigureEncoder sets the color properties of the encoder and, for
+// libx265, adds the x265 parameters writing the HDR10 SEI messages to
+// opts, the options passed to avcodec_open2. Call it once the first frame
+// went through Frame.
+func (p *Passthrough) ConfigureEncoder(enc *libavcodec.AVCodecContext, opts **libavutil.AVDictionary) error {
+	out := p.Output()
+	enc.ColorPrimaries = out.Color.Primaries
+	enc.ColorTrc = out.Color.Transfer
+	enc.Colorspace = out.Color.Space
+	enc.ColorRange = out.Color.Range
+	enc.ChromaSampleLocation = out.Color.ChromaLocation
+	if enc.Codec == nil || ffcommon.GoString(enc.Codec.Name) != "libx265" {
+		return nil
+	}
+	var params []string
+	// hdr10 signals PQ content, HLG only needs the transfer in the VUI
+	if out.Color.Transfer == libavutil.AVCOL_TRC_SMPTE2084 {
+		params = append(params, "hdr10=1", "repeat-headers=1")
+	}
+	if md := out.MasteringDisplay; md != nil && md.HasPrimaries && md.HasLuminance {
+		params = append(params, "master-display="+md.String())
+	}
+	if c := out.ContentLightLevel; c != nil {
+		params = append(params, "max-cll="+c.String())
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	if e := (*opts).AvDictGet("x265-params", nil, 0); e != nil {
+		params = append([]string{ffcommon.GoString(e.Value)}, params...)
+	}
+	if ret := libavutil.AvDictSet(opts, "x265-params", strings.Join(params, ":"), 0); ret < 0 {
+		return fmt.Errorf("hdr: set x265-params: %w", libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// ConfigureStream sets the color properties and HDR side data of the
+// muxer stream st, after its codec parameters were copied from the
+// encoder.
+func (p *Passthrough) ConfigureStream(st *libavformat.AVStream) error {
+	out := p.Output()
+	st.Codecpar.ColorPrimaries = out.Color.Primaries
+	st.Codecpar.ColorTrc = out.Color.Transfer
+	st.Codecpar.ColorSpace = out.Color.Space
+	st.Codecpar.ColorRange = out.Color.Range
+	st.Codecpar.ChromaLocation = out.Color.ChromaLocation
+	sd := sidedata.OfStream(st)
+	if out.MasteringDisplay != nil {
+		if err := sd.SetMasteringDisplay(*out.MasteringDisplay); err != nil {
+			return fmt.Errorf("hdr: %w", err)
+		}
+	}
+	if out.ContentLightLevel != nil {
+		if err := sd.SetContentLightLevel(*out.ContentLightLevel); err != nil {
+			return fmt.Errorf("hdr: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package hdr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+// Status tells what became of a source value in the output.
+type Status int
+
+const (
+	// Preserved values are in the output as in the source.
+	Preserved Status = iota
+	// Overridden values are in the output, or left out of it, as
+	// requested by the overrides.
+	Overridden
+	// Dropped values are in the source but not in the output, without an
+	// override asking for it.
+	Dropped
+	// Changed values differ in the output without an override asking for
+	// it.
+	Changed
+	// Added values are in the output only, as requested by the overrides.
+	Added
+)
+
+func (s Status) String() string {
+	switch s {
+	case Preserved:
+		return "preserved"
+	case Overridden:
+		return "overridden"
+	case Dropped:
+		return "dropped"
+	case Changed:
+		return "changed"
+	case Added:
+		return "added"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Item is the fate of one property.
+type Item struct {
+	Name   string
+	Status Status
+	// Source and Output are the values in the source and the output, ""
+	// when absent.
+	Source, Output string
+}
+
+func (it Item) String() string {
+	switch it.Status {
+	case Preserved:
+		return fmt.Sprintf("%s: preserved (%s)", it.Name, it.Source)
+	case Added:
+		return fmt.Sprintf("%s: added (%s)", it.Name, it.Output)
+	case Dropped:
+		return fmt.Sprintf("%s: dropped (%s)", it.Name, it.Source)
+	}
+	return fmt.Sprintf("%s: %s (%s -> %s)", it.Name, it.Status, it.Source, it.Output)
+}
+
+// Report tells what the output kept of the source metadata. Properties
+// absent from both are left out.
+type Report []Item
+
+// Lost returns the items dropped or changed without an override asking
+// for it.
+func (r Report) Lost() Report {
+	var lost Report
+	for _, it := range r {
+		if it.Status == Dropped || it.Status == Changed {
+			lost = append(lost, it)
+		}
+	}
+	return lost
+}
+
+func (r Report) String() string {
+	lines := make([]string, len(r))
+	for i, it := range r {
+		lines[i] = it.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate compares the metadata out found in the output, with FromStream
+// or FromFrame, with what p was asked to carry.
+func (p *Passthrough) Validate(out Metadata) Report {
+	src, want := p.src, p.Output()
+	var r Report
+	item := func(name, s, w, o string, override bool, same func(a, b string) bool) {
+		if st, ok := classify(s, w, o, override, same); ok {
+			r = append(r, Item{Name: name, Status: st, Source: s, Output: o})
+		}
+	}
+	equal := func(a, b string) bool { return a == b }
+
+	color := func(name string, s, w, o string, set func(Color) bool) {
+		if !set(src.Color) {
+			s = ""
+		}
+		if !set(want.Color) {
+			w = ""
+		}
+		if !set(out.Color) {
+			o = ""
+		}
+		item(name, s, w, o, set(p.ov.Color), equal)
+	}
+	color("primaries", libavutil.AvColorPrimariesName(src.Color.Primaries), libavutil.AvColorPrimariesName(want.Color.Primaries),
+		libavutil.AvColorPrimariesName(out.Color.Primaries), Color.primariesSet)
+	color("transfer", libavutil.AvColorTransferName(src.Color.Transfer), libavutil.AvColorTransferName(want.Color.Transfer),
+		libavutil.AvColorTransferName(out.Color.Transfer), Color.transferSet)
+	color("matrix", libavutil.AvColorSpaceName(src.Color.Space), libavutil.AvColorSpaceName(want.Color.Space),
+		libavutil.AvColorSpaceName(out.Color.Space), Color.spaceSet)
+	color("range", libavutil.AvColorRangeName(src.Color.Range), libavutil.AvColorRangeName(want.Color.Range),
+		libavutil.AvColorRangeName(out.Color.Range), func(c Color) bool { return c.Range != libavutil.AVCOL_RANGE_UNSPECIFIED })
+	color("chroma location", libavutil.AvChromaLocationName(src.Color.ChromaLocation), libavutil.AvChromaLocationName(want.Color.ChromaLocation),
+		libavutil.AvChromaLocationName(out.Color.ChromaLocation), func(c Color) bool { return c.ChromaLocation != libavutil.AVCHROMA_LOC_UNSPECIFIED })
+
+	md := func(m *sidedata.MasteringDisplay) string {
+		if m == nil {
+			return ""
+		}
+		return m.String()
+	}
+	item("mastering display", md(src.MasteringDisplay), md(want.MasteringDisplay), md(out.MasteringDisplay),
+		p.ov.MasteringDisplay != nil || p.ov.DropMastering, sameMasteringDisplay)
+	cll := func(c *sidedata.ContentLightLevel) string {
+		if c == nil {
+			return ""
+		}
+		return c.String()
+	}
+	item("content light level", cll(src.ContentLightLevel), cll(want.ContentLightLevel), cll(out.ContentLightLevel),
+		p.ov.ContentLightLevel != nil || p.ov.DropContentLight, equal)
+	return r
+}
+
+// classify returns the status of a property with source value s, wanted
+// value w and output value o, "" when absent, false if it is absent from
+// both source and output. override tells whether an override set it.
+func classify(s, w, o string, override bool, same func(a, b string) bool) (Status, bool) {
+	switch {
+	case s == "" && o == "":
+		return 0, false
+	case o == "":
+		if w == "" && override {
+			// dropped on request
+			return Overridden, true
+		}
+		return Dropped, true
+	case s == "":
+		if !same(w, o) {
+			return Changed, true
+		}
+		return Added, true
+	case same(s, o) && same(w, o):
+		return Preserved, true
+	case override && same(w, o):
+		return Overridden, true
+	}
+	return Changed, true
+}
+
+// sameMasteringDisplay compares two mastering displays in the x265 form,
+// allowing the rounding of containers storing them in other units: one
+// unit of chromaticity and 1% of luminance.
+func sameMasteringDisplay(a, b string) bool {
+	if a == b {
+		return true
+	}
+	va, vb := masteringValues(a), masteringValues(b)
+	if va == nil || vb == nil || len(va) != len(vb) {
+		return false
+	}
+	for i := range va {
+		d := math.Abs(va[i] - vb[i])
+		if i < 8 && d > 1 || i >= 8 && d > 1+math.Max(va[i], vb[i])/100 {
+			return false
+		}
+	}
+	return true
+}
+
+// masteringValues returns the ten numbers of a master-display string:
+// the eight chromaticities, then the maximum and minimum luminance.
+func masteringValues(s string) []float64 {
+	var v [10]float64
+	_, err := fmt.Sscanf(s, "G(%g,%g)B(%g,%g)R(%g,%g)WP(%g,%g)L(%g,%g)",
+		&v[0], &v[1], &v[2], &v[3], &v[4], &v[5], &v[6], &v[7], &v[8], &v[9])
+	if err != nil {
+		return nil
+	}
+	return v[:]
+}
+
+// ValidateFile opens the transcoded file at path and validates the
+// metadata of its best video stream. Metadata only coded in the bitstream
+// is not seen.
+func (p *Passthrough) ValidateFile(path string) (Report, error) {
+	ic, err := remux.OpenInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("hdr: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+	i := ic.AvFindBestStream(libavutil.AVMEDIA_TYPE_VIDEO, -1, -1, nil, 0)
+	if i < 0 {
+		return nil, fmt.Errorf("hdr: %s: no video stream", path)
+	}
+	return p.Validate(FromStream(ic.GetStream(ffcommon.FUnsignedInt(i)))), nil
+}
@@ -0,0 +1,157 @@
+package hdr
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dwdcth/ffmpeg-go/v7/avutil"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+func TestClassify(t *testing.T) {
+	equal := func(a, b string) bool { return a == b }
+	for _, tc := range []struct {
+		name     string
+		s, w, o  string
+		override bool
+		want     Status
+		ok       bool
+	}{
+		{"absent", "", "", "", false, 0, false},
+		{"absent but wanted", "", "x", "", true, 0, false},
+		{"preserved", "a", "a", "a", false, Preserved, true},
+		{"dropped", "a", "a", "", false, Dropped, true},
+		{"dropped on request", "a", "", "", true, Overridden, true},
+		{"dropped despite override", "a", "b", "", true, Dropped, true},
+		{"overridden", "a", "b", "b", true, Overridden, true},
+		{"changed", "a", "a", "c", false, Changed, true},
+		{"changed despite override", "a", "b", "c", true, Changed, true},
+		{"override same as source", "a", "a", "a", true, Preserved, true},
+		{"added", "", "b", "b", true, Added, true},
+		{"added unasked", "", "", "b", false, Changed, true},
+		{"added differently", "", "b", "c", true, Changed, true},
+	} {
+		st, ok := classify(tc.s, tc.w, tc.o, tc.override, equal)
+		if st != tc.want || ok != tc.ok {
+			t.Errorf("%s: classify(%q, %q, %q, %v) = %v, %v, want %v, %v", tc.name, tc.s, tc.w, tc.o, tc.override, st, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestMasteringValues(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []float64
+	}{
+		{"G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10000000,1)",
+			[]float64{8500, 39850, 6550, 2300, 35400, 14600, 15635, 16450, 10000000, 1}},
+		{"G(0,0)B(0,0)R(0,0)WP(0,0)L(0,0)", make([]float64, 10)},
+		{"", nil},
+		{"1000,400", nil},
+		{"G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)", nil},
+	} {
+		if got := masteringValues(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("masteringValues(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSameMasteringDisplay(t *testing.T) {
+	const ref = "G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10000000,1)"
+	for _, tc := range []struct {
+		name string
+		b    string
+		want bool
+	}{
+		{"identical", ref, true},
+		{"one chromaticity unit", "G(8501,39850)B(6550,2299)R(35400,14600)WP(15635,16450)L(10000000,1)", true},
+		{"two chromaticity units", "G(8502,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10000000,1)", false},
+		{"max luminance within 1%", "G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10100000,1)", true},
+		{"max luminance 2% off", "G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10200000,1)", false},
+		{"min luminance rounded", "G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10000000,2)", true},
+		{"min luminance off", "G(8500,39850)B(6550,2300)R(35400,14600)WP(15635,16450)L(10000000,3)", false},
+		{"malformed", "G(8500,39850)", false},
+		{"absent", "", false},
+	} {
+		if got := sameMasteringDisplay(ref, tc.b); got != tc.want {
+			t.Errorf("%s: sameMasteringDisplay(%q) = %v, want %v", tc.name, tc.b, got, tc.want)
+		}
+	}
+	if !sameMasteringDisplay("", "") {
+		t.Error("two absent displays differ")
+	}
+}
+
+// haveAvutil tells whether libavutil loads, which the color names need.
+var haveAvutil = sync.OnceValue(func() (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return ffcommon.GetAvutilDll() != 0
+})
+
+func bt2020Display(maxLum int32) *sidedata.MasteringDisplay {
+	return &sidedata.MasteringDisplay{
+		Primaries: [3][2]avutil.Rational{
+			{avutil.Q(35400, 50000), avutil.Q(14600, 50000)},
+			{avutil.Q(8500, 50000), avutil.Q(39850, 50000)},
+			{avutil.Q(6550, 50000), avutil.Q(2300, 50000)},
+		},
+		WhitePoint:   [2]avutil.Rational{avutil.Q(15635, 50000), avutil.Q(16450, 50000)},
+		MinLuminance: avutil.Q(1, 10000),
+		MaxLuminance: avutil.Q(maxLum, 1),
+		HasPrimaries: true,
+		HasLuminance: true,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if !haveAvutil() {
+		t.Skip("libavutil not found")
+	}
+	src := Metadata{
+		Color: Color{
+			Primaries: libavutil.AVCOL_PRI_BT2020,
+			Transfer:  libavutil.AVCOL_TRC_SMPTE2084,
+			Space:     libavutil.AVCOL_SPC_BT2020_NCL,
+			Range:     libavutil.AVCOL_RANGE_MPEG,
+		},
+		MasteringDisplay: bt2020Display(1000),
+	}
+	p := New(src, Overrides{ContentLightLevel: &sidedata.ContentLightLevel{MaxCLL: 1000, MaxFALL: 400}})
+	out := Metadata{
+		Color: Color{
+			Primaries: libavutil.AVCOL_PRI_BT2020,
+			Transfer:  libavutil.AVCOL_TRC_SMPTE2084,
+			Space:     libavutil.AVCOL_SPC_UNSPECIFIED,
+			Range:     libavutil.AVCOL_RANGE_MPEG,
+		},
+		// a container storing the luminance in other units
+		MasteringDisplay:  bt2020Display(1005),
+		ContentLightLevel: &sidedata.ContentLightLevel{MaxCLL: 1000, MaxFALL: 400},
+	}
+	want := map[string]Status{
+		"primaries":           Preserved,
+		"transfer":            Preserved,
+		"matrix":              Dropped,
+		"range":               Preserved,
+		"mastering display":   Preserved,
+		"content light level": Added,
+	}
+	r := p.Validate(out)
+	got := map[string]Status{}
+	for _, it := range r {
+		got[it.Name] = it.Status
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() =\n%v\nwant %v", r, want)
+	}
+	if lost := r.Lost(); len(lost) != 1 || lost[0].Name != "matrix" {
+		t.Errorf("Lost() = %v, want the matrix", lost)
+	}
+}
@@ -0,0 +1,205 @@
+// Package remux holds the stream-copy plumbing shared by the high-level
+// packages: opening inputs, mirroring their streams into an output and
+// forwarding packets with rescaled timestamps.
+package remux
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/dict"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// OpenInput opens path and reads its stream information.
+func OpenInput(path string) (*libavformat.AVFormatContext, error) {
+	var in *libavformat.AVFormatContext
+	if ret := libavformat.AvformatOpenInput(&in, path, nil, nil); ret < 0 {
+		return nil, fmt.Errorf("open %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	if ret := in.AvformatFindStreamInfo(nil); ret < 0 {
+		libavformat.AvformatCloseInput(&in)
+		return nil, fmt.Errorf("find stream info %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	return in, nil
+}
+
+// Output is a muxer whose streams mirror those of an input.
+type Output struct {
+	Ctx *libavformat.AVFormatContext
+	// Map gives the output stream index of every input stream, or -1 for
+	// streams the muxer cannot hold.
+	Map []int
+
+	in     *libavformat.AVFormatContext
+	path   string
+	header bool
+}
+
+// NewOutput allocates a muxer for path, guessing the format from the file
+// name unless format is given, and adds a copy of every input stream the
+// muxer supports, including disposition, metadata and side data. The
+// container metadata is copied too. Dropped lists the streams left out.
+func NewOutput(in *libavformat.AVFormatContext, path, format string) (*Output, error) {
+	o := &Output{in: in, path: path}
+	if ret := libavformat.AvformatAllocOutputContext2(&o.Ctx, nil, format, path); ret < 0 || o.Ctx == nil {
+		return nil, fmt.Errorf("create output %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	libavutil.AvDictCopy(&o.Ctx.Metadata, in.Metadata, 0)
+
+	o.Map = make([]int, in.NbStreams)
+	for i := range o.Map {
+		ist := in.GetStream(ffcommon.FUnsignedInt(i))
+		if o.Ctx.Oformat.AvformatQueryCodec(ist.Codecpar.CodecId, libavcodec.FF_COMPLIANCE_NORMAL) == 0 {
+			o.Map[i] = -1
+			continue
+		}
+		ost, err := o.AddStream(ist)
+		if err != nil {
+			o.Close()
+			return nil, err
+		}
+		o.Map[i] = int(ost.Index)
+	}
+	return o, nil
+}
+
+// Dropped returns the indexes of the input streams the muxer cannot hold,
+// which NewOutput left out.
+func (o *Output) Dropped() []int {
+	var dropped []int
+	for i, idx := range o.Map {
+		if idx < 0 {
+			dropped = append(dropped, i)
+		}
+	}
+	return dropped
+}
+
+// AddStream adds a stream copying the parameters of ist.
+func (o *Output) AddStream(ist *libavformat.AVStream) (*libavformat.AVStream, error) {
+	ost := o.Ctx.AvformatNewStream(nil)
+	if ost == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := libavcodec.AvcodecParametersCopy(ost.Codecpar, ist.Codecpar); ret < 0 {
+		return nil, fmt.Errorf("copy codec parameters: %w", libavutil.ErrorFromCode(ret))
+	}
+	// the input tag may be meaningless in the output container
+	ost.Codecpar.CodecTag = 0
+	ost.TimeBase = ist.TimeBase
+	ost.Disposition = ist.Disposition
+	ost.SampleAspectRatio = ist.SampleAspectRatio
+	ost.AvgFrameRate = ist.AvgFrameRate
+	ost.RFrameRate = ist.RFrameRate
+	libavutil.AvDictCopy(&ost.Metadata, ist.Metadata, 0)
+	for i := ffcommon.FInt(0); i < ist.NbSideData; i++ {
+		sd := (*libavcodec.AVPacketSideData)(unsafe.Add(unsafe.Pointer(ist.SideData), uintptr(i)*unsafe.Sizeof(*ist.SideData)))
+		dst := ost.AvStreamNewSideData(sd.Type, sd.Size)
+		if dst == nil {
+			return nil, libavutil.AVError(-libavutil.ENOMEM)
+		}
+		copy(ffcommon.ByteSliceFromByteP(dst, int(sd.Size)), ffcommon.ByteSliceFromByteP(sd.Data, int(sd.Size)))
+	}
+	return ost, nil
+}
+
+// WriteHeader opens the output file if the muxer needs one and writes the
+// header with the given muxer options. Options the muxer did not consume
+// are reported with a *dict.UnusedError, once the header is written.
+func (o *Output) WriteHeader(options map[string]string) error {
+	if o.Ctx.Oformat.Flags&libavformat.AVFMT_NOFILE == 0 {
+		if ret := libavformat.AvioOpen(&o.Ctx.Pb, o.path, libavformat.AVIO_FLAG_WRITE); ret < 0 {
+			return fmt.Errorf("open %s: %w", o.path, libavutil.ErrorFromCode(ret))
+		}
+	}
+	d, err := dict.FromMap(options)
+	if err != nil {
+		return fmt.Errorf("muxer options: %w", err)
+	}
+	ret := o.Ctx.AvformatWriteHeader(&d)
+	unused := dict.Keys(d)
+	libavutil.AvDictFree(&d)
+	if ret < 0 {
+		return fmt.Errorf("write header %s: %w", o.path, libavutil.ErrorFromCode(ret))
+	}
+	o.header = true
+	if err = dict.Check(unused); err != nil {
+		return fmt.Errorf("write header %s: %w", o.path, err)
+	}
+	return nil
+}
+
+// WritePacket writes pkt, read from the input, to the matching output
+// stream. Packets of unmapped streams are dropped. pkt is unreferenced.
+func (o *Output) WritePacket(pkt *libavcodec.AVPacket) error {
+	idx := int(pkt.StreamIndex)
+	if idx >= len(o.Map) || o.Map[idx] < 0 {
+		pkt.AvPacketUnref()
+		return nil
+	}
+	ist := o.in.GetStream(pkt.StreamIndex)
+	ost := o.Ctx.GetStream(ffcommon.FUnsignedInt(o.Map[idx]))
+	pkt.AvPacketRescaleTs(ist.TimeBase, ost.TimeBase)
+	pkt.StreamIndex = ffcommon.FUnsignedInt(o.Map[idx])
+	pkt.Pos = -1
+	if ret := o.Ctx.AvInterleavedWriteFrame(pkt); ret < 0 {
+		return fmt.Errorf("write packet: %w", libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// Copy forwards every packet of the input until its end or until ctx is
+// done.
+func (o *Output) Copy(ctx context.Context) error {
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := libavutil.ErrorFromCode(o.in.AvReadFrame(pkt))
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read packet: %w", err)
+		}
+		if err = o.WritePacket(pkt); err != nil {
+			return err
+		}
+	}
+}
+
+// Finish writes the trailer. The output still has to be closed.
+func (o *Output) Finish() error {
+	if !o.header {
+		return nil
+	}
+	o.header = false
+	if ret := o.Ctx.AvWriteTrailer(); ret < 0 {
+		return fmt.Errorf("write trailer %s: %w", o.path, libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// Close closes the output file and frees the muxer without writing the
+// trailer.
+func (o *Output) Close() {
+	if o.Ctx == nil {
+		return
+	}
+	if o.Ctx.Oformat.Flags&libavformat.AVFMT_NOFILE == 0 && o.Ctx.Pb != nil {
+		libavformat.AvioClosep(&o.Ctx.Pb)
+	}
+	o.Ctx.AvformatFreeContext()
+	o.Ctx = nil
+}
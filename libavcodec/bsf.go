@@ -430,10 +430,10 @@ func (lst *AVBSFList) AvBsfListAppend2(bsf_name ffcommon.FConstCharP, options **
  * @return >=0 on success, negative AVERROR in case of failure
  */
 //int av_bsf_list_finalize(AVBSFList **lst, AVBSFContext **bsf);
-var avBsfListFinalize func(lst **AVBSFList, bsf *AVBSFContext) ffcommon.FInt
+var avBsfListFinalize func(lst **AVBSFList, bsf **AVBSFContext) ffcommon.FInt
 var avBsfListFinalizeOnce sync.Once
 
-func AvBsfListFinalize(lst **AVBSFList, bsf *AVBSFContext) ffcommon.FInt {
+func AvBsfListFinalize(lst **AVBSFList, bsf **AVBSFContext) ffcommon.FInt {
 	avBsfListFinalizeOnce.Do(func() {
 		purego.RegisterLibFunc(&avBsfListFinalize, ffcommon.GetAvcodecDll(), "av_bsf_list_finalize")
 	})
@@ -454,10 +454,10 @@ func AvBsfListFinalize(lst **AVBSFList, bsf *AVBSFContext) ffcommon.FInt {
  * @return >=0 on success, negative AVERROR in case of failure
  */
 //int av_bsf_list_parse_str(const char *str, AVBSFContext **bsf);
-var avBsfListParseStr func(str ffcommon.FConstCharP, bsf *AVBSFContext) ffcommon.FInt
+var avBsfListParseStr func(str ffcommon.FConstCharP, bsf **AVBSFContext) ffcommon.FInt
 var avBsfListParseStrOnce sync.Once
 
-func AvBsfListParseStr(str ffcommon.FConstCharP, bsf *AVBSFContext) ffcommon.FInt {
+func AvBsfListParseStr(str ffcommon.FConstCharP, bsf **AVBSFContext) ffcommon.FInt {
 	avBsfListParseStrOnce.Do(func() {
 		purego.RegisterLibFunc(&avBsfListParseStr, ffcommon.GetAvcodecDll(), "av_bsf_list_parse_str")
 	})
@@ -472,10 +472,10 @@ func AvBsfListParseStr(str ffcommon.FConstCharP, bsf *AVBSFContext) ffcommon.FIn
  * @return
  */
 //int av_bsf_get_null_filter(AVBSFContext **bsf);
-var avBsfGetNullFilter func(bsf *AVBSFContext) ffcommon.FInt
+var avBsfGetNullFilter func(bsf **AVBSFContext) ffcommon.FInt
 var avBsfGetNullFilterOnce sync.Once
 
-func AvBsfGetNullFilter(bsf *AVBSFContext) ffcommon.FInt {
+func AvBsfGetNullFilter(bsf **AVBSFContext) ffcommon.FInt {
 	avBsfGetNullFilterOnce.Do(func() {
 		purego.RegisterLibFunc(&avBsfGetNullFilter, ffcommon.GetAvcodecDll(), "av_bsf_get_null_filter")
 	})
@@ -715,10 +715,10 @@ func (pkt *AVPacket) AvFreePacket() {
 //#else
 //size_t size);
 //#endif
-var avPacketNewSideData func(pkt *AVPacket, type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) ffcommon.FInt
+var avPacketNewSideData func(pkt *AVPacket, type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) *ffcommon.FUint8T
 var avPacketNewSideDataOnce sync.Once
 
-func (pkt *AVPacket) AvPacketNewSideData(type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) ffcommon.FInt {
+func (pkt *AVPacket) AvPacketNewSideData(type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) *ffcommon.FUint8T {
 	avPacketNewSideDataOnce.Do(func() {
 		purego.RegisterLibFunc(&avPacketNewSideData, ffcommon.GetAvcodecDll(), "av_packet_new_side_data")
 	})
@@ -764,14 +764,14 @@ func (pkt *AVPacket) AvPacketAddSideData(type0 AVPacketSideDataType, data *ffcom
 //#else
 //size_t size);
 //#endif
-var avPacketShrinkSideData func(pkt *AVPacket, type0 AVPacketSideDataType, data *ffcommon.FUint8T, size ffcommon.FIntOrSizeT) ffcommon.FInt
+var avPacketShrinkSideData func(pkt *AVPacket, type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) ffcommon.FInt
 var avPacketShrinkSideDataOnce sync.Once
 
-func (pkt *AVPacket) AvPacketShrinkSideData(type0 AVPacketSideDataType, data *ffcommon.FUint8T, size ffcommon.FIntOrSizeT) ffcommon.FInt {
+func (pkt *AVPacket) AvPacketShrinkSideData(type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) ffcommon.FInt {
 	avPacketShrinkSideDataOnce.Do(func() {
 		purego.RegisterLibFunc(&avPacketShrinkSideData, ffcommon.GetAvcodecDll(), "av_packet_shrink_side_data")
 	})
-	return avPacketShrinkSideData(pkt, type0, data, size)
+	return avPacketShrinkSideData(pkt, type0, size)
 }
 
 /**
@@ -788,14 +788,14 @@ func (pkt *AVPacket) AvPacketShrinkSideData(type0 AVPacketSideDataType, data *ff
 //int *size);
 //#else
 //size_t *size);
-var avPacketGetSideData func(pkt *AVPacket, type0 AVPacketSideDataType, data *ffcommon.FUint8T, size *ffcommon.FIntOrSizeT) ffcommon.FInt
+var avPacketGetSideData func(pkt *AVPacket, type0 AVPacketSideDataType, size *ffcommon.FIntOrSizeT) *ffcommon.FUint8T
 var avPacketGetSideDataOnce sync.Once
 
-func (pkt *AVPacket) AvPacketGetSideData(type0 AVPacketSideDataType, data *ffcommon.FUint8T, size *ffcommon.FIntOrSizeT) ffcommon.FInt {
+func (pkt *AVPacket) AvPacketGetSideData(type0 AVPacketSideDataType, size *ffcommon.FIntOrSizeT) *ffcommon.FUint8T {
 	avPacketGetSideDataOnce.Do(func() {
 		purego.RegisterLibFunc(&avPacketGetSideData, ffcommon.GetAvcodecDll(), "av_packet_get_side_data")
 	})
-	return avPacketGetSideData(pkt, type0, data, size)
+	return avPacketGetSideData(pkt, type0, size)
 }
 
 //#endif
@@ -1055,8 +1055,8 @@ func (pkt *AVPacket) AvPacketRescaleTs(tb_src, tb_dst AVRational) {
 	})
 	avPacketRescaleTs(
 		uintptr(unsafe.Pointer(pkt)),
-		uintptr(unsafe.Pointer(&tb_src)),
-		uintptr(unsafe.Pointer(&tb_dst)),
+		*(*uintptr)(unsafe.Pointer(&tb_src)), // AVRational is passed by value in a single register
+		*(*uintptr)(unsafe.Pointer(&tb_dst)),
 	)
 }
 
@@ -890,10 +890,10 @@ const AVFILTER_CMD_FLAG_FAST = 2 ///< Only execute command when its fast (like a
  * It is recommended to use avfilter_graph_send_command().
  */
 //int avfilter_process_command(AVFilterContext *filter, const char *cmd, const char *arg, char *res, int res_len, int flags);
-var avfilterProcessCommand func(filter *AVFilterContext, cmd, arg, res0 ffcommon.FConstCharP, res_len, flags ffcommon.FInt) ffcommon.FInt
+var avfilterProcessCommand func(filter *AVFilterContext, cmd, arg ffcommon.FConstCharP, res0 ffcommon.FBuf, res_len, flags ffcommon.FInt) ffcommon.FInt
 var avfilterProcessCommandOnce sync.Once
 
-func (filter *AVFilterContext) AvfilterProcessCommand(cmd, arg, res0 ffcommon.FConstCharP, res_len, flags ffcommon.FInt) ffcommon.FInt {
+func (filter *AVFilterContext) AvfilterProcessCommand(cmd, arg ffcommon.FConstCharP, res0 ffcommon.FBuf, res_len, flags ffcommon.FInt) ffcommon.FInt {
 	avfilterProcessCommandOnce.Do(func() {
 		purego.RegisterLibFunc(&avfilterProcessCommand, ffcommon.GetAvfilterDll(), "avfilter_process_command")
 	})
@@ -1574,10 +1574,10 @@ func (graph *AVFilterGraph) AvfilterGraphParse2(filters ffcommon.FConstCharP, in
  *              AVERROR(ENOSYS) on unsupported commands
  */
 //int avfilter_graph_send_command(AVFilterGraph *graph, const char *target, const char *cmd, const char *arg, char *res, int res_len, int flags);
-var avfilterGraphSendCommand func(graph *AVFilterGraph, target, cmd, arg, res0 ffcommon.FConstCharP, res_len, flags ffcommon.FInt) ffcommon.FInt
+var avfilterGraphSendCommand func(graph *AVFilterGraph, target, cmd, arg ffcommon.FConstCharP, res0 ffcommon.FBuf, res_len, flags ffcommon.FInt) ffcommon.FInt
 var avfilterGraphSendCommandOnce sync.Once
 
-func (graph *AVFilterGraph) AvfilterGraphSendCommand(target, cmd, arg, res0 ffcommon.FConstCharP, res_len, flags ffcommon.FInt) ffcommon.FInt {
+func (graph *AVFilterGraph) AvfilterGraphSendCommand(target, cmd, arg ffcommon.FConstCharP, res0 ffcommon.FBuf, res_len, flags ffcommon.FInt) ffcommon.FInt {
 	avfilterGraphSendCommandOnce.Do(func() {
 		purego.RegisterLibFunc(&avfilterGraphSendCommand, ffcommon.GetAvfilterDll(), "avfilter_graph_send_command")
 	})
@@ -2,6 +2,7 @@ package libavfilter
 
 import (
 	"sync"
+	"unsafe"
 
 	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
 	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
@@ -212,14 +213,16 @@ func (ctx *AVFilterContext) AvBuffersinkGetType() AVMediaType {
 }
 
 // AVRational       av_buffersink_get_time_base           (const AVFilterContext *ctx);
-var avBuffersinkGetTimeBase func(ctx *AVFilterContext) AVRational
+var avBuffersinkGetTimeBase func(ctx *AVFilterContext) ffcommon.FUint64T
 var avBuffersinkGetTimeBaseOnce sync.Once
 
-func (ctx *AVFilterContext) AvBuffersinkGetTimeBase() AVRational {
+func (ctx *AVFilterContext) AvBuffersinkGetTimeBase() (res AVRational) {
 	avBuffersinkGetTimeBaseOnce.Do(func() {
 		purego.RegisterLibFunc(&avBuffersinkGetTimeBase, ffcommon.GetAvfilterDll(), "av_buffersink_get_time_base")
 	})
-	return avBuffersinkGetTimeBase(ctx)
+	t := avBuffersinkGetTimeBase(ctx)
+	res = *(*AVRational)(unsafe.Pointer(&t)) // AVRational is returned in a single register
+	return
 }
 
 // int              av_buffersink_get_format              (const AVFilterContext *ctx);
@@ -234,14 +237,16 @@ func (ctx *AVFilterContext) AvBuffersinkGetFormat() ffcommon.FInt {
 }
 
 // AVRational       av_buffersink_get_frame_rate          (const AVFilterContext *ctx);
-var avBuffersinkGetFrameRate func(ctx *AVFilterContext) AVRational
+var avBuffersinkGetFrameRate func(ctx *AVFilterContext) ffcommon.FUint64T
 var avBuffersinkGetFrameRateOnce sync.Once
 
-func (ctx *AVFilterContext) AvBuffersinkGetFrameRate() AVRational {
+func (ctx *AVFilterContext) AvBuffersinkGetFrameRate() (res AVRational) {
 	avBuffersinkGetFrameRateOnce.Do(func() {
 		purego.RegisterLibFunc(&avBuffersinkGetFrameRate, ffcommon.GetAvfilterDll(), "av_buffersink_get_frame_rate")
 	})
-	return avBuffersinkGetFrameRate(ctx)
+	t := avBuffersinkGetFrameRate(ctx)
+	res = *(*AVRational)(unsafe.Pointer(&t)) // AVRational is returned in a single register
+	return
 }
 
 // int              av_buffersink_get_w                   (const AVFilterContext *ctx);
@@ -267,14 +272,16 @@ func (ctx *AVFilterContext) AvBuffersinkGetH() ffcommon.FInt {
 }
 
 // AVRational       av_buffersink_get_sample_aspect_ratio (const AVFilterContext *ctx);
-var avBuffersinkGetSampleAspectRatio func(ctx *AVFilterContext) AVRational
+var avBuffersinkGetSampleAspectRatio func(ctx *AVFilterContext) ffcommon.FUint64T
 var avBuffersinkGetSampleAspectRatioOnce sync.Once
 
-func (ctx *AVFilterContext) AvBuffersinkGetSampleAspectRatio() AVRational {
+func (ctx *AVFilterContext) AvBuffersinkGetSampleAspectRatio() (res AVRational) {
 	avBuffersinkGetSampleAspectRatioOnce.Do(func() {
 		purego.RegisterLibFunc(&avBuffersinkGetSampleAspectRatio, ffcommon.GetAvfilterDll(), "av_buffersink_get_sample_aspect_ratio")
 	})
-	return avBuffersinkGetSampleAspectRatio(ctx)
+	t := avBuffersinkGetSampleAspectRatio(ctx)
+	res = *(*AVRational)(unsafe.Pointer(&t)) // AVRational is returned in a single register
+	return
 }
 
 // int              av_buffersink_get_channels            (const AVFilterContext *ctx);
@@ -272,8 +272,8 @@ func (ctx *AVFilterContext) AvBuffersrcAddFrameFlags(frame *AVFrame, flags ffcom
  */
 //int av_buffersrc_close(AVFilterContext *ctx, int64_t pts, unsigned flags);
 
-func (ctx *AVFilterContext) AvBuffersrcClose(pts ffcommon.FInt, flags ffcommon.FUnsigned) ffcommon.FInt {
-	var avBuffersrcClose func(*AVFilterContext, ffcommon.FInt, ffcommon.FUnsigned) ffcommon.FInt
+func (ctx *AVFilterContext) AvBuffersrcClose(pts ffcommon.FInt64T, flags ffcommon.FUnsigned) ffcommon.FInt {
+	var avBuffersrcClose func(*AVFilterContext, ffcommon.FInt64T, ffcommon.FUnsigned) ffcommon.FInt
 	var avBuffersrcCloseOnce sync.Once
 
 	avBuffersrcCloseOnce.Do(func() {
@@ -2608,10 +2608,10 @@ func (s *AVFormatContext) AvformatNewStream(c *AVCodec) (res *AVStream) {
 //uint8_t *data, size_t size);
 type AVPacketSideDataType = libavcodec.AVPacketSideDataType
 
-var av_stream_add_side_data func(st *AVStream, type0 AVPacketSideDataType, data *ffcommon.FUint8T, size ffcommon.FSizeT) ffcommon.FCharP
+var av_stream_add_side_data func(st *AVStream, type0 AVPacketSideDataType, data *ffcommon.FUint8T, size ffcommon.FSizeT) ffcommon.FInt
 var av_stream_add_side_data_once sync.Once
 
-func (st *AVStream) AvStreamAddSideData(type0 AVPacketSideDataType, data *ffcommon.FUint8T, size ffcommon.FSizeT) (res ffcommon.FCharP) {
+func (st *AVStream) AvStreamAddSideData(type0 AVPacketSideDataType, data *ffcommon.FUint8T, size ffcommon.FSizeT) (res ffcommon.FInt) {
 	av_stream_add_side_data_once.Do(func() {
 		purego.RegisterLibFunc(&av_stream_add_side_data, ffcommon.GetAvformatDll(), "av_stream_add_side_data")
 	})
@@ -2658,10 +2658,10 @@ func (stream *AVStream) AvStreamNewSideData(type0 AVPacketSideDataType, size ffc
 //#else
 //enum AVPacketSideDataType type, size_t *size);
 //#endif
-var av_stream_get_side_data func(stream *AVStream, type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) *ffcommon.FUint8T
+var av_stream_get_side_data func(stream *AVStream, type0 AVPacketSideDataType, size *ffcommon.FIntOrSizeT) *ffcommon.FUint8T
 var av_stream_get_side_data_once sync.Once
 
-func (stream *AVStream) AvStreamGetSideData(type0 AVPacketSideDataType, size ffcommon.FIntOrSizeT) (res *ffcommon.FUint8T) {
+func (stream *AVStream) AvStreamGetSideData(type0 AVPacketSideDataType, size *ffcommon.FIntOrSizeT) (res *ffcommon.FUint8T) {
 	av_stream_get_side_data_once.Do(func() {
 		purego.RegisterLibFunc(&av_stream_get_side_data, ffcommon.GetAvformatDll(), "av_stream_get_side_data")
 	})
@@ -1,6 +1,7 @@
 package libavutil
 
 import (
+	"io"
 	"sync"
 	"unsafe"
 
@@ -208,6 +209,37 @@ func AvErr2str(errnum ffcommon.FInt) (res ffcommon.FCharP) {
 	return
 }
 
+/**
+ * AVError is a negative AVERROR code usable as a Go error.
+ */
+type AVError ffcommon.FInt
+
+func (e AVError) Error() string {
+	return AvErr2str(ffcommon.FInt(e))
+}
+
+/**
+ * ErrEAGAIN is returned when output is not available in the current state
+ * and more input must be sent first.
+ */
+var ErrEAGAIN error = AVError(-EAGAIN)
+
+/**
+ * ErrorFromCode converts the return value of an FFmpeg call to a Go error.
+ *
+ * Non-negative values yield nil, AVERROR_EOF yields io.EOF and any other
+ * negative value is returned as an AVError.
+ */
+func ErrorFromCode(ret ffcommon.FInt) error {
+	switch {
+	case ret >= 0:
+		return nil
+	case ret == AVERROR_EOF:
+		return io.EOF
+	}
+	return AVError(ret)
+}
+
 /**
  * @}
  */
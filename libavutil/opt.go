@@ -1,7 +1,9 @@
 package libavutil
 
 import (
+	"math"
 	"sync"
+	"unsafe"
 
 	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
 	"github.com/ebitengine/purego"
@@ -315,6 +317,28 @@ type AVOption struct {
 	Unit ffcommon.FCharPStruct
 }
 
+const (
+	AV_OPT_FLAG_ENCODING_PARAM = 1 ///< a generic parameter which can be set by the user for muxing or encoding
+	AV_OPT_FLAG_DECODING_PARAM = 2 ///< a generic parameter which can be set by the user for demuxing or decoding
+	AV_OPT_FLAG_AUDIO_PARAM    = 8
+	AV_OPT_FLAG_VIDEO_PARAM    = 16
+	AV_OPT_FLAG_SUBTITLE_PARAM = 32
+	/**
+	 * The option is intended for exporting values to the caller.
+	 */
+	AV_OPT_FLAG_EXPORT = 64
+	/**
+	 * The option may not be set through the AVOptions API, only read.
+	 * This flag only makes sense when AV_OPT_FLAG_EXPORT is also set.
+	 */
+	AV_OPT_FLAG_READONLY        = 128
+	AV_OPT_FLAG_BSF_PARAM       = (1 << 8)  ///< a generic parameter which can be set by the user for bit stream filtering
+	AV_OPT_FLAG_RUNTIME_PARAM   = (1 << 15) ///< a generic parameter which can be set by the user at runtime
+	AV_OPT_FLAG_FILTERING_PARAM = (1 << 16) ///< a generic parameter which can be set by the user for filtering
+	AV_OPT_FLAG_DEPRECATED      = (1 << 17) ///< set if option is deprecated, users should refer to AVOption.help text for more information
+	AV_OPT_FLAG_CHILD_CONSTS    = (1 << 18) ///< set if option constants can also reside in child objects
+)
+
 /**
  * A single allowed range of values, or a single allowed value.
  */
@@ -773,7 +797,7 @@ const AV_OPT_MULTI_COMPONENT_RANGE = (1 << 12)
  */
 //const AVOption *av_opt_find(void *obj, const char *name, const char *unit,
 //int opt_flags, int search_flags);
-var avOptFind func(obj ffcommon.FVoidP, name, unit ffcommon.FConstCharP,
+var avOptFind func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, unit *byte,
 	opt_flags, search_flags ffcommon.FInt) *AVOption
 var avOptFindOnce sync.Once
 
@@ -782,7 +806,12 @@ func AvOptFind(obj ffcommon.FVoidP, name, unit ffcommon.FConstCharP,
 	avOptFindOnce.Do(func() {
 		purego.RegisterLibFunc(&avOptFind, ffcommon.GetAvutilDll(), "av_opt_find")
 	})
-	return avOptFind(obj, name, unit, opt_flags, search_flags)
+	// an empty unit is passed as NULL, which searches options rather than named constants
+	var unitp *byte
+	if unit != "" {
+		unitp = ffcommon.BytePtrFromString(unit)
+	}
+	return avOptFind(obj, name, unitp, opt_flags, search_flags)
 }
 
 /**
@@ -808,7 +837,7 @@ func AvOptFind(obj ffcommon.FVoidP, name, unit ffcommon.FConstCharP,
  */
 //const AVOption *av_opt_find2(void *obj, const char *name, const char *unit,
 //int opt_flags, int search_flags, void **target_obj);
-var avOptFind2 func(obj ffcommon.FVoidP, name, unit ffcommon.FConstCharP,
+var avOptFind2 func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, unit *byte,
 	opt_flags, search_flags ffcommon.FInt, target_obj *ffcommon.FVoidP) *AVOption
 var avOptFind2Once sync.Once
 
@@ -817,7 +846,12 @@ func AvOptFind2(obj ffcommon.FVoidP, name, unit ffcommon.FConstCharP,
 	avOptFind2Once.Do(func() {
 		purego.RegisterLibFunc(&avOptFind2, ffcommon.GetAvutilDll(), "av_opt_find2")
 	})
-	return avOptFind2(obj, name, unit, opt_flags, search_flags, target_obj)
+	// an empty unit is passed as NULL, which searches options rather than named constants
+	var unitp *byte
+	if unit != "" {
+		unitp = ffcommon.BytePtrFromString(unit)
+	}
+	return avOptFind2(obj, name, unitp, opt_flags, search_flags, target_obj)
 }
 
 /**
@@ -847,10 +881,10 @@ func AvOptNext(obj ffcommon.FVoidP, prev *AVOption) *AVOption {
  * @return next AVOptions-enabled child or NULL
  */
 //void *av_opt_child_next(void *obj, void *prev);
-var avOptChildNext func(obj ffcommon.FVoidP, prev *AVOption) ffcommon.FVoidP
+var avOptChildNext func(obj ffcommon.FVoidP, prev ffcommon.FVoidP) ffcommon.FVoidP
 var avOptChildNextOnce sync.Once
 
-func AvOptChildNext(obj ffcommon.FVoidP, prev *AVOption) ffcommon.FVoidP {
+func AvOptChildNext(obj ffcommon.FVoidP, prev ffcommon.FVoidP) ffcommon.FVoidP {
 	avOptChildNextOnce.Do(func() {
 		purego.RegisterLibFunc(&avOptChildNext, ffcommon.GetAvutilDll(), "av_opt_child_next")
 	})
@@ -960,14 +994,14 @@ func AvOptSetDouble(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val ffcommon
 }
 
 // int av_opt_set_q       (void *obj, const char *name, AVRational  val, int search_flags);
-var avOptSetQ func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val AVRational, searchFlags ffcommon.FInt) ffcommon.FInt
+var avOptSetQ func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val uintptr, searchFlags ffcommon.FInt) ffcommon.FInt
 var avOptSetQOnce sync.Once
 
 func AvOptSetQ(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val AVRational, searchFlags ffcommon.FInt) ffcommon.FInt {
 	avOptSetQOnce.Do(func() {
 		purego.RegisterLibFunc(&avOptSetQ, ffcommon.GetAvutilDll(), "av_opt_set_q")
 	})
-	return avOptSetQ(obj, name, val, searchFlags)
+	return avOptSetQ(obj, name, *(*uintptr)(unsafe.Pointer(&val)), searchFlags) // AVRational is passed in a single register
 }
 
 // int av_opt_set_bin     (void *obj, const char *name, const uint8_t *val, int size, int search_flags);
@@ -1015,14 +1049,14 @@ func AvOptSetSampleFmt(obj ffcommon.FVoidP, name ffcommon.FConstCharP, fmt0 AVSa
 }
 
 // int av_opt_set_video_rate(void *obj, const char *name, AVRational val, int search_flags);
-var avOptSetVideoRate func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val AVRational, searchFlags ffcommon.FInt) ffcommon.FInt
+var avOptSetVideoRate func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val uintptr, searchFlags ffcommon.FInt) ffcommon.FInt
 var avOptSetVideoRateOnce sync.Once
 
 func AvOptSetVideoRate(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val AVRational, searchFlags ffcommon.FInt) ffcommon.FInt {
 	avOptSetVideoRateOnce.Do(func() {
 		purego.RegisterLibFunc(&avOptSetVideoRate, ffcommon.GetAvutilDll(), "av_opt_set_video_rate")
 	})
-	return avOptSetVideoRate(obj, name, val, searchFlags)
+	return avOptSetVideoRate(obj, name, *(*uintptr)(unsafe.Pointer(&val)), searchFlags) // AVRational is passed in a single register
 }
 
 // int av_opt_set_channel_layout(void *obj, const char *name, int64_t ch_layout, int search_flags);
@@ -1066,15 +1100,13 @@ func AvOptSetDictVal(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val *AVDict
 //AVERROR(EINVAL) : \
 //av_opt_set_bin(obj, name, (const uint8_t *)(val), \
 //av_int_list_length(val, term) * sizeof(*(val)), flags))
-//todo可能有问题，暂时别用
-var avOptSetIntList func(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val uintptr, size ffcommon.FInt, term ffcommon.FUint64T, flags ffcommon.FInt) ffcommon.FInt
-var avOptSetIntListOnce sync.Once
-
+// av_opt_set_int_list is a macro, size is sizeof(*(val))
 func AvOptSetIntList(obj ffcommon.FVoidP, name ffcommon.FConstCharP, val uintptr, size ffcommon.FInt, term ffcommon.FUint64T, flags ffcommon.FInt) ffcommon.FInt {
-	avOptSetIntListOnce.Do(func() {
-		purego.RegisterLibFunc(&avOptSetIntList, ffcommon.GetAvutilDll(), "av_opt_set_int_list")
-	})
-	return avOptSetIntList(obj, name, val, size, term, flags)
+	length := AvIntListLengthForSize(ffcommon.FUnsigned(size), val, term)
+	if uint64(length) > uint64(math.MaxInt32/size) {
+		return -EINVAL
+	}
+	return AvOptSetBin(obj, name, *(**ffcommon.FUint8T)(unsafe.Pointer(&val)), ffcommon.FInt(length)*size, flags)
 }
 
 /**
@@ -317,14 +317,21 @@ type AVSampleFormat = libavutil.AVSampleFormat
 var swrAllocSetOpts func(s *SwrContext, outChLayout ffcommon.FInt64T, outSampleFmt AVSampleFormat, outSampleRate ffcommon.FInt, inChLayout ffcommon.FInt64T, inSampleFmt AVSampleFormat, inSampleRate, logOffset ffcommon.FInt, logCtx ffcommon.FVoidP) *SwrContext
 var swrAllocSetOptsOnce sync.Once
 
-// SwrAllocSetOpts is a purego method to allocate a SwrContext with options.
-func (s *SwrContext) SwrAllocSetOpts(outChLayout ffcommon.FInt64T, outSampleFmt AVSampleFormat, outSampleRate ffcommon.FInt, inChLayout ffcommon.FInt64T, inSampleFmt AVSampleFormat, inSampleRate, logOffset ffcommon.FInt, logCtx ffcommon.FVoidP) *SwrContext {
+// SwrAllocSetOpts is a purego function to allocate a SwrContext with options, s may be nil.
+func SwrAllocSetOpts(s *SwrContext, outChLayout ffcommon.FInt64T, outSampleFmt AVSampleFormat, outSampleRate ffcommon.FInt, inChLayout ffcommon.FInt64T, inSampleFmt AVSampleFormat, inSampleRate, logOffset ffcommon.FInt, logCtx ffcommon.FVoidP) *SwrContext {
 	swrAllocSetOptsOnce.Do(func() {
 		purego.RegisterLibFunc(&swrAllocSetOpts, ffcommon.GetAvswresampleDll(), "swr_alloc_set_opts")
 	})
 	return swrAllocSetOpts(s, outChLayout, outSampleFmt, outSampleRate, inChLayout, inSampleFmt, inSampleRate, logOffset, logCtx)
 }
 
+// SwrAllocSetOpts is a purego method to allocate a SwrContext with options.
+//
+// Deprecated: s may be nil here, use the SwrAllocSetOpts function instead.
+func (s *SwrContext) SwrAllocSetOpts(outChLayout ffcommon.FInt64T, outSampleFmt AVSampleFormat, outSampleRate ffcommon.FInt, inChLayout ffcommon.FInt64T, inSampleFmt AVSampleFormat, inSampleRate, logOffset ffcommon.FInt, logCtx ffcommon.FVoidP) *SwrContext {
+	return SwrAllocSetOpts(s, outChLayout, outSampleFmt, outSampleRate, inChLayout, inSampleFmt, inSampleRate, logOffset, logCtx)
+}
+
 /**
  * @}
  *
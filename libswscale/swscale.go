@@ -406,10 +406,10 @@ func (c *SwsContext) SwsScale(srcSlice **ffcommon.FUint8T, srcStride *ffcommon.F
 //int sws_setColorspaceDetails(struct SwsContext *c, const int inv_table[4],
 //int srcRange, const int table[4], int dstRange,
 //int brightness, int contrast, int saturation);
-var swsSetColorspaceDetails func(c *SwsContext, inv_table [4]*ffcommon.FInt, srcRange ffcommon.FInt, table [4]ffcommon.FInt, dstRange, brightness, contrast, saturation ffcommon.FInt) ffcommon.FInt
+var swsSetColorspaceDetails func(c *SwsContext, inv_table *ffcommon.FInt, srcRange ffcommon.FInt, table *ffcommon.FInt, dstRange, brightness, contrast, saturation ffcommon.FInt) ffcommon.FInt
 var swsSetColorspaceDetailsOnce sync.Once
 
-func (c *SwsContext) SwsSetColorspaceDetails(inv_table [4]*ffcommon.FInt, srcRange ffcommon.FInt, table [4]ffcommon.FInt, dstRange, brightness, contrast, saturation ffcommon.FInt) ffcommon.FInt {
+func (c *SwsContext) SwsSetColorspaceDetails(inv_table *ffcommon.FInt, srcRange ffcommon.FInt, table *ffcommon.FInt, dstRange, brightness, contrast, saturation ffcommon.FInt) ffcommon.FInt {
 	swsSetColorspaceDetailsOnce.Do(func() {
 		purego.RegisterLibFunc(&swsSetColorspaceDetails, ffcommon.GetAvswscaleDll(), "sws_setColorspaceDetails")
 	})
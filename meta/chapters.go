@@ -0,0 +1,97 @@
+package meta
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/dict"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Chapter is a chapter mark of a file.
+type Chapter struct {
+	ID         int64
+	Start, End time.Duration
+	Title      string
+	// Metadata holds the chapter tags other than the title.
+	Metadata Dict
+}
+
+// chapterTimeBase is the time base of the chapters written by SetChapters,
+// which keeps time.Duration values exact.
+var chapterTimeBase = libavutil.AVRational{Num: 1, Den: int32(time.Second)}
+
+func chapterAt(ctx *libavformat.AVFormatContext, i ffcommon.FUnsignedInt) *libavformat.AVChapter {
+	p := unsafe.Add(unsafe.Pointer(ctx.Chapters), uintptr(i)*unsafe.Sizeof(*ctx.Chapters))
+	return *(**libavformat.AVChapter)(p)
+}
+
+// Chapters returns the chapters of an opened input.
+func Chapters(ctx *libavformat.AVFormatContext) []Chapter {
+	var chapters []Chapter
+	for i := ffcommon.FUnsignedInt(0); i < ctx.NbChapters; i++ {
+		ch := chapterAt(ctx, i)
+		c := Chapter{
+			ID:       int64(ch.Id),
+			Start:    time.Duration(libavutil.AvRescaleQ(ch.Start, ch.TimeBase, chapterTimeBase)),
+			End:      time.Duration(libavutil.AvRescaleQ(ch.End, ch.TimeBase, chapterTimeBase)),
+			Metadata: Dict(dict.Entries(ch.Metadata)),
+		}
+		c.Title, _ = c.Metadata.Get("title")
+		c.Metadata.Delete("title")
+		chapters = append(chapters, c)
+	}
+	return chapters
+}
+
+// SetChapters replaces the chapters of an output context. Call it before
+// avformat_write_header; the chapters are freed with the context. Chapters
+// with a zero ID are numbered from 1 in order.
+func SetChapters(ctx *libavformat.AVFormatContext, chapters []Chapter) error {
+	freeChapters(ctx)
+	for i, c := range chapters {
+		if c.End < c.Start {
+			return fmt.Errorf("meta: chapter %d ends before it starts", i)
+		}
+		mem := libavutil.AvMallocz(ffcommon.FSizeT(unsafe.Sizeof(libavformat.AVChapter{})))
+		ch := *(**libavformat.AVChapter)(unsafe.Pointer(&mem))
+		if ch == nil {
+			return libavutil.AVError(-libavutil.ENOMEM)
+		}
+		ch.Id = ffcommon.FIntOrInt64(c.ID)
+		if c.ID == 0 {
+			ch.Id = ffcommon.FIntOrInt64(i + 1)
+		}
+		ch.TimeBase = chapterTimeBase
+		ch.Start = ffcommon.FInt64T(c.Start)
+		ch.End = ffcommon.FInt64T(c.End)
+		tags := append(Dict(nil), c.Metadata...)
+		if c.Title != "" {
+			tags.Set("title", c.Title)
+		}
+		if err := replace(&ch.Metadata, tags); err != nil {
+			libavutil.AvFree(uintptr(unsafe.Pointer(ch)))
+			return err
+		}
+		if ret := libavutil.AvDynarrayAddNofree(uintptr(unsafe.Pointer(&ctx.Chapters)), uintptr(unsafe.Pointer(&ctx.NbChapters)), uintptr(unsafe.Pointer(ch))); ret < 0 {
+			libavutil.AvDictFree(&ch.Metadata)
+			libavutil.AvFree(uintptr(unsafe.Pointer(ch)))
+			return libavutil.ErrorFromCode(ret)
+		}
+	}
+	return nil
+}
+
+// freeChapters releases the chapters the way avformat_free_context does.
+func freeChapters(ctx *libavformat.AVFormatContext) {
+	for i := ffcommon.FUnsignedInt(0); i < ctx.NbChapters; i++ {
+		ch := chapterAt(ctx, i)
+		libavutil.AvDictFree(&ch.Metadata)
+		libavutil.AvFree(uintptr(unsafe.Pointer(ch)))
+	}
+	libavutil.AvFreep(uintptr(unsafe.Pointer(&ctx.Chapters)))
+	ctx.NbChapters = 0
+}
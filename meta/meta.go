@@ -0,0 +1,215 @@
+// Package meta reads and rewrites container and stream metadata without
+// re-encoding.
+package meta
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dwdcth/ffmpeg-go/v7/dict"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Dict is an ordered list of tags. Keys compare case-insensitively, like
+// AVDictionary keys.
+type Dict []dict.Entry
+
+// Get returns the value of key.
+func (d Dict) Get(key string) (string, bool) {
+	for _, e := range d {
+		if strings.EqualFold(e.Key, key) {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the value of key, or appends it.
+func (d *Dict) Set(key, value string) {
+	for i, e := range *d {
+		if strings.EqualFold(e.Key, key) {
+			(*d)[i].Value = value
+			return
+		}
+	}
+	*d = append(*d, dict.Entry{Key: key, Value: value})
+}
+
+// Delete removes key.
+func (d *Dict) Delete(key string) {
+	out := (*d)[:0]
+	for _, e := range *d {
+		if !strings.EqualFold(e.Key, key) {
+			out = append(out, e)
+		}
+	}
+	*d = out
+}
+
+// Tags holds the metadata of a file.
+type Tags struct {
+	// Format holds the container tags such as title, artist or
+	// creation_time.
+	Format Dict
+	// Streams holds the tags of every input stream, e.g. language or
+	// handler_name, indexed like the input streams.
+	Streams []Dict
+	// Chapters are written to formats that support them, e.g. MP4 and
+	// Matroska.
+	Chapters []Chapter
+}
+
+// Read returns the tags of an opened input.
+func Read(in *libavformat.AVFormatContext) *Tags {
+	t := &Tags{Format: Dict(dict.Entries(in.Metadata)), Chapters: Chapters(in)}
+	for i := ffcommon.FUnsignedInt(0); i < in.NbStreams; i++ {
+		t.Streams = append(t.Streams, Dict(dict.Entries(in.GetStream(i).Metadata)))
+	}
+	return t
+}
+
+// Options tune how Edit writes the output.
+type Options struct {
+	// Format forces the output format instead of guessing it from the file
+	// name.
+	Format string
+	// ID3v2Version selects ID3v2.3 or ID3v2.4 tags for MP3 output. Zero
+	// keeps the muxer default (4). Use 3 for older players.
+	ID3v2Version int
+	// WriteID3v1 adds an ID3v1 tag to MP3 output as well.
+	WriteID3v1 bool
+	// MuxerOptions are passed to avformat_write_header. Options the muxer
+	// does not know fail the edit.
+	MuxerOptions map[string]string
+	// DropUnsupported leaves out the streams the output format cannot
+	// hold instead of failing.
+	DropUnsupported bool
+}
+
+// Edit copies in to out, letting edit change the tags in between. All
+// streams are copied with their dispositions and side data, and Edit fails
+// if the output format cannot hold one of them; deleting a tag from Tags
+// removes it from the output.
+func Edit(in, out string, edit func(*Tags)) error {
+	return EditWith(in, out, Options{}, edit)
+}
+
+// EditWith is Edit with options.
+func EditWith(in, out string, opts Options, edit func(*Tags)) error {
+	ictx, err := remux.OpenInput(in)
+	if err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ictx)
+
+	tags := Read(ictx)
+	if edit != nil {
+		edit(tags)
+	}
+
+	o, err := remux.NewOutput(ictx, out, opts.Format)
+	if err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	defer o.Close()
+	if dropped := o.Dropped(); len(dropped) > 0 && !opts.DropUnsupported {
+		return fmt.Errorf("meta: %s cannot hold streams %v of %s", out, dropped, in)
+	}
+
+	if err = Write(o.Ctx, tags, o.Map); err != nil {
+		return err
+	}
+	muxOpts := muxerOptions(ffcommon.GoString(o.Ctx.Oformat.Name), tags, opts)
+	if err = o.WriteHeader(muxOpts); err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	if err = o.Copy(context.Background()); err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	if err = o.Finish(); err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	return nil
+}
+
+// Write replaces the metadata and chapters of an output context before its
+// header is written. streamMap gives the output index of every entry of
+// t.Streams, or -1 to skip it; nil maps them one to one.
+func Write(octx *libavformat.AVFormatContext, t *Tags, streamMap []int) error {
+	if err := replace(&octx.Metadata, t.Format); err != nil {
+		return err
+	}
+	for i, tags := range t.Streams {
+		idx := i
+		if streamMap != nil {
+			if i >= len(streamMap) {
+				break
+			}
+			idx = streamMap[i]
+		}
+		if idx < 0 || idx >= int(octx.NbStreams) {
+			continue
+		}
+		if err := replace(&octx.GetStream(ffcommon.FUnsignedInt(idx)).Metadata, tags); err != nil {
+			return err
+		}
+	}
+	return SetChapters(octx, t.Chapters)
+}
+
+func replace(m **libavutil.AVDictionary, tags Dict) error {
+	d, err := dict.FromEntries(tags)
+	if err != nil {
+		return fmt.Errorf("meta: %w", err)
+	}
+	libavutil.AvDictFree(m)
+	*m = d
+	return nil
+}
+
+// itunesKeys are the tags the mov muxer maps to iTunes-style udta atoms.
+// Any other key is only written with movflags=use_metadata_tags.
+var itunesKeys = map[string]bool{
+	"title": true, "artist": true, "author": true, "album_artist": true, "album": true,
+	"composer": true, "date": true, "comment": true, "genre": true, "copyright": true,
+	"grouping": true, "lyrics": true, "description": true, "synopsis": true, "show": true,
+	"episode_id": true, "network": true, "encoder": true, "track": true, "disc": true,
+	"compilation": true, "gapless_playback": true, "hd_video": true, "media_type": true,
+	"episode_sort": true, "season_number": true, "keywords": true, "location": true,
+	"creation_time": true, "major_brand": true, "minor_version": true, "compatible_brands": true,
+	"sort_name": true, "sort_artist": true, "sort_album_artist": true, "sort_album": true,
+	"sort_composer": true, "sort_show": true, "rating": true, "podcast": true, "category": true,
+}
+
+// muxerOptions adds the muxer options needed for tags to survive in the
+// given output format.
+func muxerOptions(format string, t *Tags, opts Options) map[string]string {
+	m := map[string]string{}
+	for k, v := range opts.MuxerOptions {
+		m[k] = v
+	}
+	switch format {
+	case "mp4", "mov", "ipod", "3gp", "3g2", "psp", "ismv", "f4v":
+		for _, e := range t.Format {
+			if !itunesKeys[strings.ToLower(e.Key)] {
+				if _, ok := m["movflags"]; !ok {
+					m["movflags"] = "+use_metadata_tags"
+				}
+				break
+			}
+		}
+	case "mp3":
+		if opts.ID3v2Version != 0 {
+			m["id3v2_version"] = strconv.Itoa(opts.ID3v2Version)
+		}
+		if opts.WriteID3v1 {
+			m["write_id3v1"] = "1"
+		}
+	}
+	return m
+}
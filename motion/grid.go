@@ -0,0 +1,93 @@
+package motion
+
+import "github.com/dwdcth/ffmpeg-go/v7/sidedata"
+
+// Grid is the amount of motion in each cell of a frame cut in square
+// blocks.
+type Grid struct {
+	Cols, Rows int
+	BlockSize  int
+	// Mag holds the motion of each cell in pixels, row by row: the mean
+	// magnitude of the vectors covering it, weighted by the area they
+	// cover. Intra coded cells have no vectors and no motion.
+	Mag []float64
+}
+
+// NewGrid aggregates the motion vectors of a width x height frame into
+// blockSize x blockSize cells, 16 if blockSize is not positive.
+func NewGrid(mvs []sidedata.MotionVector, width, height, blockSize int) *Grid {
+	if blockSize <= 0 {
+		blockSize = 16
+	}
+	g := &Grid{
+		Cols:      (width + blockSize - 1) / blockSize,
+		Rows:      (height + blockSize - 1) / blockSize,
+		BlockSize: blockSize,
+	}
+	g.Mag = make([]float64, g.Cols*g.Rows)
+	area := make([]float64, len(g.Mag))
+	for _, mv := range mvs {
+		// DstX, DstY is the center of the block
+		x0 := max(mv.DstX-mv.W/2, 0)
+		y0 := max(mv.DstY-mv.H/2, 0)
+		x1 := min(mv.DstX-mv.W/2+mv.W, width)
+		y1 := min(mv.DstY-mv.H/2+mv.H, height)
+		if x0 >= x1 || y0 >= y1 {
+			continue
+		}
+		m := mv.Magnitude()
+		for row := y0 / blockSize; row*blockSize < y1; row++ {
+			h := min(y1, (row+1)*blockSize) - max(y0, row*blockSize)
+			for col := x0 / blockSize; col*blockSize < x1; col++ {
+				w := min(x1, (col+1)*blockSize) - max(x0, col*blockSize)
+				i := row*g.Cols + col
+				g.Mag[i] += m * float64(w*h)
+				area[i] += float64(w * h)
+			}
+		}
+	}
+	for i, a := range area {
+		if a > 0 {
+			g.Mag[i] /= a
+		}
+	}
+	return g
+}
+
+// At returns the motion of the cell at column col and row row.
+func (g *Grid) At(col, row int) float64 {
+	return g.Mag[row*g.Cols+col]
+}
+
+// Max returns the largest motion of the cells.
+func (g *Grid) Max() float64 {
+	var m float64
+	for _, v := range g.Mag {
+		m = max(m, v)
+	}
+	return m
+}
+
+// Mean returns the mean motion over the frame.
+func (g *Grid) Mean() float64 {
+	if len(g.Mag) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range g.Mag {
+		sum += v
+	}
+	return sum / float64(len(g.Mag))
+}
+
+// Active returns the number of cells that moved by at least threshold
+// pixels.
+func (g *Grid) Active(threshold float64) int {
+	n := 0
+	for _, v := range g.Mag {
+		if v >= threshold {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,126 @@
+package motion
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+// block returns a w x h vector centered on x, y moving by mx, my pixels.
+func block(x, y, w, h, mx, my int) sidedata.MotionVector {
+	return sidedata.MotionVector{
+		Source: -1, W: w, H: h,
+		SrcX: x + mx, SrcY: y + my, DstX: x, DstY: y,
+		MotionX: 4 * mx, MotionY: 4 * my, MotionScale: 4,
+	}
+}
+
+func TestNewGrid(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		mvs                  []sidedata.MotionVector
+		width, height, block int
+		cols, rows, size     int
+		mag                  []float64
+	}{
+		{
+			name:  "empty",
+			width: 32, height: 32, block: 16,
+			cols: 2, rows: 2, size: 16,
+			mag: []float64{0, 0, 0, 0},
+		},
+		{
+			name:  "default block size",
+			mvs:   []sidedata.MotionVector{block(8, 8, 16, 16, 3, 4)},
+			width: 40, height: 20,
+			cols: 3, rows: 2, size: 16,
+			mag: []float64{5, 0, 0, 0, 0, 0},
+		},
+		{
+			name:  "one block per cell",
+			mvs:   []sidedata.MotionVector{block(8, 8, 16, 16, 3, 4), block(24, 24, 16, 16, 0, -2)},
+			width: 32, height: 32, block: 16,
+			cols: 2, rows: 2, size: 16,
+			mag: []float64{5, 0, 0, 2},
+		},
+		{
+			name:  "block straddling four cells",
+			mvs:   []sidedata.MotionVector{block(16, 16, 8, 8, 1, 0)},
+			width: 32, height: 32, block: 16,
+			cols: 2, rows: 2, size: 16,
+			mag: []float64{1, 1, 1, 1},
+		},
+		{
+			name: "weighted by area",
+			mvs: []sidedata.MotionVector{
+				block(8, 6, 16, 12, 1, 0),
+				block(8, 14, 16, 4, 5, 0),
+			},
+			width: 16, height: 16, block: 16,
+			cols: 1, rows: 1, size: 16,
+			mag: []float64{(12*1 + 4*5) / 16.0},
+		},
+		{
+			name: "clipped to the frame",
+			mvs: []sidedata.MotionVector{
+				block(18, 18, 16, 16, 2, 0),
+				// entirely outside
+				block(-20, 4, 16, 16, 9, 9),
+			},
+			width: 20, height: 20, block: 16,
+			cols: 2, rows: 2, size: 16,
+			mag: []float64{2, 2, 2, 2},
+		},
+		{
+			name:  "small cells",
+			mvs:   []sidedata.MotionVector{block(8, 8, 16, 16, 0, 3)},
+			width: 16, height: 16, block: 8,
+			cols: 2, rows: 2, size: 8,
+			mag: []float64{3, 3, 3, 3},
+		},
+	} {
+		g := NewGrid(tc.mvs, tc.width, tc.height, tc.block)
+		if g.Cols != tc.cols || g.Rows != tc.rows || g.BlockSize != tc.size {
+			t.Errorf("%s: grid %dx%d of %d, want %dx%d of %d", tc.name, g.Cols, g.Rows, g.BlockSize, tc.cols, tc.rows, tc.size)
+			continue
+		}
+		if len(g.Mag) != len(tc.mag) {
+			t.Errorf("%s: %d cells, want %d", tc.name, len(g.Mag), len(tc.mag))
+			continue
+		}
+		for i, want := range tc.mag {
+			if math.Abs(g.Mag[i]-want) > 1e-9 {
+				t.Errorf("%s: cell %d = %g, want %g", tc.name, i, g.Mag[i], want)
+			}
+		}
+	}
+}
+
+func TestGridStats(t *testing.T) {
+	g := &Grid{Cols: 3, Rows: 2, BlockSize: 16, Mag: []float64{0, 1, 2, 3, 4, 8}}
+	if v := g.At(2, 1); v != 8 {
+		t.Errorf("At(2, 1) = %g, want 8", v)
+	}
+	if v := g.At(1, 0); v != 1 {
+		t.Errorf("At(1, 0) = %g, want 1", v)
+	}
+	if v := g.Max(); v != 8 {
+		t.Errorf("Max() = %g, want 8", v)
+	}
+	if v := g.Mean(); v != 3 {
+		t.Errorf("Mean() = %g, want 3", v)
+	}
+	for _, tc := range []struct {
+		threshold float64
+		want      int
+	}{{0, 6}, {0.5, 5}, {3, 3}, {8, 1}, {9, 0}} {
+		if n := g.Active(tc.threshold); n != tc.want {
+			t.Errorf("Active(%g) = %d, want %d", tc.threshold, n, tc.want)
+		}
+	}
+	empty := NewGrid(nil, 0, 0, 16)
+	if empty.Max() != 0 || empty.Mean() != 0 || empty.Active(0) != 0 {
+		t.Errorf("empty grid: Max %g, Mean %g, Active %d", empty.Max(), empty.Mean(), empty.Active(0))
+	}
+}
@@ -0,0 +1,160 @@
+// Package motion decodes video with motion vector export and turns the
+// vectors into per-block motion grids, for cheap motion detection without
+// comparing pictures.
+package motion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+// EnableExport adds the flags2 option making a decoder export the motion
+// vectors of the frames, to the options passed to avcodec_open2.
+func EnableExport(opts **libavutil.AVDictionary) {
+	libavutil.AvDictSet(opts, "flags2", "+export_mvs", libavutil.AV_DICT_APPEND)
+}
+
+// Decoder decodes a video stream, attaching the motion vectors to the
+// frames.
+type Decoder struct {
+	ctx   *libavcodec.AVCodecContext
+	frame *libavutil.AVFrame
+}
+
+// NewDecoder opens a decoder for a stream with codec parameters par and
+// time base tb.
+func NewDecoder(par *libavcodec.AVCodecParameters, tb libavutil.AVRational) (*Decoder, error) {
+	codec := libavcodec.AvcodecFindDecoder(par.CodecId)
+	if codec == nil {
+		return nil, fmt.Errorf("motion: no decoder for %s", libavcodec.AvcodecGetName(par.CodecId))
+	}
+	d := &Decoder{ctx: codec.AvcodecAllocContext3(), frame: libavutil.AvFrameAlloc()}
+	if d.ctx == nil || d.frame == nil {
+		d.Close()
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := d.ctx.AvcodecParametersToContext(par); ret < 0 {
+		d.Close()
+		return nil, fmt.Errorf("motion: %w", libavutil.ErrorFromCode(ret))
+	}
+	d.ctx.PktTimebase = tb
+	d.ctx.Flags2 |= libavcodec.AV_CODEC_FLAG2_EXPORT_MVS
+	if ret := d.ctx.AvcodecOpen2(codec, nil); ret < 0 {
+		d.Close()
+		return nil, fmt.Errorf("motion: open decoder: %w", libavutil.ErrorFromCode(ret))
+	}
+	return d, nil
+}
+
+// Decode sends pkt to the decoder when iterated and yields the frames that
+// come out. The yielded frame is reused: it is only valid until the next
+// iteration. A nil pkt flushes the decoder.
+func (d *Decoder) Decode(pkt *libavcodec.AVPacket) iter.Seq2[*libavutil.AVFrame, error] {
+	return func(yield func(*libavutil.AVFrame, error) bool) {
+		if ret := d.ctx.AvcodecSendPacket(pkt); ret < 0 && ret != libavutil.AVERROR_EOF {
+			yield(nil, fmt.Errorf("motion: decode: %w", libavutil.ErrorFromCode(ret)))
+			return
+		}
+		for {
+			err := libavutil.ErrorFromCode(d.ctx.AvcodecReceiveFrame(d.frame))
+			if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("motion: decode: %w", err))
+				return
+			}
+			more := yield(d.frame, nil)
+			d.frame.AvFrameUnref()
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+// Close frees the decoder.
+func (d *Decoder) Close() {
+	libavutil.AvFrameFree(&d.frame)
+	libavcodec.AvcodecFreeContext(&d.ctx)
+}
+
+// Scan decodes the best video stream of the file at path and calls fn with
+// the motion grid of every frame that has motion vectors, which excludes
+// intra coded frames.
+func Scan(ctx context.Context, path string, blockSize int, fn func(pts time.Duration, g *Grid) error) error {
+	ic, err := remux.OpenInput(path)
+	if err != nil {
+		return fmt.Errorf("motion: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+	stream := int(ic.AvFindBestStream(libavutil.AVMEDIA_TYPE_VIDEO, -1, -1, nil, 0))
+	if stream < 0 {
+		return fmt.Errorf("motion: %s: no video stream", path)
+	}
+	st := ic.GetStream(ffcommon.FUnsignedInt(stream))
+	d, err := NewDecoder(st.Codecpar, st.TimeBase)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+	us := libavutil.AVRational{Num: 1, Den: libavutil.AV_TIME_BASE}
+	frames := func(pkt *libavcodec.AVPacket) error {
+		for f, err := range d.Decode(pkt) {
+			if err != nil {
+				return err
+			}
+			mvs := sidedata.OfFrame(f).MotionVectors()
+			if len(mvs) == 0 {
+				continue
+			}
+			var pts time.Duration
+			if f.BestEffortTimestamp != libavutil.AV_NOPTS_VALUE {
+				pts = time.Duration(libavutil.AvRescaleQ(f.BestEffortTimestamp, st.TimeBase, us)) * time.Microsecond
+			}
+			if err := fn(pts, NewGrid(mvs, int(f.Width), int(f.Height), blockSize)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = libavutil.ErrorFromCode(ic.AvReadFrame(pkt))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("motion: read packet: %w", err)
+		}
+		if int(pkt.StreamIndex) != stream {
+			pkt.AvPacketUnref()
+			continue
+		}
+		err = frames(pkt)
+		pkt.AvPacketUnref()
+		if err != nil {
+			return err
+		}
+	}
+	return frames(nil)
+}
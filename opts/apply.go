@@ -0,0 +1,312 @@
+package opts
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// ImageSize is the Go type of AV_OPT_TYPE_IMAGE_SIZE options.
+type ImageSize struct {
+	Width, Height ffcommon.FInt
+}
+
+// Report lists how the tagged fields of a struct were handled by Apply or
+// Read.
+type Report struct {
+	// Done are the options that were set or read.
+	Done []string
+	// Unknown are the tags naming no option of the object or its children.
+	Unknown []string
+	// Unset are the options Apply skipped because their field was a zero
+	// value or a nil pointer.
+	Unset []string
+}
+
+var (
+	rationalType  = reflect.TypeOf(libavutil.AVRational{})
+	imageSizeType = reflect.TypeOf(ImageSize{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+)
+
+// searchFlags makes Apply and Read reach the private options of codecs,
+// formats and filters through their child objects.
+const searchFlags = libavutil.AV_OPT_SEARCH_CHILDREN
+
+type field struct {
+	name  string
+	value reflect.Value
+}
+
+// taggedFields returns the fields of *v or v tagged `av:"name"`. Fields
+// tagged `av:"-"` and untagged fields are ignored.
+func taggedFields(v interface{}, settable bool) ([]field, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	} else if settable {
+		return nil, fmt.Errorf("opts: Read needs a pointer to a struct, got %T", v)
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("opts: expected a struct, got %T", v)
+	}
+	var fields []field
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := strings.Split(rt.Field(i).Tag.Get("av"), ",")[0]
+		if name == "" || name == "-" || rt.Field(i).PkgPath != "" {
+			continue
+		}
+		fields = append(fields, field{name: name, value: rv.Field(i)})
+	}
+	return fields, nil
+}
+
+// Apply sets the options of obj from the fields of v, a struct or a pointer
+// to one, tagged with the option name:
+//
+//	type X264 struct {
+//		Crf    float64             `av:"crf"`
+//		Preset string              `av:"preset"`
+//		Gop    int                 `av:"g"`
+//		Aspect libavutil.AVRational `av:"aspect"`
+//		Size   opts.ImageSize      `av:"video_size"`
+//	}
+//
+// Each field is set with the av_opt_set_* function matching the option
+// type: rationals, image sizes, pixel and sample formats, channel layouts
+// and time.Duration for durations are all supported, strings are parsed by
+// av_opt_set and map[string]string sets dictionary options. Zero values and
+// nil pointers are left alone and listed in Report.Unset; use a pointer
+// field to set an option to zero. Options that do not exist are listed in
+// Report.Unknown. Apply stops at the first option FFmpeg rejects.
+func Apply(obj ffcommon.FVoidP, v interface{}) (Report, error) {
+	var r Report
+	fields, err := taggedFields(v, false)
+	if err != nil {
+		return r, err
+	}
+	for _, f := range fields {
+		o := libavutil.AvOptFind2(obj, f.name, "", 0, searchFlags, nil)
+		if o == nil {
+			r.Unknown = append(r.Unknown, f.name)
+			continue
+		}
+		val := f.value
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				r.Unset = append(r.Unset, f.name)
+				continue
+			}
+			val = val.Elem()
+		} else if val.IsZero() {
+			r.Unset = append(r.Unset, f.name)
+			continue
+		}
+		if ret := set(obj, f.name, o.Type, val); ret < 0 {
+			return r, fmt.Errorf("opts: set %s: %w", f.name, libavutil.ErrorFromCode(ret))
+		}
+		r.Done = append(r.Done, f.name)
+	}
+	return r, nil
+}
+
+func set(obj ffcommon.FVoidP, name string, typ libavutil.AVOptionType, val reflect.Value) ffcommon.FInt {
+	switch val.Type() {
+	case rationalType:
+		q := val.Interface().(libavutil.AVRational)
+		if typ == libavutil.AV_OPT_TYPE_VIDEO_RATE {
+			return libavutil.AvOptSetVideoRate(obj, name, q, searchFlags)
+		}
+		return libavutil.AvOptSetQ(obj, name, q, searchFlags)
+	case imageSizeType:
+		s := val.Interface().(ImageSize)
+		return libavutil.AvOptSetImageSize(obj, name, s.Width, s.Height, searchFlags)
+	case durationType:
+		d := val.Interface().(time.Duration)
+		return libavutil.AvOptSetInt(obj, name, ffcommon.FInt64T(d/time.Microsecond), searchFlags)
+	}
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return setInt(obj, name, typ, val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return setInt(obj, name, typ, int64(val.Uint()))
+	case reflect.Bool:
+		var i int64
+		if val.Bool() {
+			i = 1
+		}
+		return libavutil.AvOptSetInt(obj, name, ffcommon.FInt64T(i), searchFlags)
+	case reflect.Float32, reflect.Float64:
+		return libavutil.AvOptSetDouble(obj, name, ffcommon.FDouble(val.Float()), searchFlags)
+	case reflect.String:
+		return libavutil.AvOptSet(obj, name, val.String(), searchFlags)
+	case reflect.Map:
+		if val.Type().Key().Kind() == reflect.String && val.Type().Elem().Kind() == reflect.String {
+			var dict *libavutil.AVDictionary
+			defer libavutil.AvDictFree(&dict)
+			iter := val.MapRange()
+			for iter.Next() {
+				if ret := libavutil.AvDictSet(&dict, iter.Key().String(), iter.Value().String(), 0); ret < 0 {
+					return ret
+				}
+			}
+			return libavutil.AvOptSetDictVal(obj, name, dict, searchFlags)
+		}
+	}
+	return -libavutil.EINVAL
+}
+
+func setInt(obj ffcommon.FVoidP, name string, typ libavutil.AVOptionType, i int64) ffcommon.FInt {
+	switch typ {
+	case libavutil.AV_OPT_TYPE_PIXEL_FMT:
+		return libavutil.AvOptSetPixelFmt(obj, name, libavutil.AVPixelFormat(i), searchFlags)
+	case libavutil.AV_OPT_TYPE_SAMPLE_FMT:
+		return libavutil.AvOptSetSampleFmt(obj, name, libavutil.AVSampleFormat(i), searchFlags)
+	case libavutil.AV_OPT_TYPE_CHANNEL_LAYOUT:
+		return libavutil.AvOptSetChannelLayout(obj, name, ffcommon.FInt64T(i), searchFlags)
+	}
+	return libavutil.AvOptSetInt(obj, name, ffcommon.FInt64T(i), searchFlags)
+}
+
+// Read fills the tagged fields of the struct v points to with the current
+// option values of obj, using the same tags and types as Apply. Pointer
+// fields are allocated. Options that do not exist are listed in
+// Report.Unknown.
+func Read(obj ffcommon.FVoidP, v interface{}) (Report, error) {
+	var r Report
+	fields, err := taggedFields(v, true)
+	if err != nil {
+		return r, err
+	}
+	for _, f := range fields {
+		o := libavutil.AvOptFind2(obj, f.name, "", 0, searchFlags, nil)
+		if o == nil {
+			r.Unknown = append(r.Unknown, f.name)
+			continue
+		}
+		val := f.value
+		if val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		if ret := get(obj, f.name, o.Type, val); ret < 0 {
+			return r, fmt.Errorf("opts: get %s: %w", f.name, libavutil.ErrorFromCode(ret))
+		}
+		r.Done = append(r.Done, f.name)
+	}
+	return r, nil
+}
+
+func get(obj ffcommon.FVoidP, name string, typ libavutil.AVOptionType, val reflect.Value) ffcommon.FInt {
+	switch val.Type() {
+	case rationalType:
+		var q libavutil.AVRational
+		var ret ffcommon.FInt
+		if typ == libavutil.AV_OPT_TYPE_VIDEO_RATE {
+			ret = libavutil.AvOptGetVideoRate(obj, name, searchFlags, &q)
+		} else {
+			ret = libavutil.AvOptGetQ(obj, name, searchFlags, &q)
+		}
+		if ret >= 0 {
+			val.Set(reflect.ValueOf(q))
+		}
+		return ret
+	case imageSizeType:
+		var s ImageSize
+		ret := libavutil.AvOptGetImageSize(obj, name, searchFlags, &s.Width, &s.Height)
+		if ret >= 0 {
+			val.Set(reflect.ValueOf(s))
+		}
+		return ret
+	case durationType:
+		var i ffcommon.FInt64T
+		ret := libavutil.AvOptGetInt(obj, name, searchFlags, &i)
+		if ret >= 0 {
+			val.SetInt(int64(time.Duration(i) * time.Microsecond))
+		}
+		return ret
+	}
+
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ret := getInt(obj, name, typ)
+		if ret >= 0 {
+			val.SetInt(i)
+		}
+		return ret
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ret := getInt(obj, name, typ)
+		if ret >= 0 {
+			val.SetUint(uint64(i))
+		}
+		return ret
+	case reflect.Bool:
+		i, ret := getInt(obj, name, typ)
+		if ret >= 0 {
+			val.SetBool(i != 0)
+		}
+		return ret
+	case reflect.Float32, reflect.Float64:
+		var d ffcommon.FDouble
+		ret := libavutil.AvOptGetDouble(obj, name, searchFlags, &d)
+		if ret >= 0 {
+			val.SetFloat(float64(d))
+		}
+		return ret
+	case reflect.String:
+		var out *ffcommon.FUint8T
+		ret := libavutil.AvOptGet(obj, name, searchFlags, &out)
+		if ret >= 0 {
+			p := uintptr(unsafe.Pointer(out))
+			val.SetString(ffcommon.GoString(p))
+			libavutil.AvFree(p)
+		}
+		return ret
+	case reflect.Map:
+		if val.Type().Key().Kind() == reflect.String && val.Type().Elem().Kind() == reflect.String {
+			var dict *libavutil.AVDictionary
+			ret := libavutil.AvOptGetDictVal(obj, name, searchFlags, &dict)
+			if ret < 0 {
+				return ret
+			}
+			m := reflect.MakeMap(val.Type())
+			for e := dict.AvDictGet("", nil, libavutil.AV_DICT_IGNORE_SUFFIX); e != nil; e = dict.AvDictGet("", e, libavutil.AV_DICT_IGNORE_SUFFIX) {
+				m.SetMapIndex(reflect.ValueOf(ffcommon.GoString(e.Key)).Convert(val.Type().Key()),
+					reflect.ValueOf(ffcommon.GoString(e.Value)).Convert(val.Type().Elem()))
+			}
+			libavutil.AvDictFree(&dict)
+			val.Set(m)
+			return 0
+		}
+	}
+	return -libavutil.EINVAL
+}
+
+func getInt(obj ffcommon.FVoidP, name string, typ libavutil.AVOptionType) (int64, ffcommon.FInt) {
+	switch typ {
+	case libavutil.AV_OPT_TYPE_PIXEL_FMT:
+		var f libavutil.AVPixelFormat
+		ret := libavutil.AvOptGetPixelFmt(obj, name, searchFlags, &f)
+		return int64(f), ret
+	case libavutil.AV_OPT_TYPE_SAMPLE_FMT:
+		var f libavutil.AVSampleFormat
+		ret := libavutil.AvOptGetSampleFmt(obj, name, searchFlags, &f)
+		return int64(f), ret
+	case libavutil.AV_OPT_TYPE_CHANNEL_LAYOUT:
+		var l ffcommon.FInt64T
+		ret := libavutil.AvOptGetChannelLayout(obj, name, searchFlags, &l)
+		return int64(l), ret
+	}
+	var i ffcommon.FInt64T
+	ret := libavutil.AvOptGetInt(obj, name, searchFlags, &i)
+	return int64(i), ret
+}
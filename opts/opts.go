@@ -0,0 +1,210 @@
+// Package opts inspects and sets AVOptions of FFmpeg objects such as codec,
+// format and filter contexts.
+package opts
+
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Constant is a named value of an option unit, e.g. "veryfast" for the
+// "preset" option.
+type Constant struct {
+	Name  string
+	Help  string
+	Value int64
+}
+
+// OptionInfo describes one AVOption.
+type OptionInfo struct {
+	Name string
+	Help string
+	Type libavutil.AVOptionType
+	// Class is the name of the AVClass the option was found in, which
+	// differs from the described object for options of child objects.
+	Class string
+	// Default is an int64 for integer, flag, format and layout options, a
+	// float64 for float, double and rational options, and a string for
+	// string-like options. It is nil for binary options.
+	Default  interface{}
+	Min, Max float64
+	Flags    ffcommon.FInt
+	Unit     string
+	// Constants are the named values of Unit.
+	Constants []Constant
+}
+
+// TypeName returns the type as printed by ffmpeg -h, e.g. "int" or "flags".
+func (o *OptionInfo) TypeName() string {
+	return TypeName(o.Type)
+}
+
+func (o *OptionInfo) has(flag ffcommon.FInt) bool { return o.Flags&flag != 0 }
+
+func (o *OptionInfo) Encoding() bool   { return o.has(libavutil.AV_OPT_FLAG_ENCODING_PARAM) }
+func (o *OptionInfo) Decoding() bool   { return o.has(libavutil.AV_OPT_FLAG_DECODING_PARAM) }
+func (o *OptionInfo) Audio() bool      { return o.has(libavutil.AV_OPT_FLAG_AUDIO_PARAM) }
+func (o *OptionInfo) Video() bool      { return o.has(libavutil.AV_OPT_FLAG_VIDEO_PARAM) }
+func (o *OptionInfo) Subtitle() bool   { return o.has(libavutil.AV_OPT_FLAG_SUBTITLE_PARAM) }
+func (o *OptionInfo) Filtering() bool  { return o.has(libavutil.AV_OPT_FLAG_FILTERING_PARAM) }
+func (o *OptionInfo) Runtime() bool    { return o.has(libavutil.AV_OPT_FLAG_RUNTIME_PARAM) }
+func (o *OptionInfo) ReadOnly() bool   { return o.has(libavutil.AV_OPT_FLAG_READONLY) }
+func (o *OptionInfo) Deprecated() bool { return o.has(libavutil.AV_OPT_FLAG_DEPRECATED) }
+
+// Constant returns the value of the named constant of the option's unit.
+func (o *OptionInfo) Constant(name string) (int64, bool) {
+	for _, c := range o.Constants {
+		if c.Name == name {
+			return c.Value, true
+		}
+	}
+	return 0, false
+}
+
+var typeNames = map[libavutil.AVOptionType]string{
+	libavutil.AV_OPT_TYPE_FLAGS:          "flags",
+	libavutil.AV_OPT_TYPE_INT:            "int",
+	libavutil.AV_OPT_TYPE_INT64:          "int64",
+	libavutil.AV_OPT_TYPE_DOUBLE:         "double",
+	libavutil.AV_OPT_TYPE_FLOAT:          "float",
+	libavutil.AV_OPT_TYPE_STRING:         "string",
+	libavutil.AV_OPT_TYPE_RATIONAL:       "rational",
+	libavutil.AV_OPT_TYPE_BINARY:         "binary",
+	libavutil.AV_OPT_TYPE_DICT:           "dictionary",
+	libavutil.AV_OPT_TYPE_UINT64:         "uint64",
+	libavutil.AV_OPT_TYPE_CONST:          "const",
+	libavutil.AV_OPT_TYPE_IMAGE_SIZE:     "image_size",
+	libavutil.AV_OPT_TYPE_PIXEL_FMT:      "pix_fmt",
+	libavutil.AV_OPT_TYPE_SAMPLE_FMT:     "sample_fmt",
+	libavutil.AV_OPT_TYPE_VIDEO_RATE:     "video_rate",
+	libavutil.AV_OPT_TYPE_DURATION:       "duration",
+	libavutil.AV_OPT_TYPE_COLOR:          "color",
+	libavutil.AV_OPT_TYPE_CHANNEL_LAYOUT: "channel_layout",
+	libavutil.AV_OPT_TYPE_BOOL:           "boolean",
+}
+
+// TypeName returns the name of an option type.
+func TypeName(t libavutil.AVOptionType) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Describe lists the options of obj, a pointer to a struct whose first
+// member is an AVClass pointer, followed by the options of its existing
+// AVOptions-enabled children.
+func Describe(obj ffcommon.FVoidP) []OptionInfo {
+	if obj == 0 {
+		return nil
+	}
+	var infos []OptionInfo
+	seen := map[ffcommon.FVoidP]bool{}
+	var walk func(obj ffcommon.FVoidP)
+	walk = func(obj ffcommon.FVoidP) {
+		if seen[obj] {
+			return
+		}
+		seen[obj] = true
+		infos = append(infos, describe(obj, className(obj))...)
+		for child := libavutil.AvOptChildNext(obj, 0); child != 0; child = libavutil.AvOptChildNext(obj, child) {
+			walk(child)
+		}
+	}
+	walk(obj)
+	return infos
+}
+
+// DescribeClass lists the options of class and of every class its objects
+// may have as children, without needing an instance. Use it with e.g.
+// libavcodec.AvcodecGetClass or the PrivClass of a codec.
+func DescribeClass(class *libavutil.AVClass) []OptionInfo {
+	if class == nil {
+		return nil
+	}
+	var infos []OptionInfo
+	seen := map[*libavutil.AVClass]bool{}
+	var walk func(class *libavutil.AVClass)
+	walk = func(class *libavutil.AVClass) {
+		if seen[class] {
+			return
+		}
+		seen[class] = true
+		// a fake object is a pointer to the class pointer, see AV_OPT_SEARCH_FAKE_OBJ
+		fake := new(*libavutil.AVClass)
+		*fake = class
+		infos = append(infos, describe(ffcommon.FVoidP(unsafe.Pointer(fake)), ffcommon.GoString(class.ClassName))...)
+		runtime.KeepAlive(fake)
+		var iter ffcommon.FVoidP
+		for child := class.AvOptChildClassIterate(&iter); child != nil; child = class.AvOptChildClassIterate(&iter) {
+			walk(child)
+		}
+	}
+	walk(class)
+	return infos
+}
+
+func className(obj ffcommon.FVoidP) string {
+	class := **(***libavutil.AVClass)(unsafe.Pointer(&obj))
+	if class == nil {
+		return ""
+	}
+	return ffcommon.GoString(class.ClassName)
+}
+
+// describe lists the options of a single object and attaches the named
+// constants to the options sharing their unit.
+func describe(obj ffcommon.FVoidP, class string) []OptionInfo {
+	var infos []OptionInfo
+	consts := map[string][]Constant{}
+	for o := libavutil.AvOptNext(obj, nil); o != nil; o = libavutil.AvOptNext(obj, o) {
+		unit := ffcommon.GoString(o.Unit)
+		if o.Type == libavutil.AV_OPT_TYPE_CONST {
+			consts[unit] = append(consts[unit], Constant{
+				Name:  ffcommon.GoString(o.Name),
+				Help:  ffcommon.GoString(o.Help),
+				Value: int64(*(*ffcommon.FInt64T)(unsafe.Pointer(&o.DefaultVal))),
+			})
+			continue
+		}
+		infos = append(infos, OptionInfo{
+			Name:    ffcommon.GoString(o.Name),
+			Help:    ffcommon.GoString(o.Help),
+			Type:    o.Type,
+			Class:   class,
+			Default: defaultValue(o),
+			Min:     float64(o.Min),
+			Max:     float64(o.Max),
+			Flags:   o.Flags,
+			Unit:    unit,
+		})
+	}
+	for i := range infos {
+		if infos[i].Unit != "" {
+			infos[i].Constants = consts[infos[i].Unit]
+		}
+	}
+	return infos
+}
+
+// defaultValue decodes the default_val union of o.
+func defaultValue(o *libavutil.AVOption) interface{} {
+	p := unsafe.Pointer(&o.DefaultVal)
+	switch o.Type {
+	case libavutil.AV_OPT_TYPE_FLAGS, libavutil.AV_OPT_TYPE_INT, libavutil.AV_OPT_TYPE_INT64,
+		libavutil.AV_OPT_TYPE_UINT64, libavutil.AV_OPT_TYPE_PIXEL_FMT, libavutil.AV_OPT_TYPE_SAMPLE_FMT,
+		libavutil.AV_OPT_TYPE_DURATION, libavutil.AV_OPT_TYPE_CHANNEL_LAYOUT, libavutil.AV_OPT_TYPE_BOOL,
+		libavutil.AV_OPT_TYPE_CONST:
+		return int64(*(*ffcommon.FInt64T)(p))
+	case libavutil.AV_OPT_TYPE_DOUBLE, libavutil.AV_OPT_TYPE_FLOAT, libavutil.AV_OPT_TYPE_RATIONAL:
+		// rational defaults are stored in .dbl as well
+		return float64(*(*ffcommon.FDouble)(p))
+	case libavutil.AV_OPT_TYPE_STRING, libavutil.AV_OPT_TYPE_IMAGE_SIZE, libavutil.AV_OPT_TYPE_VIDEO_RATE,
+		libavutil.AV_OPT_TYPE_COLOR, libavutil.AV_OPT_TYPE_DICT:
+		return ffcommon.GoString(*(*ffcommon.FCharPStruct)(p))
+	}
+	return nil
+}
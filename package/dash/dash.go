@@ -0,0 +1,151 @@
+// Package dash packages media files for MPEG-DASH by stream copy through
+// the FFmpeg dash muxer.
+package dash
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+)
+
+// SegmentType is the container of the segments.
+type SegmentType int
+
+const (
+	// Auto picks WebM for VP8/VP9/Vorbis/Opus streams and MP4 otherwise.
+	Auto SegmentType = iota
+	MP4
+	WebM
+)
+
+// AdaptationSet groups the streams a player switches between. Streams uses
+// the muxer syntax, e.g. "v" for all video streams or "0,2" for stream
+// indices.
+type AdaptationSet struct {
+	ID      int
+	Streams string
+}
+
+// Config configures Write. The zero value writes numbered segments of the
+// muxer's default duration with one adaptation set per media type.
+type Config struct {
+	// ManifestName is the name of the MPD, "manifest.mpd" by default.
+	ManifestName string
+	// SegmentDuration is the target segment duration. Segments are cut at
+	// keyframes.
+	SegmentDuration time.Duration
+	SegmentType     SegmentType
+
+	// InitTemplate and MediaTemplate name the initialization and media
+	// segments with the DASH template identifiers $RepresentationID$,
+	// $Number$, $Time$ and $Bandwidth$. The muxer defaults are
+	// "init-stream$RepresentationID$.$ext$" and
+	// "chunk-stream$RepresentationID$-$Number%05d$.$ext$".
+	InitTemplate  string
+	MediaTemplate string
+	// Timeline writes a SegmentTimeline with the exact segment durations
+	// instead of a fixed duration per segment. Media templates using
+	// $Time$ need it.
+	Timeline bool
+
+	// SingleFile writes every representation into one file with byte
+	// ranges in the manifest instead of a file per segment. SingleFileName
+	// names the files, "$RepresentationID$.$ext$" style, by default the
+	// manifest name with the representation ID.
+	SingleFile     bool
+	SingleFileName string
+
+	// AdaptationSets overrides the grouping of the streams.
+	AdaptationSets []AdaptationSet
+	// MuxerOptions are passed to the dash muxer as is, after the options
+	// derived from the fields above.
+	MuxerOptions map[string]string
+}
+
+// Write packages all streams of src into dir, which is created if needed,
+// and returns the parsed manifest with the segments written for every
+// representation.
+func Write(ctx context.Context, src, dir string, c Config) (*Manifest, error) {
+	if c.ManifestName == "" {
+		c.ManifestName = "manifest.mpd"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+	path := filepath.Join(dir, c.ManifestName)
+
+	ic, err := remux.OpenInput(src)
+	if err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+
+	o, err := remux.NewOutput(ic, path, "dash")
+	if err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+	defer o.Close()
+
+	if err = o.WriteHeader(c.options()); err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+	if err = o.Copy(ctx); err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+	if err = o.Finish(); err != nil {
+		return nil, fmt.Errorf("dash: %w", err)
+	}
+	return ReadManifest(path)
+}
+
+// options returns the dash muxer options.
+func (c *Config) options() map[string]string {
+	opts := map[string]string{
+		"use_template": "1",
+		"use_timeline": "0",
+	}
+	if c.Timeline {
+		opts["use_timeline"] = "1"
+	}
+	if c.SegmentDuration > 0 {
+		opts["seg_duration"] = strconv.FormatFloat(c.SegmentDuration.Seconds(), 'f', -1, 64)
+	}
+	switch c.SegmentType {
+	case MP4:
+		opts["dash_segment_type"] = "mp4"
+	case WebM:
+		opts["dash_segment_type"] = "webm"
+	}
+	if c.InitTemplate != "" {
+		opts["init_seg_name"] = c.InitTemplate
+	}
+	if c.MediaTemplate != "" {
+		opts["media_seg_name"] = c.MediaTemplate
+	}
+	if c.SingleFile {
+		// byte ranges are listed in a SegmentList, not a template
+		opts["use_template"] = "0"
+		opts["single_file"] = "1"
+		if c.SingleFileName != "" {
+			opts["single_file_name"] = c.SingleFileName
+		}
+	}
+	if len(c.AdaptationSets) > 0 {
+		sets := make([]string, len(c.AdaptationSets))
+		for i, s := range c.AdaptationSets {
+			sets[i] = fmt.Sprintf("id=%d,streams=%s", s.ID, s.Streams)
+		}
+		opts["adaptation_sets"] = strings.Join(sets, " ")
+	}
+	for k, v := range c.MuxerOptions {
+		opts[k] = v
+	}
+	return opts
+}
@@ -0,0 +1,271 @@
+package dash
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is one media segment of a representation.
+type Segment struct {
+	URI      string
+	Start    time.Duration
+	Duration time.Duration
+	// Range is the byte range of the segment within URI in single file
+	// mode, "" otherwise.
+	Range string
+}
+
+// Representation is one encoded version of a stream in the manifest.
+type Representation struct {
+	ID        string
+	MimeType  string
+	Codecs    string
+	Bandwidth int64
+	Width     int
+	Height    int
+	// Init is the URI of the initialization segment and InitRange its
+	// byte range in single file mode.
+	Init      string
+	InitRange string
+	Segments  []Segment
+}
+
+// Manifest is a parsed MPD.
+type Manifest struct {
+	Path            string
+	Static          bool
+	Duration        time.Duration
+	Representations []*Representation
+}
+
+type mpdXML struct {
+	Type     string `xml:"type,attr"`
+	Duration string `xml:"mediaPresentationDuration,attr"`
+	Periods  []struct {
+		Sets []struct {
+			MimeType        string           `xml:"mimeType,attr"`
+			Template        *templateXML     `xml:"SegmentTemplate"`
+			Representations []representation `xml:"Representation"`
+		} `xml:"AdaptationSet"`
+	} `xml:"Period"`
+}
+
+type representation struct {
+	ID        string       `xml:"id,attr"`
+	MimeType  string       `xml:"mimeType,attr"`
+	Codecs    string       `xml:"codecs,attr"`
+	Bandwidth int64        `xml:"bandwidth,attr"`
+	Width     int          `xml:"width,attr"`
+	Height    int          `xml:"height,attr"`
+	BaseURL   string       `xml:"BaseURL"`
+	Template  *templateXML `xml:"SegmentTemplate"`
+	List      *struct {
+		Timescale int64 `xml:"timescale,attr"`
+		Duration  int64 `xml:"duration,attr"`
+		Init      struct {
+			SourceURL string `xml:"sourceURL,attr"`
+			Range     string `xml:"range,attr"`
+		} `xml:"Initialization"`
+		URLs []struct {
+			Media string `xml:"media,attr"`
+			Range string `xml:"mediaRange,attr"`
+		} `xml:"SegmentURL"`
+	} `xml:"SegmentList"`
+}
+
+type templateXML struct {
+	Timescale      int64  `xml:"timescale,attr"`
+	Duration       int64  `xml:"duration,attr"`
+	StartNumber    *int64 `xml:"startNumber,attr"`
+	Initialization string `xml:"initialization,attr"`
+	Media          string `xml:"media,attr"`
+	Timeline       []struct {
+		T *int64 `xml:"t,attr"`
+		D int64  `xml:"d,attr"`
+		R int64  `xml:"r,attr"`
+	} `xml:"SegmentTimeline>S"`
+}
+
+// ReadManifest parses the MPD at path and expands the segment list of every
+// representation.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var x mpdXML
+	if err = xml.Unmarshal(data, &x); err != nil {
+		return nil, fmt.Errorf("dash: %s: %w", path, err)
+	}
+	m := &Manifest{Path: path, Static: x.Type != "dynamic"}
+	if x.Duration != "" {
+		if m.Duration, err = parseDuration(x.Duration); err != nil {
+			return nil, fmt.Errorf("dash: %s: %w", path, err)
+		}
+	}
+	for _, p := range x.Periods {
+		for _, set := range p.Sets {
+			for _, rx := range set.Representations {
+				r := &Representation{
+					ID:        rx.ID,
+					MimeType:  rx.MimeType,
+					Codecs:    rx.Codecs,
+					Bandwidth: rx.Bandwidth,
+					Width:     rx.Width,
+					Height:    rx.Height,
+				}
+				if r.MimeType == "" {
+					r.MimeType = set.MimeType
+				}
+				tmpl := rx.Template
+				if tmpl == nil {
+					tmpl = set.Template
+				}
+				switch {
+				case tmpl != nil:
+					err = r.expandTemplate(tmpl, m.Duration)
+				case rx.List != nil:
+					r.expandList(rx, m.Duration)
+				default:
+					err = fmt.Errorf("representation %s has no segment information", rx.ID)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("dash: %s: %w", path, err)
+				}
+				m.Representations = append(m.Representations, r)
+			}
+		}
+	}
+	return m, nil
+}
+
+// scaledDuration converts v timescale units to a duration without
+// overflowing for the epoch based times of live manifests.
+func scaledDuration(v, timescale int64) time.Duration {
+	ts := time.Duration(timescale)
+	return time.Duration(v/timescale)*time.Second + time.Duration(v%timescale)*time.Second/ts
+}
+
+func (r *Representation) expandTemplate(t *templateXML, total time.Duration) error {
+	timescale := t.Timescale
+	if timescale <= 0 {
+		timescale = 1
+	}
+	scaled := func(v int64) time.Duration { return scaledDuration(v, timescale) }
+	number := int64(1)
+	if t.StartNumber != nil {
+		number = *t.StartNumber
+	}
+	r.Init = expand(t.Initialization, r, number, 0)
+	if len(t.Timeline) > 0 {
+		var ts int64
+		for _, s := range t.Timeline {
+			if s.T != nil {
+				ts = *s.T
+			}
+			// a negative repeat count runs to the next S or the end, which
+			// static manifests written by FFmpeg do not use
+			for i := int64(0); i <= s.R; i++ {
+				r.Segments = append(r.Segments, Segment{URI: expand(t.Media, r, number, ts), Start: scaled(ts), Duration: scaled(s.D)})
+				ts += s.D
+				number++
+			}
+		}
+		return nil
+	}
+	if t.Duration <= 0 {
+		return fmt.Errorf("representation %s has neither a segment duration nor a timeline", r.ID)
+	}
+	d := scaled(t.Duration)
+	for ts := int64(0); scaled(ts) < total; ts += t.Duration {
+		start := scaled(ts)
+		seg := Segment{URI: expand(t.Media, r, number, ts), Start: start, Duration: d}
+		if start+d > total {
+			seg.Duration = total - start
+		}
+		r.Segments = append(r.Segments, seg)
+		number++
+	}
+	return nil
+}
+
+func (r *Representation) expandList(rx representation, total time.Duration) {
+	l := rx.List
+	r.Init = l.Init.SourceURL
+	r.InitRange = l.Init.Range
+	if r.Init == "" {
+		r.Init = rx.BaseURL
+	}
+	var d time.Duration
+	if l.Timescale > 0 {
+		d = scaledDuration(l.Duration, l.Timescale)
+	}
+	var start time.Duration
+	for _, u := range l.URLs {
+		seg := Segment{URI: u.Media, Start: start, Duration: d, Range: u.Range}
+		if seg.URI == "" {
+			seg.URI = rx.BaseURL
+		}
+		if total > 0 && start+d > total {
+			seg.Duration = total - start
+		}
+		r.Segments = append(r.Segments, seg)
+		start += d
+	}
+}
+
+var identifier = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(%0?\d*d)?\$|\$\$`)
+
+// expand substitutes the identifiers of a segment template.
+func expand(tmpl string, r *Representation, number, ts int64) string {
+	return identifier.ReplaceAllStringFunc(tmpl, func(s string) string {
+		if s == "$$" {
+			return "$"
+		}
+		m := identifier.FindStringSubmatch(s)
+		format := m[2]
+		if format == "" {
+			format = "%d"
+		}
+		switch m[1] {
+		case "RepresentationID":
+			return r.ID
+		case "Number":
+			return fmt.Sprintf(format, number)
+		case "Time":
+			return fmt.Sprintf(format, ts)
+		default:
+			return fmt.Sprintf(format, r.Bandwidth)
+		}
+	})
+}
+
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:([\d.]+)S)?)?$`)
+
+// parseDuration parses the xs:duration subset written by MPD muxers.
+func parseDuration(s string) (time.Duration, error) {
+	m := isoDuration.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("bad duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute} {
+		if m[i+1] != "" {
+			n, _ := strconv.ParseInt(m[i+1], 10, 64)
+			d += time.Duration(n) * unit
+		}
+	}
+	if m[4] != "" {
+		sec, err := strconv.ParseFloat(m[4], 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad duration %q", s)
+		}
+		d += time.Duration(sec * float64(time.Second))
+	}
+	return d, nil
+}
@@ -0,0 +1,122 @@
+package dash
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testMPD = `<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" mediaPresentationDuration="PT10.0S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <SegmentTemplate timescale="1000" duration="4000" startNumber="1" initialization="init-$RepresentationID$.m4s" media="seg-$RepresentationID$-$Number%05d$.m4s"/>
+      <Representation id="0" codecs="avc1.64001f" bandwidth="800000" width="1280" height="720"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <Representation id="1" codecs="mp4a.40.2" bandwidth="128000">
+        <SegmentTemplate timescale="90000" initialization="init-$RepresentationID$.m4s" media="chunk-$Time$-$$.m4s">
+          <SegmentTimeline>
+            <S t="155520000000000" d="180000" r="1"/>
+            <S d="45000"/>
+          </SegmentTimeline>
+        </SegmentTemplate>
+      </Representation>
+    </AdaptationSet>
+    <AdaptationSet mimeType="text/vtt">
+      <Representation id="2" bandwidth="1000">
+        <BaseURL>single.mp4</BaseURL>
+        <SegmentList timescale="1000" duration="6000">
+          <Initialization range="0-99"/>
+          <SegmentURL mediaRange="100-199"/>
+          <SegmentURL mediaRange="200-299"/>
+        </SegmentList>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`
+
+func TestReadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.mpd")
+	if err := os.WriteFile(path, []byte(testMPD), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Static || m.Duration != 10*time.Second || len(m.Representations) != 3 {
+		t.Fatalf("manifest = static %v, duration %v, %d representations", m.Static, m.Duration, len(m.Representations))
+	}
+	epoch := 1728000000 * time.Second
+	want := [][]Segment{
+		{
+			{URI: "seg-0-00001.m4s", Start: 0, Duration: 4 * time.Second},
+			{URI: "seg-0-00002.m4s", Start: 4 * time.Second, Duration: 4 * time.Second},
+			{URI: "seg-0-00003.m4s", Start: 8 * time.Second, Duration: 2 * time.Second},
+		},
+		{
+			{URI: "chunk-155520000000000-$.m4s", Start: epoch, Duration: 2 * time.Second},
+			{URI: "chunk-155520000180000-$.m4s", Start: epoch + 2*time.Second, Duration: 2 * time.Second},
+			{URI: "chunk-155520000360000-$.m4s", Start: epoch + 4*time.Second, Duration: 500 * time.Millisecond},
+		},
+		{
+			{URI: "single.mp4", Start: 0, Duration: 6 * time.Second, Range: "100-199"},
+			{URI: "single.mp4", Start: 6 * time.Second, Duration: 4 * time.Second, Range: "200-299"},
+		},
+	}
+	inits := []string{"init-0.m4s", "init-1.m4s", "single.mp4"}
+	for i, r := range m.Representations {
+		if r.Init != inits[i] {
+			t.Errorf("representation %s: init %q, want %q", r.ID, r.Init, inits[i])
+		}
+		if !reflect.DeepEqual(r.Segments, want[i]) {
+			t.Errorf("representation %s: segments\n%+v\nwant\n%+v", r.ID, r.Segments, want[i])
+		}
+	}
+	if r := m.Representations[2]; r.InitRange != "0-99" || r.MimeType != "text/vtt" {
+		t.Errorf("representation 2: init range %q, mime type %q", r.InitRange, r.MimeType)
+	}
+}
+
+func TestScaledDuration(t *testing.T) {
+	tests := []struct {
+		v, timescale int64
+		want         time.Duration
+	}{
+		{0, 90000, 0},
+		{45000, 90000, 500 * time.Millisecond},
+		{1001, 30000, 33366666 * time.Nanosecond},
+		{155520000000000, 90000, 1728000000 * time.Second},
+		{155520000045000, 90000, 1728000000*time.Second + 500*time.Millisecond},
+		{9223372036854, 1000, 9223372036854 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := scaledDuration(tt.v, tt.timescale); got != tt.want {
+			t.Errorf("scaledDuration(%d, %d) = %v, want %v", tt.v, tt.timescale, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"PT10.0S", 10 * time.Second, true},
+		{"PT1H2M3.5S", time.Hour + 2*time.Minute + 3500*time.Millisecond, true},
+		{"P1DT1S", 24*time.Hour + time.Second, true},
+		{"PT0S", 0, true},
+		{"10s", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, %v", tt.s, got, err)
+		}
+	}
+}
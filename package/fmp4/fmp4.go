@@ -0,0 +1,303 @@
+// Package fmp4 muxes fragmented MP4 for low-latency delivery, handing out
+// the init segment and every fragment as soon as it is complete.
+package fmp4
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Fragment is one moof+mdat pair.
+type Fragment struct {
+	// Seq numbers the fragments from 1.
+	Seq      int
+	Start    time.Duration
+	Duration time.Duration
+	Data     []byte
+}
+
+// Config configures a Writer.
+type Config struct {
+	// FragmentDuration cuts fragments at this duration in addition to every
+	// video keyframe, 0 for keyframes only.
+	FragmentDuration time.Duration
+	// OnInit receives the init segment (ftyp+moov) once, from WriteHeader.
+	OnInit func(init []byte) error
+	// OnFragment receives every fragment once it is complete. Data is not
+	// reused by the Writer.
+	OnFragment func(f Fragment) error
+	// MuxerOptions are passed to the mp4 muxer. A movflags entry is added
+	// to the flags the Writer needs.
+	MuxerOptions map[string]string
+}
+
+const bufferSize = 64 * 1024
+
+var timeBaseQ = libavutil.AVRational{Num: 1, Den: libavutil.AV_TIME_BASE}
+
+// Writer muxes packets into fragmented MP4. The muxer writes a whole
+// fragment at once when the next one starts, which the Writer detects from
+// the AVIO data markers, so packets must be written in decoding order.
+type Writer struct {
+	c   Config
+	ctx *libavformat.AVFormatContext
+	id  uintptr
+	err error
+
+	init    []byte
+	frag    *Fragment
+	seq     int
+	started bool
+	// next is where the next fragment starts, cut where the packet being
+	// written starts and end how far the packets written so far reach, in
+	// AV_TIME_BASE units
+	next, cut, end int64
+	// ready holds the fragments completed during the current write
+	ready []*Fragment
+}
+
+var (
+	writers     = map[uintptr]*Writer{}
+	writersMu   sync.Mutex
+	lastID      uintptr
+	writeData   uintptr
+	writeDataMu sync.Once
+)
+
+// NewWriter allocates a Writer. Add the streams with AddStream, then call
+// WriteHeader.
+func NewWriter(c Config) (*Writer, error) {
+	w := &Writer{c: c, next: math.MinInt64, cut: math.MinInt64, end: math.MinInt64}
+	if ret := libavformat.AvformatAllocOutputContext2(&w.ctx, nil, "mp4", ""); ret < 0 || w.ctx == nil {
+		return nil, fmt.Errorf("fmp4: %w", libavutil.ErrorFromCode(ret))
+	}
+
+	buf := libavutil.AvMalloc(bufferSize)
+	if buf == 0 {
+		w.Close()
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	writersMu.Lock()
+	lastID++
+	w.id = lastID
+	writers[w.id] = w
+	writersMu.Unlock()
+	pb := libavformat.AvioAllocContext(*(*ffcommon.FBuf)(unsafe.Pointer(&buf)), bufferSize, 1, w.id, nil, nil, nil)
+	if pb == nil {
+		libavutil.AvFree(buf)
+		w.Close()
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	writeDataMu.Do(func() {
+		writeData = ffcommon.NewCallback(writeDataType)
+	})
+	// write_data_type replaces write_packet and tells which kind of data
+	// each chunk is
+	pb.WriteDataType = writeData
+	w.ctx.Pb = pb
+	return w, nil
+}
+
+// writeDataType is the write_data_type callback of every Writer.
+func writeDataType(opaque ffcommon.FVoidP, buf *ffcommon.FUint8T, size ffcommon.FInt, typ libavformat.AVIODataMarkerType, t ffcommon.FInt64T) uintptr {
+	writersMu.Lock()
+	w := writers[opaque]
+	writersMu.Unlock()
+	if w == nil {
+		ret := ffcommon.FInt(libavutil.AVERROR_EXTERNAL)
+		return uintptr(ret)
+	}
+	data := ffcommon.ByteSliceFromByteP(buf, int(size))
+	switch typ {
+	case libavformat.AVIO_DATA_MARKER_HEADER:
+		w.init = append(w.init, data...)
+	case libavformat.AVIO_DATA_MARKER_SYNC_POINT, libavformat.AVIO_DATA_MARKER_BOUNDARY_POINT:
+		w.closeFragment(w.cut)
+		w.seq++
+		w.frag = &Fragment{Seq: w.seq, Data: append([]byte(nil), data...)}
+	case libavformat.AVIO_DATA_MARKER_TRAILER:
+		// the mfra box, useless for live delivery
+	default:
+		if w.frag == nil {
+			w.init = append(w.init, data...)
+		} else {
+			w.frag.Data = append(w.frag.Data, data...)
+		}
+	}
+	return uintptr(size)
+}
+
+// closeFragment queues the current fragment as ending at end.
+func (w *Writer) closeFragment(end int64) {
+	if w.frag == nil {
+		return
+	}
+	start := w.next
+	if start == math.MinInt64 {
+		start = end
+	}
+	w.frag.Start = time.Duration(start) * time.Microsecond
+	w.frag.Duration = time.Duration(end-start) * time.Microsecond
+	w.next = end
+	w.ready = append(w.ready, w.frag)
+	w.frag = nil
+}
+
+// AddStream adds a stream with the given codec parameters and time base
+// and returns its index.
+func (w *Writer) AddStream(par *libavcodec.AVCodecParameters, tb libavutil.AVRational) (int, error) {
+	st := w.ctx.AvformatNewStream(nil)
+	if st == nil {
+		return 0, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := libavcodec.AvcodecParametersCopy(st.Codecpar, par); ret < 0 {
+		return 0, fmt.Errorf("fmp4: copy codec parameters: %w", libavutil.ErrorFromCode(ret))
+	}
+	st.Codecpar.CodecTag = 0
+	st.TimeBase = tb
+	return int(st.Index), nil
+}
+
+// Stream returns the stream at index i, whose time base may have been
+// changed by WriteHeader.
+func (w *Writer) Stream(i int) *libavformat.AVStream {
+	return w.ctx.GetStream(ffcommon.FUnsignedInt(i))
+}
+
+// WriteHeader writes the init segment and passes it to OnInit.
+func (w *Writer) WriteHeader() error {
+	var d *libavutil.AVDictionary
+	for k, v := range w.c.MuxerOptions {
+		libavutil.AvDictSet(&d, k, v, 0)
+	}
+	// empty_moov puts no samples in the init segment and default_base_moof
+	// makes every fragment self-contained
+	libavutil.AvDictSet(&d, "movflags", "+frag_keyframe+empty_moov+default_base_moof+skip_trailer", libavutil.AV_DICT_APPEND)
+	if w.c.FragmentDuration > 0 {
+		libavutil.AvDictSet(&d, "frag_duration", strconv.FormatInt(w.c.FragmentDuration.Microseconds(), 10), 0)
+	}
+	ret := w.ctx.AvformatWriteHeader(&d)
+	libavutil.AvDictFree(&d)
+	if ret < 0 {
+		return fmt.Errorf("fmp4: write header: %w", libavutil.ErrorFromCode(ret))
+	}
+	w.started = true
+	w.flush(w.end)
+	if w.err != nil {
+		return w.err
+	}
+	init := w.init
+	w.init = nil
+	if w.c.OnInit != nil {
+		if err := w.c.OnInit(init); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePacket writes pkt, with timestamps in the time base of its stream
+// after WriteHeader, and passes the fragments it completes to OnFragment.
+// pkt is unreferenced.
+func (w *Writer) WritePacket(pkt *libavcodec.AVPacket) error {
+	if w.err != nil {
+		pkt.AvPacketUnref()
+		return w.err
+	}
+	if !w.started {
+		pkt.AvPacketUnref()
+		return errors.New("fmp4: WritePacket before WriteHeader")
+	}
+	tb := w.Stream(int(pkt.StreamIndex)).TimeBase
+	dts := pkt.Dts
+	if dts == libavutil.AV_NOPTS_VALUE {
+		dts = pkt.Pts
+	}
+	var at, end int64 = math.MinInt64, math.MinInt64
+	if dts != libavutil.AV_NOPTS_VALUE {
+		at = int64(libavutil.AvRescaleQ(dts, tb, timeBaseQ))
+		end = int64(libavutil.AvRescaleQ(dts+pkt.Duration, tb, timeBaseQ))
+	}
+	w.begin(at)
+	ret := w.ctx.AvWriteFrame(pkt)
+	pkt.AvPacketUnref()
+	if ret < 0 && w.err == nil {
+		w.err = fmt.Errorf("fmp4: write packet: %w", libavutil.ErrorFromCode(ret))
+	}
+	w.flush(w.cut)
+	w.end = max(w.end, end)
+	return w.err
+}
+
+// begin sets the times for a packet starting at at, math.MinInt64 if
+// unknown.
+func (w *Writer) begin(at int64) {
+	if w.next == math.MinInt64 {
+		w.next = at
+	}
+	// a fragment completed by this packet ends where the packet starts
+	w.cut = w.end
+	if at != math.MinInt64 {
+		w.cut = at
+	}
+}
+
+// flush pushes the buffered output through writeDataType and hands out
+// the completed fragments, the last one ending at end.
+func (w *Writer) flush(end int64) {
+	w.ctx.Pb.AvioWriteMarker(libavutil.AV_NOPTS_VALUE, libavformat.AVIO_DATA_MARKER_FLUSH_POINT)
+	w.deliver(end)
+}
+
+// deliver passes the fragments completed so far to OnFragment, the last
+// one ending at end.
+func (w *Writer) deliver(end int64) {
+	// the muxer writes a fragment in one go, so it is complete once the
+	// write that started it returns
+	w.closeFragment(end)
+	ready := w.ready
+	w.ready = nil
+	for _, f := range ready {
+		if w.err != nil {
+			return
+		}
+		if w.c.OnFragment != nil {
+			w.err = w.c.OnFragment(*f)
+		}
+	}
+}
+
+// Close writes the last fragment and frees the Writer.
+func (w *Writer) Close() error {
+	if w.ctx == nil {
+		return w.err
+	}
+	if w.started {
+		w.started = false
+		if ret := w.ctx.AvWriteTrailer(); ret < 0 && w.err == nil {
+			w.err = fmt.Errorf("fmp4: write trailer: %w", libavutil.ErrorFromCode(ret))
+		}
+		w.flush(w.end)
+	}
+	if w.ctx.Pb != nil {
+		libavutil.AvFreep(uintptr(unsafe.Pointer(&w.ctx.Pb.Buffer)))
+		libavformat.AvioContextFree(&w.ctx.Pb)
+	}
+	w.ctx.AvformatFreeContext()
+	w.ctx = nil
+	writersMu.Lock()
+	delete(writers, w.id)
+	writersMu.Unlock()
+	return w.err
+}
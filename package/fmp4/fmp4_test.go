@@ -0,0 +1,169 @@
+package fmp4
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// newTestWriter registers a Writer without a muxer, to drive
+// writeDataType directly.
+func newTestWriter(t *testing.T, c Config) *Writer {
+	w := &Writer{c: c, next: math.MinInt64, cut: math.MinInt64, end: math.MinInt64, started: true}
+	writersMu.Lock()
+	lastID++
+	w.id = lastID
+	writers[w.id] = w
+	writersMu.Unlock()
+	t.Cleanup(func() {
+		writersMu.Lock()
+		delete(writers, w.id)
+		writersMu.Unlock()
+	})
+	return w
+}
+
+// chunk is a write of the muxer.
+type chunk struct {
+	typ  libavformat.AVIODataMarkerType
+	data string
+}
+
+func feed(t *testing.T, id uintptr, chunks []chunk) {
+	t.Helper()
+	for _, c := range chunks {
+		buf := []byte(c.data)
+		if ret := writeDataType(id, &buf[0], ffcommon.FInt(len(buf)), c.typ, libavutil.AV_NOPTS_VALUE); ret != uintptr(len(buf)) {
+			t.Fatalf("writeDataType(%q) = %d", c.data, ret)
+		}
+		// the muxer reuses its buffer
+		clear(buf)
+	}
+}
+
+// packet does what WritePacket does around av_write_frame for a packet
+// from at to end, during which the muxer writes chunks.
+func packet(t *testing.T, w *Writer, at, end int64, chunks ...chunk) {
+	t.Helper()
+	w.begin(at)
+	feed(t, w.id, chunks)
+	w.deliver(w.cut)
+	w.end = max(w.end, end)
+}
+
+func TestFragments(t *testing.T) {
+	var got []Fragment
+	w := newTestWriter(t, Config{OnFragment: func(f Fragment) error {
+		got = append(got, f)
+		return nil
+	}})
+	expect := func(step string, want ...Fragment) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: fragments\n%+v\nwant\n%+v", step, got, want)
+		}
+		got = nil
+	}
+
+	// WriteHeader
+	feed(t, w.id, []chunk{
+		{libavformat.AVIO_DATA_MARKER_HEADER, "ftyp"},
+		{libavformat.AVIO_DATA_MARKER_UNKNOWN, "moov"},
+	})
+	w.deliver(w.end)
+	if string(w.init) != "ftypmoov" {
+		t.Errorf("init segment %q, want ftypmoov", w.init)
+	}
+	expect("header")
+
+	// the first fragment is written when the keyframe at 80ms starts the
+	// next one
+	packet(t, w, 0, 40000)
+	packet(t, w, 40000, 80000)
+	expect("buffered")
+	packet(t, w, 80000, 120000,
+		chunk{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof1"},
+		chunk{libavformat.AVIO_DATA_MARKER_UNKNOWN, "mdat1"},
+	)
+	expect("keyframe", Fragment{Seq: 1, Start: 0, Duration: 80 * time.Millisecond, Data: []byte("moof1mdat1")})
+
+	// cut by frag_duration
+	packet(t, w, 120000, 160000,
+		chunk{libavformat.AVIO_DATA_MARKER_BOUNDARY_POINT, "moof2"},
+		chunk{libavformat.AVIO_DATA_MARKER_UNKNOWN, "md"},
+		chunk{libavformat.AVIO_DATA_MARKER_UNKNOWN, "at2"},
+	)
+	expect("boundary", Fragment{Seq: 2, Start: 80 * time.Millisecond, Duration: 40 * time.Millisecond, Data: []byte("moof2mdat2")})
+
+	// a packet without timestamps ends the fragment where the last one did
+	packet(t, w, math.MinInt64, math.MinInt64)
+	packet(t, w, 200000, 240000,
+		chunk{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof3"},
+		chunk{libavformat.AVIO_DATA_MARKER_UNKNOWN, "mdat3"},
+	)
+	expect("after untimed packet", Fragment{Seq: 3, Start: 120 * time.Millisecond, Duration: 80 * time.Millisecond, Data: []byte("moof3mdat3")})
+
+	// Close
+	feed(t, w.id, []chunk{
+		{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof4"},
+		{libavformat.AVIO_DATA_MARKER_UNKNOWN, "mdat4"},
+		{libavformat.AVIO_DATA_MARKER_TRAILER, "mfra"},
+	})
+	w.deliver(w.end)
+	expect("trailer", Fragment{Seq: 4, Start: 200 * time.Millisecond, Duration: 40 * time.Millisecond, Data: []byte("moof4mdat4")})
+	if string(w.init) != "ftypmoov" {
+		t.Errorf("init segment %q after the fragments", w.init)
+	}
+}
+
+func TestFragmentsInOneWrite(t *testing.T) {
+	var got []Fragment
+	w := newTestWriter(t, Config{OnFragment: func(f Fragment) error {
+		got = append(got, f)
+		return nil
+	}})
+	packet(t, w, 0, 40000)
+	packet(t, w, 40000, 80000,
+		chunk{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof1"},
+		chunk{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof2"},
+	)
+	want := []Fragment{
+		{Seq: 1, Start: 0, Duration: 40 * time.Millisecond, Data: []byte("moof1")},
+		{Seq: 2, Start: 40 * time.Millisecond, Duration: 0, Data: []byte("moof2")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fragments\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestOnFragmentError(t *testing.T) {
+	errStop := errors.New("stop")
+	calls := 0
+	w := newTestWriter(t, Config{OnFragment: func(f Fragment) error {
+		calls++
+		return errStop
+	}})
+	packet(t, w, 0, 40000,
+		chunk{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof1"},
+		chunk{libavformat.AVIO_DATA_MARKER_SYNC_POINT, "moof2"},
+	)
+	if calls != 1 || w.err != errStop {
+		t.Errorf("%d calls, error %v, want 1 call and %v", calls, w.err, errStop)
+	}
+	if len(w.ready) != 0 {
+		t.Errorf("%d fragments still queued", len(w.ready))
+	}
+}
+
+func TestWriteDataTypeUnknownWriter(t *testing.T) {
+	buf := []byte("moof")
+	if ret := ffcommon.FInt(writeDataType(0, &buf[0], 4, libavformat.AVIO_DATA_MARKER_SYNC_POINT, 0)); ret != libavutil.AVERROR_EXTERNAL {
+		t.Errorf("writeDataType = %d, want AVERROR_EXTERNAL", ret)
+	}
+}
@@ -0,0 +1,472 @@
+// Package hls packages media files for HTTP Live Streaming by stream copy
+// through the FFmpeg hls muxer.
+package hls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// SegmentType is the container of the media segments.
+type SegmentType int
+
+const (
+	// TS writes MPEG-TS segments.
+	TS SegmentType = iota
+	// FMP4 writes fragmented MP4 segments with a separate init segment.
+	FMP4
+)
+
+// PlaylistType is the EXT-X-PLAYLIST-TYPE of the media playlists.
+type PlaylistType int
+
+const (
+	// Live writes a sliding window playlist without a type.
+	Live PlaylistType = iota
+	// Event writes a playlist segments are only appended to.
+	Event
+	// VOD writes a complete playlist.
+	VOD
+)
+
+// defaultSegmentDuration is the hls_time default of the muxer.
+const defaultSegmentDuration = 2 * time.Second
+
+// Variant is one rendition of a multi-variant stream. Video and Audio
+// select the nth video and audio stream of the source, -1 for none.
+type Variant struct {
+	Name  string
+	Video int
+	Audio int
+}
+
+// Config configures Write. The zero value writes a live TS playlist with
+// the muxer's default segment duration.
+type Config struct {
+	// SegmentDuration is the target segment duration. Segments are cut at
+	// the first keyframe after it unless SplitByTime is set.
+	SegmentDuration time.Duration
+	SplitByTime     bool
+	SegmentType     SegmentType
+	PlaylistType    PlaylistType
+	// ListSize is the maximum number of segments of a Live playlist, 0
+	// for all.
+	ListSize int
+	// DeleteSegments removes the segment files a Live playlist with a
+	// ListSize no longer lists.
+	DeleteSegments bool
+
+	// PlaylistName is the name of the media playlists, "index.m3u8" by
+	// default.
+	PlaylistName string
+	// SegmentName is the segment file pattern, with a %d style sequence
+	// number, "segment_%05d.ts" or "segment_%05d.m4s" by default.
+	SegmentName string
+	// InitName is the name of the fMP4 init segment, "init.mp4" by
+	// default.
+	InitName string
+
+	// MasterPlaylist is the name of the master playlist, written if set or
+	// if there are Variants ("master.m3u8" by default then).
+	MasterPlaylist string
+	// Variants splits the source into renditions, each written to its own
+	// subdirectory named after it.
+	Variants []Variant
+
+	// KeyFile is the path of a 16 byte AES-128 key. Segments are encrypted
+	// if set.
+	KeyFile string
+	// KeyURI is the key URI written to the playlists, the base name of
+	// KeyFile by default.
+	KeyURI string
+	// KeyIV is the hexadecimal initialization vector, the segment
+	// sequence number by default.
+	KeyIV string
+
+	// StrictKeyframes makes Write fail if a segment does not start on a
+	// keyframe.
+	StrictKeyframes bool
+}
+
+// Result describes the written playlists.
+type Result struct {
+	// Master is the path of the master playlist, or "".
+	Master    string
+	Playlists []*Playlist
+	// MaxKeyframeInterval is the longest distance between two video
+	// keyframes of the source.
+	MaxKeyframeInterval time.Duration
+}
+
+// Misaligned returns the segments not starting on a keyframe.
+func (r *Result) Misaligned() []Segment {
+	var segs []Segment
+	for _, p := range r.Playlists {
+		for _, s := range p.Segments {
+			if !s.Keyframe {
+				segs = append(segs, s)
+			}
+		}
+	}
+	return segs
+}
+
+// AlignmentError is returned with StrictKeyframes when segments do not
+// start on keyframes.
+type AlignmentError struct {
+	Segments []Segment
+}
+
+func (e *AlignmentError) Error() string {
+	return fmt.Sprintf("hls: %d segments do not start on a keyframe, first %s at %v", len(e.Segments), e.Segments[0].URI, e.Segments[0].Start)
+}
+
+// Write packages src into dir, which is created if needed. Every segment
+// boundary of the resulting playlists is checked against the keyframes of
+// the source. With StrictKeyframes a misaligned boundary makes Write return
+// an *AlignmentError together with the result.
+func Write(ctx context.Context, src, dir string, c Config) (*Result, error) {
+	c.defaults()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+	playlist := filepath.Join(dir, c.PlaylistName)
+	segments := filepath.Join(dir, c.SegmentName)
+	if len(c.Variants) > 0 {
+		for _, v := range c.Variants {
+			if v.Name == "" || strings.ContainsAny(v.Name, `/\,: `) {
+				return nil, fmt.Errorf("hls: bad variant name %q", v.Name)
+			}
+			if err := os.MkdirAll(filepath.Join(dir, v.Name), 0o755); err != nil {
+				return nil, fmt.Errorf("hls: %w", err)
+			}
+		}
+		playlist = filepath.Join(dir, "%v", c.PlaylistName)
+		segments = filepath.Join(dir, "%v", c.SegmentName)
+	}
+
+	ic, err := remux.OpenInput(src)
+	if err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+
+	o, err := remux.NewOutput(ic, playlist, "hls")
+	if err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+	defer o.Close()
+
+	opts, cleanup, err := c.options(segments)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	if err = o.WriteHeader(opts); err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+
+	video, audio := outputStreams(ic, o)
+	variants := c.Variants
+	if len(variants) == 0 {
+		variants = []Variant{{Video: 0, Audio: 0}}
+	}
+	streams := make([][]int, len(variants))
+	for i, v := range variants {
+		if v.Video >= 0 && v.Video < len(video) {
+			streams[i] = append(streams[i], video[v.Video])
+		}
+		if v.Audio >= 0 && v.Audio < len(audio) {
+			streams[i] = append(streams[i], audio[v.Audio])
+		}
+	}
+	// a sliding window forgets the segments it drops, follow it while
+	// writing so segment times keep counting from the start of the stream
+	windows := make([]*window, len(variants))
+	if c.PlaylistType == Live && c.ListSize > 0 {
+		segment := c.SegmentDuration
+		if segment <= 0 {
+			segment = defaultSegmentDuration
+		}
+		for i, v := range variants {
+			if len(streams[i]) > 0 {
+				ref := streams[i][0]
+				refVideo := v.Video >= 0 && v.Video < len(video)
+				windows[i] = newWindow(filepath.Join(dir, v.Name, c.PlaylistName), ref, refVideo, segment)
+			}
+		}
+	}
+	kf, err := copyPackets(ctx, ic, o, windows)
+	if err != nil {
+		return nil, err
+	}
+	if err = o.Finish(); err != nil {
+		return nil, fmt.Errorf("hls: %w", err)
+	}
+
+	res := &Result{MaxKeyframeInterval: kf.maxInterval()}
+	if c.MasterPlaylist != "" {
+		res.Master = filepath.Join(dir, c.MasterPlaylist)
+	}
+	for i, v := range variants {
+		p, err := ReadPlaylist(filepath.Join(dir, v.Name, c.PlaylistName))
+		if err != nil {
+			return nil, fmt.Errorf("hls: %w", err)
+		}
+		p.Name = v.Name
+		if windows[i] != nil {
+			windows[i].rebase(p)
+		}
+		ref := -1
+		if v.Video >= 0 && v.Video < len(video) {
+			ref = video[v.Video]
+		}
+		if ref >= 0 {
+			kf.check(p, ref, streams[i], tolerance(ic.GetStream(ffcommon.FUnsignedInt(ref))))
+		}
+		res.Playlists = append(res.Playlists, p)
+	}
+	if c.StrictKeyframes {
+		if bad := res.Misaligned(); len(bad) > 0 {
+			return res, &AlignmentError{Segments: bad}
+		}
+	}
+	return res, nil
+}
+
+func (c *Config) defaults() {
+	if c.PlaylistName == "" {
+		c.PlaylistName = "index.m3u8"
+	}
+	if c.SegmentName == "" {
+		c.SegmentName = "segment_%05d.ts"
+		if c.SegmentType == FMP4 {
+			c.SegmentName = "segment_%05d.m4s"
+		}
+	}
+	if c.InitName == "" {
+		c.InitName = "init.mp4"
+	}
+	if c.MasterPlaylist == "" && len(c.Variants) > 0 {
+		c.MasterPlaylist = "master.m3u8"
+	}
+	if c.KeyFile != "" && c.KeyURI == "" {
+		c.KeyURI = filepath.Base(c.KeyFile)
+	}
+}
+
+// options returns the hls muxer options. cleanup removes the temporary key
+// info file and must be called after the trailer is written.
+func (c *Config) options(segments string) (opts map[string]string, cleanup func(), err error) {
+	cleanup = func() {}
+	opts = map[string]string{
+		"hls_segment_filename": segments,
+		"hls_list_size":        strconv.Itoa(c.ListSize),
+	}
+	if c.SegmentDuration > 0 {
+		opts["hls_time"] = strconv.FormatFloat(c.SegmentDuration.Seconds(), 'f', -1, 64)
+	}
+	flags := []string{"independent_segments"}
+	if c.SplitByTime {
+		flags = append(flags, "split_by_time")
+	}
+	if c.DeleteSegments && c.PlaylistType == Live && c.ListSize > 0 {
+		flags = append(flags, "delete_segments")
+	}
+	opts["hls_flags"] = strings.Join(flags, "+")
+	switch c.PlaylistType {
+	case Event:
+		opts["hls_playlist_type"] = "event"
+		opts["hls_list_size"] = "0"
+	case VOD:
+		opts["hls_playlist_type"] = "vod"
+		opts["hls_list_size"] = "0"
+	}
+	if c.SegmentType == FMP4 {
+		opts["hls_segment_type"] = "fmp4"
+		opts["hls_fmp4_init_filename"] = c.InitName
+	}
+	if c.MasterPlaylist != "" {
+		opts["master_pl_name"] = c.MasterPlaylist
+	}
+	if len(c.Variants) > 0 {
+		var maps []string
+		for _, v := range c.Variants {
+			var m []string
+			if v.Video >= 0 {
+				m = append(m, "v:"+strconv.Itoa(v.Video))
+			}
+			if v.Audio >= 0 {
+				m = append(m, "a:"+strconv.Itoa(v.Audio))
+			}
+			if len(m) == 0 {
+				return opts, cleanup, fmt.Errorf("hls: variant %s has no streams", v.Name)
+			}
+			maps = append(maps, strings.Join(append(m, "name:"+v.Name), ","))
+		}
+		opts["var_stream_map"] = strings.Join(maps, " ")
+	}
+	if c.KeyFile != "" {
+		// the key info file holds the key URI, the key path and the IV
+		f, err := os.CreateTemp("", "hls-keyinfo-*")
+		if err != nil {
+			return opts, cleanup, fmt.Errorf("hls: %w", err)
+		}
+		cleanup = func() { os.Remove(f.Name()) }
+		info := c.KeyURI + "\n" + c.KeyFile + "\n"
+		if c.KeyIV != "" {
+			info += c.KeyIV + "\n"
+		}
+		_, err = f.WriteString(info)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return opts, cleanup, fmt.Errorf("hls: %w", err)
+		}
+		opts["hls_key_info_file"] = f.Name()
+	}
+	return opts, cleanup, nil
+}
+
+// outputStreams returns the input indices of the video and audio streams
+// written, in the order var_stream_map numbers them.
+func outputStreams(ic *libavformat.AVFormatContext, o *remux.Output) (video, audio []int) {
+	for i, m := range o.Map {
+		if m < 0 {
+			continue
+		}
+		switch ic.GetStream(ffcommon.FUnsignedInt(i)).Codecpar.CodecType {
+		case libavutil.AVMEDIA_TYPE_VIDEO:
+			video = append(video, i)
+		case libavutil.AVMEDIA_TYPE_AUDIO:
+			audio = append(audio, i)
+		}
+	}
+	return video, audio
+}
+
+// tolerance is how far a segment boundary may be from a keyframe: half a
+// frame of st, at least a millisecond for the rounding of EXTINF.
+func tolerance(st *libavformat.AVStream) time.Duration {
+	tol := time.Millisecond
+	if r := st.AvgFrameRate; r.Num > 0 && r.Den > 0 {
+		if half := time.Duration(float64(time.Second) * float64(r.Den) / float64(r.Num) / 2); half > tol {
+			tol = half
+		}
+	}
+	return tol
+}
+
+// keyframes records the timing of the source packets in AV_TIME_BASE
+// units, per input stream.
+type keyframes struct {
+	// first is the time of the first packet, math.MinInt64 if none
+	first []int64
+	key   [][]int64
+}
+
+func (k *keyframes) maxInterval() time.Duration {
+	var max int64
+	for _, key := range k.key {
+		for i := 1; i < len(key); i++ {
+			if d := key[i] - key[i-1]; d > max {
+				max = d
+			}
+		}
+	}
+	return time.Duration(max) * time.Microsecond
+}
+
+// check marks the segments of p that do not start on a keyframe of stream
+// ref. Segment times count from the first packet of the variant's streams.
+func (k *keyframes) check(p *Playlist, ref int, streams []int, tol time.Duration) {
+	origin := int64(-1)
+	for _, s := range streams {
+		if k.first[s] != math.MinInt64 && (origin < 0 || k.first[s] < origin) {
+			origin = k.first[s]
+		}
+	}
+	key := k.key[ref]
+	j := 0
+	for i := range p.Segments {
+		t := p.Segments[i].Start
+		for j+1 < len(key) && time.Duration(key[j+1]-origin)*time.Microsecond <= t+tol {
+			j++
+		}
+		ok := false
+		for _, n := range []int{j, j + 1} {
+			if n < len(key) {
+				d := time.Duration(key[n]-origin)*time.Microsecond - t
+				if d < 0 {
+					d = -d
+				}
+				ok = ok || d <= tol
+			}
+		}
+		p.Segments[i].Keyframe = ok
+	}
+}
+
+// copyPackets forwards all packets of ic to o, recording the keyframes and
+// updating the windows after the packets the muxer may cut a segment on.
+func copyPackets(ctx context.Context, ic *libavformat.AVFormatContext, o *remux.Output, windows []*window) (*keyframes, error) {
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+
+	timeBaseQ := libavutil.AVRational{Num: 1, Den: libavutil.AV_TIME_BASE}
+	k := &keyframes{first: make([]int64, ic.NbStreams), key: make([][]int64, ic.NbStreams)}
+	for i := range k.first {
+		k.first[i] = math.MinInt64
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := libavutil.ErrorFromCode(ic.AvReadFrame(pkt))
+		if err == io.EOF {
+			return k, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("hls: read packet: %w", err)
+		}
+		idx := int(pkt.StreamIndex)
+		timed := idx < len(o.Map) && o.Map[idx] >= 0 && pkt.Pts != libavutil.AV_NOPTS_VALUE
+		var t int64
+		if timed {
+			st := ic.GetStream(pkt.StreamIndex)
+			t = int64(libavutil.AvRescaleQ(pkt.Pts, st.TimeBase, timeBaseQ))
+			if k.first[idx] == math.MinInt64 {
+				k.first[idx] = t
+			}
+			if st.Codecpar.CodecType == libavutil.AVMEDIA_TYPE_VIDEO && pkt.Flags&libavcodec.AV_PKT_FLAG_KEY != 0 {
+				k.key[idx] = append(k.key[idx], t)
+			}
+		}
+		key := pkt.Flags&libavcodec.AV_PKT_FLAG_KEY != 0
+		if err = o.WritePacket(pkt); err != nil {
+			return nil, fmt.Errorf("hls: %w", err)
+		}
+		for _, w := range windows {
+			if w != nil && timed && w.ref == idx && (key || !w.refVideo) {
+				w.update(time.Duration(t) * time.Microsecond)
+			}
+		}
+	}
+}
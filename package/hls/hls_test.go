@@ -0,0 +1,121 @@
+package hls
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestKeyframesCheck(t *testing.T) {
+	k := &keyframes{
+		// video, audio, and a stream without packets
+		first: []int64{1000000, 900000, math.MinInt64},
+		key:   [][]int64{{1000000, 3000000, 5000000, 7000000}, nil, nil},
+	}
+	for _, tc := range []struct {
+		name    string
+		streams []int
+		starts  []time.Duration
+		want    []bool
+	}{
+		{
+			name:    "from the audio",
+			streams: []int{0, 1, 2},
+			starts: []time.Duration{
+				100 * time.Millisecond,
+				2115 * time.Millisecond,
+				4 * time.Second,
+				6090 * time.Millisecond,
+				8 * time.Second,
+			},
+			want: []bool{true, true, false, true, false},
+		},
+		{
+			name:    "video only",
+			streams: []int{0},
+			starts:  []time.Duration{0, 2 * time.Second, 4030 * time.Millisecond, 5990 * time.Millisecond},
+			want:    []bool{true, true, false, true},
+		},
+	} {
+		p := &Playlist{}
+		for _, s := range tc.starts {
+			p.Segments = append(p.Segments, Segment{Start: s})
+		}
+		k.check(p, 0, tc.streams, 20*time.Millisecond)
+		for i, s := range p.Segments {
+			if s.Keyframe != tc.want[i] {
+				t.Errorf("%s: segment at %v: keyframe %v, want %v", tc.name, s.Start, s.Keyframe, tc.want[i])
+			}
+		}
+	}
+	if got := k.maxInterval(); got != 2*time.Second {
+		t.Errorf("maxInterval() = %v, want 2s", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		c    Config
+		want map[string]string
+	}{
+		{
+			name: "live window",
+			c:    Config{ListSize: 5, SegmentDuration: 4 * time.Second},
+			want: map[string]string{"hls_list_size": "5", "hls_time": "4", "hls_flags": "independent_segments"},
+		},
+		{
+			name: "live window deleting segments",
+			c:    Config{ListSize: 5, DeleteSegments: true, SplitByTime: true},
+			want: map[string]string{"hls_list_size": "5", "hls_flags": "independent_segments+split_by_time+delete_segments"},
+		},
+		{
+			name: "vod",
+			c:    Config{PlaylistType: VOD, ListSize: 5, DeleteSegments: true, SegmentType: FMP4},
+			want: map[string]string{
+				"hls_list_size":          "0",
+				"hls_flags":              "independent_segments",
+				"hls_playlist_type":      "vod",
+				"hls_segment_type":       "fmp4",
+				"hls_fmp4_init_filename": "init.mp4",
+			},
+		},
+		{
+			name: "variants",
+			c:    Config{PlaylistType: Event, Variants: []Variant{{Name: "hd", Video: 0, Audio: 0}, {Name: "audio", Video: -1, Audio: 1}}},
+			want: map[string]string{
+				"hls_list_size":     "0",
+				"hls_flags":         "independent_segments",
+				"hls_playlist_type": "event",
+				"master_pl_name":    "master.m3u8",
+				"var_stream_map":    "v:0,a:0,name:hd a:1,name:audio",
+			},
+		},
+	} {
+		tc.c.defaults()
+		opts, cleanup, err := tc.c.options("segment_%05d.ts")
+		cleanup()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		tc.want["hls_segment_filename"] = "segment_%05d.ts"
+		if len(opts) != len(tc.want) {
+			t.Errorf("%s: options %v, want %v", tc.name, opts, tc.want)
+			continue
+		}
+		for k, v := range tc.want {
+			if opts[k] != v {
+				t.Errorf("%s: %s = %q, want %q", tc.name, k, opts[k], v)
+			}
+		}
+	}
+
+	c := Config{Variants: []Variant{{Name: "none", Video: -1, Audio: -1}}}
+	c.defaults()
+	_, cleanup, err := c.options("segment_%05d.ts")
+	cleanup()
+	if err == nil {
+		t.Error("a variant without streams was accepted")
+	}
+}
@@ -0,0 +1,164 @@
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is one media segment of a playlist.
+type Segment struct {
+	URI string
+	// Sequence is the media sequence number of the segment.
+	Sequence int
+	// Start is the time of the segment from the start of the stream. For
+	// a sliding window playlist read with ReadPlaylist, it counts from the
+	// first segment still listed.
+	Start    time.Duration
+	Duration time.Duration
+	// Keyframe tells whether the segment starts on a keyframe of the
+	// variant's video stream. It is always true for audio-only variants.
+	Keyframe bool
+}
+
+// Playlist is a parsed media playlist.
+type Playlist struct {
+	Path string
+	Name string
+	Init string
+	// MediaSequence is the sequence number of the first segment, above 0
+	// once a sliding window dropped segments.
+	MediaSequence int
+	Segments      []Segment
+}
+
+// ReadPlaylist parses the media playlist at path.
+func ReadPlaylist(path string) (*Playlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &Playlist{Path: path}
+	var dur time.Duration
+	var start time.Duration
+	inf := false
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			v := strings.TrimPrefix(line, "#EXTINF:")
+			if i := strings.IndexByte(v, ','); i >= 0 {
+				v = v[:i]
+			}
+			sec, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("hls: %s: bad EXTINF %q", path, line)
+			}
+			dur = time.Duration(sec * float64(time.Second))
+			inf = true
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			n, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("hls: %s: bad EXT-X-MEDIA-SEQUENCE %q", path, line)
+			}
+			p.MediaSequence = n
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			if i := strings.Index(line, `URI="`); i >= 0 {
+				uri := line[i+5:]
+				if j := strings.IndexByte(uri, '"'); j >= 0 {
+					p.Init = uri[:j]
+				}
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			if !inf {
+				return nil, fmt.Errorf("hls: %s: segment %q without EXTINF", path, line)
+			}
+			p.Segments = append(p.Segments, Segment{
+				URI:      line,
+				Sequence: p.MediaSequence + len(p.Segments),
+				Start:    start,
+				Duration: dur,
+				Keyframe: true,
+			})
+			start += dur
+			inf = false
+		}
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// window follows a sliding window playlist while it is written, to keep
+// the durations of the segments it drops.
+type window struct {
+	path string
+	// ref is the input index of the stream the muxer cuts the variant's
+	// segments on
+	ref      int
+	refVideo bool
+	// segment is the target segment duration, origin the time of the
+	// first packet of ref and next the time from it before which the
+	// muxer cannot have cut another segment
+	segment      time.Duration
+	origin, next time.Duration
+	started      bool
+	durations    map[int]time.Duration
+}
+
+func newWindow(path string, ref int, refVideo bool, segment time.Duration) *window {
+	return &window{
+		path:      path,
+		ref:       ref,
+		refVideo:  refVideo,
+		segment:   segment,
+		next:      segment / 2,
+		durations: map[int]time.Duration{},
+	}
+}
+
+// update records the segments currently listed if the muxer may have cut
+// one since the last time, t being the time of the packet of ref just
+// written. The playlist may not be written yet.
+func (w *window) update(t time.Duration) {
+	if !w.started {
+		w.origin, w.started = t, true
+	}
+	if t-w.origin < w.next {
+		return
+	}
+	p, err := ReadPlaylist(w.path)
+	if err != nil {
+		return
+	}
+	for _, s := range p.Segments {
+		w.durations[s.Sequence] = s.Duration
+	}
+	// the muxer cuts at the first packet past the next multiple of the
+	// segment duration, counted from the first packet of any stream of
+	// the variant: look again from half a segment before it
+	w.next = time.Duration(len(w.durations))*w.segment + w.segment/2
+}
+
+// rebase shifts the segments of p, read once the stream is complete, by
+// the durations of the segments dropped before its first one.
+func (w *window) rebase(p *Playlist) {
+	var off time.Duration
+	for seq, d := range w.durations {
+		if seq < p.MediaSequence {
+			off += d
+		}
+	}
+	for i := range p.Segments {
+		p.Segments[i].Start += off
+	}
+}
@@ -0,0 +1,158 @@
+package hls
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writePlaylist(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPlaylist(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    *Playlist
+	}{
+		{
+			name: "vod",
+			content: `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-INDEPENDENT-SEGMENTS
+#EXTINF:4.000000,
+segment_00000.ts
+#EXTINF:2.500000,
+segment_00001.ts
+
+#EXTINF:0.5,
+segment_00002.ts
+#EXT-X-ENDLIST
+`,
+			want: &Playlist{Segments: []Segment{
+				{URI: "segment_00000.ts", Sequence: 0, Start: 0, Duration: 4 * time.Second, Keyframe: true},
+				{URI: "segment_00001.ts", Sequence: 1, Start: 4 * time.Second, Duration: 2500 * time.Millisecond, Keyframe: true},
+				{URI: "segment_00002.ts", Sequence: 2, Start: 6500 * time.Millisecond, Duration: 500 * time.Millisecond, Keyframe: true},
+			}},
+		},
+		{
+			name: "sliding window fmp4",
+			content: `#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-TARGETDURATION:2
+#EXT-X-MEDIA-SEQUENCE:7
+#EXT-X-MAP:URI="init.mp4"
+#EXTINF:2.000000,title
+segment_00007.m4s
+#EXTINF:1.500000,
+segment_00008.m4s
+`,
+			want: &Playlist{Init: "init.mp4", MediaSequence: 7, Segments: []Segment{
+				{URI: "segment_00007.m4s", Sequence: 7, Start: 0, Duration: 2 * time.Second, Keyframe: true},
+				{URI: "segment_00008.m4s", Sequence: 8, Start: 2 * time.Second, Duration: 1500 * time.Millisecond, Keyframe: true},
+			}},
+		},
+		{
+			name:    "empty",
+			content: "#EXTM3U\n",
+			want:    &Playlist{},
+		},
+		{name: "bad EXTINF", content: "#EXTM3U\n#EXTINF:four,\nsegment_00000.ts\n"},
+		{name: "segment without EXTINF", content: "#EXTM3U\n#EXTINF:4,\nsegment_00000.ts\nsegment_00001.ts\n"},
+		{name: "negative media sequence", content: "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:-1\n"},
+	} {
+		path := filepath.Join(t.TempDir(), "index.m3u8")
+		writePlaylist(t, path, tc.content)
+		p, err := ReadPlaylist(path)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("%s: ReadPlaylist() = %+v, want an error", tc.name, p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		tc.want.Path = path
+		if !reflect.DeepEqual(p, tc.want) {
+			t.Errorf("%s: ReadPlaylist() =\n%+v\nwant\n%+v", tc.name, p, tc.want)
+		}
+	}
+	if _, err := ReadPlaylist(filepath.Join(t.TempDir(), "missing.m3u8")); err == nil {
+		t.Error("ReadPlaylist of a missing file succeeded")
+	}
+}
+
+func TestWindowUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.m3u8")
+	w := newWindow(path, 0, true, 2*time.Second)
+	origin := 10 * time.Second
+
+	// not written yet
+	w.update(origin)
+	writePlaylist(t, path, "#EXTM3U\n#EXTINF:2,\nsegment_00000.ts\n")
+	for _, step := range []struct {
+		t       time.Duration
+		content string
+		want    map[int]time.Duration
+	}{
+		// too early for the muxer to have cut a segment
+		{t: 500 * time.Millisecond, want: map[int]time.Duration{}},
+		{t: 2 * time.Second, want: map[int]time.Duration{0: 2 * time.Second}},
+		{
+			t:       2500 * time.Millisecond,
+			content: "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:1\n#EXTINF:2,\nsegment_00001.ts\n#EXTINF:2.5,\nsegment_00002.ts\n",
+			want:    map[int]time.Duration{0: 2 * time.Second},
+		},
+		{t: 4 * time.Second, want: map[int]time.Duration{0: 2 * time.Second, 1: 2 * time.Second, 2: 2500 * time.Millisecond}},
+		{
+			t:       6500 * time.Millisecond,
+			content: "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:3\n#EXTINF:2,\nsegment_00003.ts\n",
+			want:    map[int]time.Duration{0: 2 * time.Second, 1: 2 * time.Second, 2: 2500 * time.Millisecond},
+		},
+		{t: 7 * time.Second, want: map[int]time.Duration{0: 2 * time.Second, 1: 2 * time.Second, 2: 2500 * time.Millisecond, 3: 2 * time.Second}},
+	} {
+		if step.content != "" {
+			writePlaylist(t, path, step.content)
+		}
+		w.update(origin + step.t)
+		if !reflect.DeepEqual(w.durations, step.want) {
+			t.Errorf("at %v: durations %v, want %v", step.t, w.durations, step.want)
+		}
+	}
+}
+
+func TestWindowRebase(t *testing.T) {
+	durations := map[int]time.Duration{0: 2 * time.Second, 1: 2 * time.Second, 2: 2500 * time.Millisecond, 3: 2 * time.Second}
+	for _, tc := range []struct {
+		name string
+		seq  int
+		want []time.Duration
+	}{
+		{"nothing dropped", 0, []time.Duration{0, 2 * time.Second}},
+		{"two dropped", 2, []time.Duration{4 * time.Second, 6 * time.Second}},
+		{"past the recorded segments", 5, []time.Duration{8500 * time.Millisecond, 10500 * time.Millisecond}},
+	} {
+		w := &window{durations: durations}
+		p := &Playlist{MediaSequence: tc.seq, Segments: []Segment{
+			{Sequence: tc.seq, Start: 0, Duration: 2500 * time.Millisecond},
+			{Sequence: tc.seq + 1, Start: 2 * time.Second, Duration: 2 * time.Second},
+		}}
+		w.rebase(p)
+		for i, s := range p.Segments {
+			if s.Start != tc.want[i] {
+				t.Errorf("%s: segment %d starts at %v, want %v", tc.name, i, s.Start, tc.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Package parse splits raw elementary streams, such as Annex B H.264 or
+// ADTS AAC, into packets with the libavcodec parsers, so they can be
+// decoded or muxed without a demuxer.
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Packet is a complete frame cut from the stream.
+type Packet struct {
+	Data     []byte
+	KeyFrame bool
+	PictType libavutil.AVPictureType
+	// Width and Height are the display size of video frames, 0 if unknown.
+	Width, Height int
+	// Duration is the frame duration, 0 if the parser does not know it.
+	Duration time.Duration
+	// Pos is the byte offset of the frame in the stream.
+	Pos int64
+}
+
+// Splitter cuts a byte stream of one codec into packets.
+type Splitter struct {
+	parser *libavcodec.AVCodecParserContext
+	avctx  *libavcodec.AVCodecContext
+	buf    []byte
+	pos    int64
+	queue  []Packet
+}
+
+// NewSplitter returns a Splitter for streams of codec id.
+func NewSplitter(id libavcodec.AVCodecID) (*Splitter, error) {
+	s := &Splitter{parser: libavcodec.AvParserInit(ffcommon.FInt(id))}
+	if s.parser == nil {
+		return nil, fmt.Errorf("parse: no parser for %s", libavcodec.AvcodecGetName(id))
+	}
+	// the parsers store stream parameters in the codec context
+	if dec := libavcodec.AvcodecFindDecoder(id); dec != nil {
+		s.avctx = dec.AvcodecAllocContext3()
+	} else {
+		s.avctx = (*libavcodec.AVCodec)(nil).AvcodecAllocContext3()
+	}
+	if s.avctx == nil {
+		s.Close()
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	s.avctx.CodecId = id
+	return s, nil
+}
+
+// Write feeds a chunk of the stream, of any size. The packets it completes
+// are returned by Packets.
+func (s *Splitter) Write(p []byte) (int, error) {
+	// the parsers may read up to AV_INPUT_BUFFER_PADDING_SIZE bytes past
+	// the end of the input
+	s.buf = append(s.buf[:0], p...)
+	s.buf = append(s.buf, make([]byte, libavcodec.AV_INPUT_BUFFER_PADDING_SIZE)...)
+	data := s.buf[:len(p)]
+	for len(data) > 0 {
+		n, err := s.parse(&data[0], len(data))
+		if err != nil {
+			return len(p) - len(data), err
+		}
+		data = data[n:]
+	}
+	return len(p), nil
+}
+
+// Flush returns the packets still buffered at the end of the stream.
+func (s *Splitter) Flush() ([]Packet, error) {
+	for {
+		queued := len(s.queue)
+		if _, err := s.parse(nil, 0); err != nil {
+			return s.Packets(), err
+		}
+		if len(s.queue) == queued {
+			return s.Packets(), nil
+		}
+	}
+}
+
+// Packets returns the packets completed so far and forgets them.
+func (s *Splitter) Packets() []Packet {
+	q := s.queue
+	s.queue = nil
+	return q
+}
+
+// parse runs the parser once over size bytes at buf and queues its output.
+func (s *Splitter) parse(buf *byte, size int) (int, error) {
+	var out *ffcommon.FUint8T
+	var outSize ffcommon.FInt
+	n := s.parser.AvParserParse2(s.avctx, &out, &outSize, buf, ffcommon.FInt(size),
+		libavutil.AV_NOPTS_VALUE, libavutil.AV_NOPTS_VALUE, ffcommon.FInt64T(s.pos))
+	if n < 0 {
+		return 0, fmt.Errorf("parse: %w", libavutil.ErrorFromCode(n))
+	}
+	s.pos += int64(n)
+	if outSize > 0 {
+		s.queue = append(s.queue, s.packet(ffcommon.ByteSliceFromByteP(out, int(outSize))))
+	}
+	return int(n), nil
+}
+
+func (s *Splitter) packet(data []byte) Packet {
+	p := s.parser
+	pkt := Packet{
+		Data:     append([]byte(nil), data...),
+		KeyFrame: p.KeyFrame == 1,
+		PictType: libavutil.AVPictureType(p.PictType),
+		Width:    int(p.Width),
+		Height:   int(p.Height),
+		Pos:      int64(p.Pos),
+	}
+	if pkt.Pos < 0 {
+		pkt.Pos = int64(p.FrameOffset)
+	}
+	if p.Duration > 0 {
+		// audio durations count samples, others codec time base units
+		if s.avctx.CodecType == libavutil.AVMEDIA_TYPE_AUDIO && s.avctx.SampleRate > 0 {
+			pkt.Duration = time.Duration(p.Duration) * time.Second / time.Duration(s.avctx.SampleRate)
+		} else if tb := s.avctx.TimeBase; tb.Num > 0 && tb.Den > 0 {
+			pkt.Duration = time.Duration(int64(p.Duration) * int64(tb.Num) * int64(time.Second) / int64(tb.Den))
+		}
+	} else if fr := s.avctx.Framerate; fr.Num > 0 && fr.Den > 0 && s.avctx.CodecType == libavutil.AVMEDIA_TYPE_VIDEO {
+		// a frame plus the repeated fields
+		pkt.Duration = time.Duration(int64(fr.Den) * int64(2+p.RepeatPict) * int64(time.Second) / (2 * int64(fr.Num)))
+	} else if s.avctx.CodecId == libavcodec.AV_CODEC_ID_AAC {
+		// the aac parser sets neither the duration nor the sample rate
+		pkt.Duration = adtsDuration(pkt.Data)
+	}
+	return pkt
+}
+
+// adtsSampleRates are indexed by the sampling_frequency_index of ADTS
+// headers.
+var adtsSampleRates = [...]int64{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// adtsDuration returns the duration of the ADTS frame starting data, 1024
+// samples per raw data block, 0 if data has no valid header.
+func adtsDuration(data []byte) time.Duration {
+	if len(data) < 7 || data[0] != 0xff || data[1]&0xf6 != 0xf0 {
+		return 0
+	}
+	i := int(data[2]>>2) & 0xf
+	if i >= len(adtsSampleRates) {
+		return 0
+	}
+	blocks := int64(data[6]&3) + 1
+	return time.Duration(blocks * 1024 * int64(time.Second) / adtsSampleRates[i])
+}
+
+// Params returns the stream parameters found by the parser so far, such
+// as the size, sample rate or channel count. Free them with
+// libavcodec.AvcodecParametersFree.
+func (s *Splitter) Params() (*libavcodec.AVCodecParameters, error) {
+	par := libavcodec.AvcodecParametersAlloc()
+	if par == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := par.AvcodecParametersFromContext(s.avctx); ret < 0 {
+		libavcodec.AvcodecParametersFree(&par)
+		return nil, fmt.Errorf("parse: %w", libavutil.ErrorFromCode(ret))
+	}
+	return par, nil
+}
+
+// Close frees the parser.
+func (s *Splitter) Close() {
+	if s.parser != nil {
+		s.parser.AvParserClose()
+		s.parser = nil
+	}
+	libavcodec.AvcodecFreeContext(&s.avctx)
+}
+
+// AVPacket returns a newly allocated AVPacket holding p, without
+// timestamps. Free it with libavcodec.AvPacketFree.
+func (p *Packet) AVPacket() (*libavcodec.AVPacket, error) {
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := pkt.AvNewPacket(ffcommon.FInt(len(p.Data))); ret < 0 {
+		libavcodec.AvPacketFree(&pkt)
+		return nil, libavutil.ErrorFromCode(ret)
+	}
+	copy(ffcommon.ByteSliceFromByteP(pkt.Data, len(p.Data)), p.Data)
+	if p.KeyFrame {
+		pkt.Flags |= libavcodec.AV_PKT_FLAG_KEY
+	}
+	pkt.Pos = ffcommon.FInt64T(p.Pos)
+	return pkt, nil
+}
+
+// Split reads a raw stream of codec id from r and yields its packets.
+func Split(r io.Reader, id libavcodec.AVCodecID) iter.Seq2[Packet, error] {
+	return func(yield func(Packet, error) bool) {
+		s, err := NewSplitter(id)
+		if err != nil {
+			yield(Packet{}, err)
+			return
+		}
+		defer s.Close()
+		buf := make([]byte, 32*1024)
+		for {
+			n, rerr := r.Read(buf)
+			if n > 0 {
+				if _, err = s.Write(buf[:n]); err != nil {
+					yield(Packet{}, err)
+					return
+				}
+				for _, p := range s.Packets() {
+					if !yield(p, nil) {
+						return
+					}
+				}
+			}
+			if errors.Is(rerr, io.EOF) {
+				break
+			}
+			if rerr != nil {
+				yield(Packet{}, fmt.Errorf("parse: %w", rerr))
+				return
+			}
+		}
+		packets, err := s.Flush()
+		for _, p := range packets {
+			if !yield(p, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(Packet{}, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// adtsHeader returns the 7-byte ADTS header of an AAC LC stereo frame of
+// length bytes, header included.
+func adtsHeader(freqIndex, blocks, length int) []byte {
+	return []byte{
+		0xff, 0xf1,
+		byte(1<<6 | freqIndex<<2),
+		byte(2<<6 | length>>11),
+		byte(length >> 3),
+		byte(length<<5 | 0x1f),
+		byte(0xfc | (blocks - 1)),
+	}
+}
+
+func TestADTSDuration(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want time.Duration
+	}{
+		{"48 kHz", adtsHeader(3, 1, 371), 21333333},
+		{"44.1 kHz", adtsHeader(4, 1, 371), 23219954},
+		{"8 kHz", adtsHeader(11, 1, 100), 128 * time.Millisecond},
+		{"96 kHz", adtsHeader(0, 1, 100), 10666666},
+		{"two raw data blocks", adtsHeader(3, 2, 700), 42666666},
+		{"four raw data blocks", adtsHeader(11, 4, 700), 512 * time.Millisecond},
+		{"reserved frequency", adtsHeader(13, 1, 100), 0},
+		{"short", adtsHeader(3, 1, 100)[:6], 0},
+		{"no sync word", []byte{0xff, 0xe1, 0x4c, 0x80, 0x2e, 0x7f, 0xfc}, 0},
+		{"MPEG audio layer", []byte{0xff, 0xfb, 0x4c, 0x80, 0x2e, 0x7f, 0xfc}, 0},
+		{"empty", nil, 0},
+	} {
+		if got := adtsDuration(tc.data); got != tc.want {
+			t.Errorf("%s: adtsDuration(% x) = %v, want %v", tc.name, tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestPacketDuration(t *testing.T) {
+	aac := &libavcodec.AVCodecContext{CodecId: libavcodec.AV_CODEC_ID_AAC, CodecType: libavutil.AVMEDIA_TYPE_AUDIO}
+	for _, tc := range []struct {
+		name   string
+		avctx  *libavcodec.AVCodecContext
+		parser libavcodec.AVCodecParserContext
+		data   []byte
+		want   time.Duration
+	}{
+		{
+			// as the FFmpeg 4.4 aac parser leaves it
+			name:  "ADTS without parser duration",
+			avctx: aac,
+			data:  adtsHeader(3, 1, 7),
+			want:  21333333,
+		},
+		{
+			name:   "audio parser duration",
+			avctx:  &libavcodec.AVCodecContext{CodecId: libavcodec.AV_CODEC_ID_MP3, CodecType: libavutil.AVMEDIA_TYPE_AUDIO, SampleRate: 44100},
+			parser: libavcodec.AVCodecParserContext{Duration: 1152},
+			want:   26122448,
+		},
+		{
+			name: "video frame rate",
+			avctx: &libavcodec.AVCodecContext{CodecId: libavcodec.AV_CODEC_ID_H264, CodecType: libavutil.AVMEDIA_TYPE_VIDEO,
+				Framerate: libavutil.AVRational{Num: 25, Den: 1}},
+			want: 40 * time.Millisecond,
+		},
+		{
+			name: "video repeated field",
+			avctx: &libavcodec.AVCodecContext{CodecId: libavcodec.AV_CODEC_ID_MPEG2VIDEO, CodecType: libavutil.AVMEDIA_TYPE_VIDEO,
+				Framerate: libavutil.AVRational{Num: 30000, Den: 1001}},
+			parser: libavcodec.AVCodecParserContext{RepeatPict: 1},
+			want:   50050000,
+		},
+		{
+			name:  "unknown",
+			avctx: &libavcodec.AVCodecContext{CodecId: libavcodec.AV_CODEC_ID_H264, CodecType: libavutil.AVMEDIA_TYPE_VIDEO},
+		},
+	} {
+		s := &Splitter{parser: &tc.parser, avctx: tc.avctx}
+		if got := s.packet(tc.data).Duration; got != tc.want {
+			t.Errorf("%s: duration %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
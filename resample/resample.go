@@ -0,0 +1,178 @@
+// Package resample converts audio frames between sample formats, rates and
+// channel layouts with libswresample.
+package resample
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/libswresample"
+)
+
+// Params describes the audio on one side of the conversion.
+type Params struct {
+	SampleRate    ffcommon.FInt
+	SampleFmt     libavutil.AVSampleFormat
+	ChannelLayout ffcommon.FUint64T
+}
+
+// FrameParams returns the parameters of frame. Frames that only carry a
+// channel count get the default layout for that count.
+func FrameParams(frame *libavutil.AVFrame) Params {
+	layout := frame.ChannelLayout
+	if layout == 0 {
+		layout = ffcommon.FUint64T(libavutil.AvGetDefaultChannelLayout(frame.Channels))
+	}
+	return Params{
+		SampleRate:    frame.SampleRate,
+		SampleFmt:     libavutil.AVSampleFormat(frame.Format),
+		ChannelLayout: layout,
+	}
+}
+
+// Config configures a Resampler.
+type Config struct {
+	In, Out Params
+	// InTimeBase is the time base of the input frame timestamps. Output
+	// timestamps are in 1/Out.SampleRate.
+	InTimeBase libavutil.AVRational
+	// Options are set on the SwrContext before swr_init, for example
+	// "resampler", "min_comp", "async" or "dither_method".
+	Options map[string]string
+}
+
+// Resampler converts frames and keeps track of output timestamps.
+type Resampler struct {
+	swr *libswresample.SwrContext
+	cfg Config
+}
+
+// New allocates and initializes a resampler for cfg.
+func New(cfg Config) (*Resampler, error) {
+	if cfg.In.SampleRate <= 0 || cfg.Out.SampleRate <= 0 {
+		return nil, fmt.Errorf("resample: invalid sample rate %d -> %d", cfg.In.SampleRate, cfg.Out.SampleRate)
+	}
+	if cfg.InTimeBase.Num <= 0 || cfg.InTimeBase.Den <= 0 {
+		cfg.InTimeBase = libavutil.AVRational{Num: 1, Den: cfg.In.SampleRate}
+	}
+	swr := libswresample.SwrAllocSetOpts(nil,
+		ffcommon.FInt64T(cfg.Out.ChannelLayout), cfg.Out.SampleFmt, cfg.Out.SampleRate,
+		ffcommon.FInt64T(cfg.In.ChannelLayout), cfg.In.SampleFmt, cfg.In.SampleRate,
+		0, 0)
+	if swr == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	r := &Resampler{swr: swr, cfg: cfg}
+	for k, v := range cfg.Options {
+		if ret := libavutil.AvOptSet(r.obj(), k, v, 0); ret < 0 {
+			r.Close()
+			return nil, fmt.Errorf("resample: option %s=%s: %w", k, v, libavutil.ErrorFromCode(ret))
+		}
+	}
+	if ret := swr.SwrInit(); ret < 0 {
+		r.Close()
+		return nil, fmt.Errorf("resample: init: %w", libavutil.ErrorFromCode(ret))
+	}
+	return r, nil
+}
+
+// TimeBase returns the time base of the output timestamps.
+func (r *Resampler) TimeBase() libavutil.AVRational {
+	return libavutil.AVRational{Num: 1, Den: r.cfg.Out.SampleRate}
+}
+
+// Convert resamples in into a newly allocated frame which the caller frees
+// with libavutil.AvFrameFree. It returns a nil frame when all input was
+// buffered inside the resampler.
+func (r *Resampler) Convert(in *libavutil.AVFrame) (*libavutil.AVFrame, error) {
+	pts := ffcommon.FInt64T(libavutil.AV_NOPTS_VALUE)
+	if in.Pts != libavutil.AV_NOPTS_VALUE {
+		// swr_next_pts works in 1/(in_sample_rate*out_sample_rate) units
+		pts = libavutil.AvRescaleRnd(in.Pts,
+			ffcommon.FInt64T(r.cfg.InTimeBase.Num)*ffcommon.FInt64T(r.cfg.In.SampleRate)*ffcommon.FInt64T(r.cfg.Out.SampleRate),
+			ffcommon.FInt64T(r.cfg.InTimeBase.Den), libavutil.AV_ROUND_NEAR_INF|libavutil.AV_ROUND_PASS_MINMAX)
+	}
+	return r.convert(in, r.swr.SwrGetOutSamples(in.NbSamples), pts)
+}
+
+// Flush drains the samples still buffered in the resampler. Call it until
+// it returns io.EOF once the input has ended.
+func (r *Resampler) Flush() (*libavutil.AVFrame, error) {
+	delay := r.Delay()
+	if delay <= 0 {
+		return nil, io.EOF
+	}
+	n := r.swr.SwrGetOutSamples(0)
+	if ffcommon.FInt64T(n) < delay {
+		n = ffcommon.FInt(delay)
+	}
+	out, err := r.convert(nil, n, libavutil.AV_NOPTS_VALUE)
+	if err == nil && out == nil {
+		err = io.EOF
+	}
+	return out, err
+}
+
+func (r *Resampler) convert(in *libavutil.AVFrame, nbSamples ffcommon.FInt, pts ffcommon.FInt64T) (*libavutil.AVFrame, error) {
+	if nbSamples < 0 {
+		return nil, libavutil.ErrorFromCode(nbSamples)
+	}
+	if nbSamples == 0 {
+		nbSamples = 1
+	}
+	outPts := r.swr.SwrNextPts(pts)
+
+	out := libavutil.AvFrameAlloc()
+	if out == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	out.Format = ffcommon.FInt(r.cfg.Out.SampleFmt)
+	out.SampleRate = r.cfg.Out.SampleRate
+	out.ChannelLayout = r.cfg.Out.ChannelLayout
+	out.Channels = libavutil.AvGetChannelLayoutNbChannels(r.cfg.Out.ChannelLayout)
+	out.NbSamples = nbSamples
+	if ret := out.AvFrameGetBuffer(0); ret < 0 {
+		libavutil.AvFrameFree(&out)
+		return nil, libavutil.ErrorFromCode(ret)
+	}
+	if ret := r.swr.SwrConvertFrame(out, in); ret < 0 {
+		libavutil.AvFrameFree(&out)
+		return nil, fmt.Errorf("resample: convert: %w", libavutil.ErrorFromCode(ret))
+	}
+	if out.NbSamples == 0 {
+		libavutil.AvFrameFree(&out)
+		return nil, nil
+	}
+	out.Pts = libavutil.AvRescaleRnd(outPts, 1, ffcommon.FInt64T(r.cfg.In.SampleRate), libavutil.AV_ROUND_NEAR_INF|libavutil.AV_ROUND_PASS_MINMAX)
+	return out, nil
+}
+
+// Delay returns the number of output samples buffered in the resampler.
+func (r *Resampler) Delay() ffcommon.FInt64T {
+	return r.swr.SwrGetDelay(ffcommon.FInt64T(r.cfg.Out.SampleRate))
+}
+
+// SetCompensation stretches or squeezes the output by sampleDelta samples
+// spread over the next distance output samples, to gradually correct drift
+// between the audio clock and the reference clock. A zero sampleDelta and
+// distance stops the compensation.
+func (r *Resampler) SetCompensation(sampleDelta, distance ffcommon.FInt) error {
+	if ret := r.swr.SwrSetCompensation(sampleDelta, distance); ret < 0 {
+		return fmt.Errorf("resample: set compensation: %w", libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// Close frees the resampler.
+func (r *Resampler) Close() {
+	if r.swr != nil {
+		libswresample.SwrFree(&r.swr)
+	}
+}
+
+func (r *Resampler) obj() ffcommon.FVoidP {
+	return uintptr(unsafe.Pointer(r.swr))
+}
@@ -0,0 +1,238 @@
+// Package rotate displays video upright according to its display matrix,
+// by filtering the decoded frames, and writes rotations to muxed streams
+// without re-encoding.
+package rotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dwdcth/ffmpeg-go/v7/dict"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/filter"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+// Angle returns the clockwise rotation of m in degrees, rounded and in
+// [0, 360), like the ffmpeg tool computes it. A singular matrix gives 0.
+func Angle(m sidedata.DisplayMatrix) float64 {
+	theta := -math.Round(m.Rotation())
+	if math.IsNaN(theta) {
+		return 0
+	}
+	return theta - 360*math.Floor(theta/360+0.9/360)
+}
+
+// StreamMatrix returns the display matrix of st, from its side data or else
+// from the legacy "rotate" tag holding a clockwise angle.
+func StreamMatrix(st *libavformat.AVStream) (sidedata.DisplayMatrix, bool) {
+	if m, ok := sidedata.OfStream(st).DisplayMatrix(); ok {
+		return m, true
+	}
+	if e := st.Metadata.AvDictGet("rotate", nil, 0); e != nil {
+		if angle, err := strconv.ParseFloat(ffcommon.GoString(e.Value), 64); err == nil {
+			return sidedata.NewDisplayMatrix(-angle, false, false), true
+		}
+	}
+	return sidedata.DisplayMatrix{}, false
+}
+
+// Filters returns the filter chain that displays frames transformed by m
+// upright, "" if they already are. Right angles and flips are exact, other
+// angles go through the rotate filter.
+func Filters(m sidedata.DisplayMatrix) string {
+	theta := Angle(m)
+	var chain []string
+	switch {
+	case math.Abs(theta-90) < 1:
+		if m[3] > 0 {
+			chain = append(chain, "transpose=cclock_flip")
+		} else {
+			chain = append(chain, "transpose=clock")
+		}
+	case math.Abs(theta-180) < 1:
+		if m[0] < 0 {
+			chain = append(chain, "hflip")
+		}
+		if m[4] < 0 {
+			chain = append(chain, "vflip")
+		}
+	case math.Abs(theta-270) < 1:
+		if m[3] < 0 {
+			chain = append(chain, "transpose=clock_flip")
+		} else {
+			chain = append(chain, "transpose=cclock")
+		}
+	case math.Abs(theta) > 1:
+		chain = append(chain, "rotate="+strconv.FormatFloat(theta, 'f', -1, 64)+"*PI/180")
+	default:
+		if m[4] < 0 {
+			chain = append(chain, "vflip")
+		}
+	}
+	return strings.Join(chain, ",")
+}
+
+// Rotator turns decoded frames upright.
+type Rotator struct {
+	g     *filter.Graph
+	frame *libavutil.AVFrame
+}
+
+// New returns a Rotator for frames described by in, transformed by m. The
+// frames it returns have the size of the rotated picture, see Format.
+func New(m sidedata.DisplayMatrix, in filter.Input) (*Rotator, error) {
+	desc := Filters(m)
+	if desc == "" {
+		desc = "null"
+	}
+	in.MediaType = libavutil.AVMEDIA_TYPE_VIDEO
+	g, err := filter.NewGraph(desc, []filter.Input{in}, []filter.Output{{MediaType: libavutil.AVMEDIA_TYPE_VIDEO}})
+	if err != nil {
+		return nil, fmt.Errorf("rotate: %w", err)
+	}
+	r := &Rotator{g: g, frame: libavutil.AvFrameAlloc()}
+	if r.frame == nil {
+		r.Close()
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	return r, nil
+}
+
+// ForStream returns a Rotator for the frames decoded from st, described
+// by in, and whether st needs rotating at all.
+func ForStream(st *libavformat.AVStream, in filter.Input) (*Rotator, bool, error) {
+	m, ok := StreamMatrix(st)
+	if !ok {
+		m = sidedata.NewDisplayMatrix(0, false, false)
+	}
+	r, err := New(m, in)
+	return r, Filters(m) != "", err
+}
+
+// Format returns the format of the frames the Rotator returns.
+func (r *Rotator) Format() (filter.Format, error) {
+	return r.g.OutputFormat("out")
+}
+
+// Rotate sends frame when iterated and yields the upright frames that come
+// out, without display matrix. The caller keeps ownership of frame. The
+// yielded frame is reused: it is only valid until the next iteration. A nil
+// frame flushes the Rotator.
+func (r *Rotator) Rotate(frame *libavutil.AVFrame) iter.Seq2[*libavutil.AVFrame, error] {
+	return func(yield func(*libavutil.AVFrame, error) bool) {
+		if err := r.g.Push("in", frame); err != nil && err != io.EOF {
+			yield(nil, fmt.Errorf("rotate: %w", err))
+			return
+		}
+		for {
+			err := r.g.Pull("out", r.frame)
+			if errors.Is(err, libavutil.ErrEAGAIN) || err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("rotate: %w", err))
+				return
+			}
+			sidedata.OfFrame(r.frame).Remove(libavutil.AV_FRAME_DATA_DISPLAYMATRIX)
+			more := yield(r.frame, nil)
+			r.frame.AvFrameUnref()
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+// Close frees the Rotator.
+func (r *Rotator) Close() {
+	libavutil.AvFrameFree(&r.frame)
+	r.g.Close()
+}
+
+// SetRotation makes the muxer write st with a clockwise rotation by angle
+// degrees, then the given flips, replacing its display matrix and rotate
+// tag. Set it before writing the header.
+func SetRotation(st *libavformat.AVStream, angle float64, hflip, vflip bool) error {
+	if err := sidedata.OfStream(st).SetDisplayMatrix(sidedata.NewDisplayMatrix(-angle, hflip, vflip)); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	// the tag only holds right angles and would contradict the matrix
+	// otherwise
+	var tags []dict.Entry
+	for _, e := range dict.Entries(st.Metadata) {
+		if e.Key != "rotate" {
+			tags = append(tags, e)
+		}
+	}
+	if a := int(angle) % 360; !hflip && !vflip && angle == math.Trunc(angle) && a != 0 {
+		if a < 0 {
+			a += 360
+		}
+		tags = append(tags, dict.Entry{Key: "rotate", Value: strconv.Itoa(a)})
+	}
+	d, err := dict.FromEntries(tags)
+	if err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	libavutil.AvDictFree(&st.Metadata)
+	st.Metadata = d
+	return nil
+}
+
+// Reset marks st as upright, for streams whose frames went through a
+// Rotator.
+func Reset(st *libavformat.AVStream) error {
+	return SetRotation(st, 0, false, false)
+}
+
+// Remux copies the file at in to out, setting the rotation of the video
+// stream with index stream, or of the best video stream if stream is
+// negative, as SetRotation does. The packets are not re-encoded.
+func Remux(ctx context.Context, in, out string, stream int, angle float64, hflip, vflip bool) error {
+	ic, err := remux.OpenInput(in)
+	if err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+	if stream < 0 {
+		stream = int(ic.AvFindBestStream(libavutil.AVMEDIA_TYPE_VIDEO, -1, -1, nil, 0))
+		if stream < 0 {
+			return fmt.Errorf("rotate: %s: no video stream", in)
+		}
+	}
+	if stream >= int(ic.NbStreams) {
+		return fmt.Errorf("rotate: %s: no stream %d", in, stream)
+	}
+
+	o, err := remux.NewOutput(ic, out, "")
+	if err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	defer o.Close()
+	if o.Map[stream] < 0 {
+		return fmt.Errorf("rotate: %s cannot hold stream %d", out, stream)
+	}
+	if err = SetRotation(o.Ctx.GetStream(ffcommon.FUnsignedInt(o.Map[stream])), angle, hflip, vflip); err != nil {
+		return err
+	}
+	if err = o.WriteHeader(nil); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	if err = o.Copy(ctx); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	if err = o.Finish(); err != nil {
+		return fmt.Errorf("rotate: %w", err)
+	}
+	return nil
+}
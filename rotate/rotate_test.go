@@ -0,0 +1,111 @@
+package rotate
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/sidedata"
+)
+
+// haveAvutil tells whether libavutil loads, which the matrix functions
+// need.
+var haveAvutil = sync.OnceValue(func() (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return ffcommon.GetAvutilDll() != 0
+})
+
+func needAvutil(t *testing.T) {
+	t.Helper()
+	if !haveAvutil() {
+		t.Skip("libavutil not found")
+	}
+}
+
+// The matrices as written by the mov muxer and read by the mov demuxer.
+var (
+	upright   = sidedata.DisplayMatrix{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+	portrait  = sidedata.DisplayMatrix{0, 0x10000, 0, -0x10000, 0, 0, 0, 0, 0x40000000}
+	upside    = sidedata.DisplayMatrix{-0x10000, 0, 0, 0, -0x10000, 0, 0, 0, 0x40000000}
+	landscape = sidedata.DisplayMatrix{0, -0x10000, 0, 0x10000, 0, 0, 0, 0, 0x40000000}
+	mirrored  = sidedata.DisplayMatrix{-0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+	flipped   = sidedata.DisplayMatrix{0x10000, 0, 0, 0, -0x10000, 0, 0, 0, 0x40000000}
+)
+
+func TestAngle(t *testing.T) {
+	needAvutil(t)
+	for _, tc := range []struct {
+		name string
+		m    sidedata.DisplayMatrix
+		want float64
+	}{
+		{"upright", upright, 0},
+		{"portrait", portrait, 90},
+		{"upside down", upside, 180},
+		{"landscape", landscape, 270},
+		{"singular", sidedata.DisplayMatrix{}, 0},
+		{"clockwise 90", sidedata.NewDisplayMatrix(-90, false, false), 90},
+		{"counter clockwise 90", sidedata.NewDisplayMatrix(90, false, false), 270},
+		{"clockwise 30", sidedata.NewDisplayMatrix(-30, false, false), 30},
+		{"counter clockwise 10", sidedata.NewDisplayMatrix(10, false, false), 350},
+		{"counter clockwise 1", sidedata.NewDisplayMatrix(1, false, false), 359},
+		{"rounded to 0", sidedata.NewDisplayMatrix(0.4, false, false), 0},
+		{"full turn", sidedata.NewDisplayMatrix(-360, false, false), 0},
+		{"rounded", sidedata.NewDisplayMatrix(-44.6, false, false), 45},
+	} {
+		if got := Angle(tc.m); got != tc.want {
+			t.Errorf("%s: Angle(%v) = %g, want %g", tc.name, tc.m, got, tc.want)
+		}
+	}
+}
+
+func TestNewDisplayMatrix(t *testing.T) {
+	needAvutil(t)
+	for _, tc := range []struct {
+		angle        float64
+		hflip, vflip bool
+		want         sidedata.DisplayMatrix
+	}{
+		{0, false, false, upright},
+		{-90, false, false, portrait},
+		{180, false, false, upside},
+		{90, false, false, landscape},
+		{0, true, false, mirrored},
+		{0, false, true, flipped},
+	} {
+		if got := sidedata.NewDisplayMatrix(tc.angle, tc.hflip, tc.vflip); got != tc.want {
+			t.Errorf("NewDisplayMatrix(%g, %v, %v) = %v, want %v", tc.angle, tc.hflip, tc.vflip, got, tc.want)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	needAvutil(t)
+	for _, tc := range []struct {
+		name string
+		m    sidedata.DisplayMatrix
+		want string
+	}{
+		{"upright", upright, ""},
+		{"singular", sidedata.DisplayMatrix{}, ""},
+		{"portrait", portrait, "transpose=clock"},
+		{"upside down", upside, "hflip,vflip"},
+		{"landscape", landscape, "transpose=cclock"},
+		{"mirrored", mirrored, "hflip"},
+		{"flipped", flipped, "vflip"},
+		{"clockwise 90 mirrored", sidedata.NewDisplayMatrix(-90, true, false), "transpose=cclock_flip"},
+		{"clockwise 270 mirrored", sidedata.NewDisplayMatrix(90, true, false), "transpose=clock_flip"},
+		{"rotate tag 90", sidedata.NewDisplayMatrix(-90, false, false), "transpose=clock"},
+		{"clockwise 30", sidedata.NewDisplayMatrix(-30, false, false), "rotate=30*PI/180"},
+		{"counter clockwise 10", sidedata.NewDisplayMatrix(10, false, false), "rotate=350*PI/180"},
+		{"under a degree", sidedata.NewDisplayMatrix(0.4, false, false), ""},
+	} {
+		if got := Filters(tc.m); got != tc.want {
+			t.Errorf("%s: Filters(%v) = %q, want %q", tc.name, tc.m, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Package scale converts and resizes video frames with libswscale.
+package scale
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+	"github.com/dwdcth/ffmpeg-go/v7/libswscale"
+)
+
+// Flags selects the interpolation algorithm, optionally or-ed with the
+// accuracy flags.
+type Flags ffcommon.FInt
+
+const (
+	FastBilinear Flags = libswscale.SWS_FAST_BILINEAR
+	Bilinear     Flags = libswscale.SWS_BILINEAR
+	Bicubic      Flags = libswscale.SWS_BICUBIC
+	Experimental Flags = libswscale.SWS_X
+	Point        Flags = libswscale.SWS_POINT
+	Area         Flags = libswscale.SWS_AREA
+	Bicublin     Flags = libswscale.SWS_BICUBLIN
+	Gauss        Flags = libswscale.SWS_GAUSS
+	Sinc         Flags = libswscale.SWS_SINC
+	Lanczos      Flags = libswscale.SWS_LANCZOS
+	Spline       Flags = libswscale.SWS_SPLINE
+
+	FullChromaInt   Flags = libswscale.SWS_FULL_CHR_H_INT
+	FullChromaInput Flags = libswscale.SWS_FULL_CHR_H_INP
+	AccurateRound   Flags = libswscale.SWS_ACCURATE_RND
+	BitExact        Flags = libswscale.SWS_BITEXACT
+)
+
+// Colorspace selects the YUV<->RGB coefficients, see sws_getCoefficients.
+type Colorspace ffcommon.FInt
+
+const (
+	ColorspaceDefault   Colorspace = libswscale.SWS_CS_DEFAULT
+	ColorspaceBT709     Colorspace = libswscale.SWS_CS_ITU709
+	ColorspaceFCC       Colorspace = libswscale.SWS_CS_FCC
+	ColorspaceBT601     Colorspace = libswscale.SWS_CS_ITU601
+	ColorspaceSMPTE240M Colorspace = libswscale.SWS_CS_SMPTE240M
+	ColorspaceBT2020    Colorspace = libswscale.SWS_CS_BT2020
+)
+
+// Range is the black/white level range of the YUV data.
+type Range ffcommon.FInt
+
+const (
+	RangeLimited Range = 0 // MPEG, 16-235
+	RangeFull    Range = 1 // JPEG, 0-255
+)
+
+// ColorDetails is passed to sws_setColorspaceDetails. Brightness, Contrast
+// and Saturation are 16.16 fixed point, zero Contrast and Saturation mean 1.0.
+type ColorDetails struct {
+	SrcSpace, DstSpace Colorspace
+	SrcRange, DstRange Range
+
+	Brightness, Contrast, Saturation ffcommon.FInt
+}
+
+// Config describes the destination frames of a Scaler.
+type Config struct {
+	Width, Height ffcommon.FInt
+	PixFmt        libavutil.AVPixelFormat
+	Flags         Flags
+	// Color overrides the colorspace and range, nil keeps the swscale
+	// defaults.
+	Color *ColorDetails
+	// Slices splits the image into that many horizontal bands scaled in
+	// parallel, each with its own context. Bands are filtered independently,
+	// so vertical filter taps do not cross band borders. Formats that cannot
+	// be split (paletted, bitstream, hardware) are scaled in one piece.
+	Slices int
+}
+
+type band struct {
+	ctx        *libswscale.SwsContext
+	srcY, srcH ffcommon.FInt
+	dstY, dstH ffcommon.FInt
+}
+
+// Scaler scales source frames of any size and format to the configured
+// destination, reusing its contexts until the source changes.
+type Scaler struct {
+	cfg Config
+
+	srcW, srcH ffcommon.FInt
+	srcFmt     libavutil.AVPixelFormat
+	bands      []band
+
+	mu   sync.Mutex
+	free []*libavutil.AVFrame
+}
+
+// New returns a Scaler for cfg. Contexts are created on the first frame.
+func New(cfg Config) (*Scaler, error) {
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("scale: invalid destination size %dx%d", cfg.Width, cfg.Height)
+	}
+	if libswscale.SwsIsSupportedOutput(cfg.PixFmt) == 0 {
+		return nil, fmt.Errorf("scale: unsupported destination format %s", libavutil.AvGetPixFmtName(cfg.PixFmt))
+	}
+	if cfg.Flags == 0 {
+		cfg.Flags = Bicubic
+	}
+	return &Scaler{cfg: cfg, srcFmt: libavutil.AV_PIX_FMT_NONE}, nil
+}
+
+// Scale scales src into a frame taken from the scaler's pool. Frame
+// properties such as pts and side data are copied from src. Hand the frame
+// back with Release when done, or free it with libavutil.AvFrameFree.
+func (s *Scaler) Scale(src *libavutil.AVFrame) (*libavutil.AVFrame, error) {
+	dst, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+	if ret := libavutil.AvFrameCopyProps(dst, src); ret < 0 {
+		s.Release(dst)
+		return nil, libavutil.ErrorFromCode(ret)
+	}
+	if err = s.ScaleInto(dst, src); err != nil {
+		s.Release(dst)
+		return nil, err
+	}
+	if s.cfg.Color != nil {
+		dst.ColorRange = libavutil.AVColorRange(s.cfg.Color.DstRange + 1)
+	}
+	return dst, nil
+}
+
+// ScaleInto scales src into dst, whose buffers must already be allocated
+// with the configured size and format.
+func (s *Scaler) ScaleInto(dst, src *libavutil.AVFrame) error {
+	if dst.Width != s.cfg.Width || dst.Height != s.cfg.Height || dst.Format != s.cfg.PixFmt {
+		return fmt.Errorf("scale: destination is %dx%d %s, want %dx%d %s",
+			dst.Width, dst.Height, libavutil.AvGetPixFmtName(dst.Format),
+			s.cfg.Width, s.cfg.Height, libavutil.AvGetPixFmtName(s.cfg.PixFmt))
+	}
+	if err := s.prepare(src.Width, src.Height, src.Format); err != nil {
+		return err
+	}
+	if len(s.bands) == 1 {
+		return s.scaleBand(&s.bands[0], dst, src)
+	}
+
+	errs := make([]error, len(s.bands))
+	var wg sync.WaitGroup
+	for i := range s.bands {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.scaleBand(&s.bands[i], dst, src)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scaler) scaleBand(b *band, dst, src *libavutil.AVFrame) error {
+	var srcData, dstData [4]*ffcommon.FUint8T
+	var srcStride, dstStride [4]ffcommon.FInt
+	planeOffsets(&srcData, &srcStride, src, b.srcY)
+	planeOffsets(&dstData, &dstStride, dst, b.dstY)
+
+	ret := b.ctx.SwsScale(&srcData[0], &srcStride[0], 0, ffcommon.FUint(b.srcH), &dstData[0], &dstStride[0])
+	if ret <= 0 {
+		return fmt.Errorf("scale: sws_scale failed on rows %d-%d", b.srcY, b.srcY+b.srcH)
+	}
+	return nil
+}
+
+// planeOffsets points data at row y of every plane of frame.
+func planeOffsets(data *[4]*ffcommon.FUint8T, stride *[4]ffcommon.FInt, frame *libavutil.AVFrame, y ffcommon.FInt) {
+	shifts := planeShifts(frame.Format)
+	for p := 0; p < 4; p++ {
+		stride[p] = frame.Linesize[p]
+		data[p] = frame.Data[p]
+		if data[p] != nil && y > 0 {
+			off := int(y>>shifts[p]) * int(frame.Linesize[p])
+			data[p] = (*ffcommon.FUint8T)(unsafe.Add(unsafe.Pointer(data[p]), off))
+		}
+	}
+}
+
+// planeShifts returns the vertical subsampling of every plane of pixFmt.
+func planeShifts(pixFmt libavutil.AVPixelFormat) (shifts [4]ffcommon.FUint8T) {
+	d := libavutil.AvPixFmtDescGet(pixFmt)
+	if d == nil || d.Flags&libavutil.AV_PIX_FMT_FLAG_RGB != 0 || d.NbComponents < 3 {
+		return
+	}
+	for c := 1; c <= 2; c++ {
+		if p := d.Comp[c].Plane; p != d.Comp[0].Plane && p >= 0 && p < 4 {
+			shifts[p] = d.Log2ChromaH
+		}
+	}
+	return
+}
+
+func sliceable(pixFmt libavutil.AVPixelFormat) (ok bool, align ffcommon.FInt) {
+	d := libavutil.AvPixFmtDescGet(pixFmt)
+	if d == nil {
+		return false, 0
+	}
+	const unsplittable = libavutil.AV_PIX_FMT_FLAG_PAL | libavutil.AV_PIX_FMT_FLAG_PSEUDOPAL |
+		libavutil.AV_PIX_FMT_FLAG_BITSTREAM | libavutil.AV_PIX_FMT_FLAG_HWACCEL
+	return d.Flags&unsplittable == 0, 1 << d.Log2ChromaH
+}
+
+// prepare (re)creates the band contexts for a source of the given size and
+// format, going through sws_getCachedContext so unchanged contexts are kept.
+func (s *Scaler) prepare(w, h ffcommon.FInt, pixFmt libavutil.AVPixelFormat) error {
+	if w == s.srcW && h == s.srcH && pixFmt == s.srcFmt && len(s.bands) > 0 {
+		return nil
+	}
+	if libswscale.SwsIsSupportedInput(pixFmt) == 0 {
+		return fmt.Errorf("scale: unsupported source format %s", libavutil.AvGetPixFmtName(pixFmt))
+	}
+
+	layout := s.layout(w, h, pixFmt)
+	for i := len(layout); i < len(s.bands); i++ {
+		s.bands[i].ctx.SwsFreeContext()
+	}
+	for i := range min(len(layout), len(s.bands)) {
+		layout[i].ctx = s.bands[i].ctx
+	}
+	for i := range layout {
+		b := &layout[i]
+		b.ctx = b.ctx.SwsGetCachedContext(w, b.srcH, pixFmt, s.cfg.Width, b.dstH, s.cfg.PixFmt,
+			ffcommon.FInt(s.cfg.Flags), nil, nil, nil)
+		if b.ctx == nil {
+			// sws_getCachedContext freed the context of this band, the
+			// following ones are still held
+			for j := i + 1; j < len(layout); j++ {
+				layout[j].ctx.SwsFreeContext()
+			}
+			s.bands = layout[:i]
+			s.srcFmt = libavutil.AV_PIX_FMT_NONE
+			return fmt.Errorf("scale: cannot convert %dx%d %s to %dx%d %s", w, h, libavutil.AvGetPixFmtName(pixFmt),
+				s.cfg.Width, s.cfg.Height, libavutil.AvGetPixFmtName(s.cfg.PixFmt))
+		}
+		if c := s.cfg.Color; c != nil {
+			contrast, saturation := c.Contrast, c.Saturation
+			if contrast == 0 {
+				contrast = 1 << 16
+			}
+			if saturation == 0 {
+				saturation = 1 << 16
+			}
+			if b.ctx.SwsSetColorspaceDetails(libswscale.SwsGetCoefficients(ffcommon.FInt(c.SrcSpace)), ffcommon.FInt(c.SrcRange),
+				libswscale.SwsGetCoefficients(ffcommon.FInt(c.DstSpace)), ffcommon.FInt(c.DstRange),
+				c.Brightness, contrast, saturation) < 0 {
+				// the contexts are kept, but the next frame retries
+				s.bands = layout
+				s.srcFmt = libavutil.AV_PIX_FMT_NONE
+				return fmt.Errorf("scale: cannot set the color details of %s to %s", libavutil.AvGetPixFmtName(pixFmt),
+					libavutil.AvGetPixFmtName(s.cfg.PixFmt))
+			}
+		}
+	}
+	s.bands = layout
+	s.srcW, s.srcH, s.srcFmt = w, h, pixFmt
+	return nil
+}
+
+// layout splits source and destination into matching horizontal bands
+// aligned to the chroma subsampling of both formats.
+func (s *Scaler) layout(w, h ffcommon.FInt, pixFmt libavutil.AVPixelFormat) []band {
+	n := ffcommon.FInt(s.cfg.Slices)
+	srcOk, srcAlign := sliceable(pixFmt)
+	dstOk, dstAlign := sliceable(s.cfg.PixFmt)
+	if n <= 1 || !srcOk || !dstOk {
+		return []band{{srcH: h, dstH: s.cfg.Height}}
+	}
+
+	bands := make([]band, 0, n)
+	var srcY, dstY ffcommon.FInt
+	for i := ffcommon.FInt(1); i <= n; i++ {
+		srcEnd, dstEnd := h, s.cfg.Height
+		if i < n {
+			srcEnd = h * i / n / srcAlign * srcAlign
+			dstEnd = s.cfg.Height * i / n / dstAlign * dstAlign
+		}
+		if srcEnd <= srcY || dstEnd <= dstY {
+			return []band{{srcH: h, dstH: s.cfg.Height}}
+		}
+		bands = append(bands, band{srcY: srcY, srcH: srcEnd - srcY, dstY: dstY, dstH: dstEnd - dstY})
+		srcY, dstY = srcEnd, dstEnd
+	}
+	return bands
+}
+
+func (s *Scaler) get() (*libavutil.AVFrame, error) {
+	s.mu.Lock()
+	var f *libavutil.AVFrame
+	if n := len(s.free); n > 0 {
+		f = s.free[n-1]
+		s.free = s.free[:n-1]
+	}
+	s.mu.Unlock()
+	if f != nil {
+		if ret := f.AvFrameMakeWritable(); ret < 0 {
+			libavutil.AvFrameFree(&f)
+			return nil, libavutil.ErrorFromCode(ret)
+		}
+		return f, nil
+	}
+
+	f = libavutil.AvFrameAlloc()
+	if f == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	f.Width, f.Height, f.Format = s.cfg.Width, s.cfg.Height, s.cfg.PixFmt
+	if ret := f.AvFrameGetBuffer(0); ret < 0 {
+		libavutil.AvFrameFree(&f)
+		return nil, libavutil.ErrorFromCode(ret)
+	}
+	return f, nil
+}
+
+// Release returns a frame obtained from Scale to the pool.
+func (s *Scaler) Release(f *libavutil.AVFrame) {
+	if f == nil {
+		return
+	}
+	if f.Width != s.cfg.Width || f.Height != s.cfg.Height || f.Format != s.cfg.PixFmt || f.Data[0] == nil {
+		libavutil.AvFrameFree(&f)
+		return
+	}
+	// keep the buffers, drop what av_frame_copy_props added from the source
+	for f.NbSideData > 0 {
+		f.AvFrameRemoveSideData((*f.SideData).Type)
+	}
+	libavutil.AvDictFree(&f.Metadata)
+	s.mu.Lock()
+	s.free = append(s.free, f)
+	s.mu.Unlock()
+}
+
+// Close frees the contexts and the pooled frames.
+func (s *Scaler) Close() {
+	for i := range s.bands {
+		s.bands[i].ctx.SwsFreeContext()
+	}
+	s.bands = nil
+	s.srcFmt = libavutil.AV_PIX_FMT_NONE
+	s.mu.Lock()
+	for _, f := range s.free {
+		libavutil.AvFrameFree(&f)
+	}
+	s.free = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,229 @@
+package sidedata
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/avutil"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// MasteringDisplay is the SMPTE ST 2086 mastering display color volume.
+// Chromaticities are CIE 1931 xy coordinates and luminances are in cd/m².
+type MasteringDisplay struct {
+	// Primaries are the red, green and blue primaries, as {x, y}.
+	Primaries    [3][2]avutil.Rational
+	WhitePoint   [2]avutil.Rational
+	MinLuminance avutil.Rational
+	MaxLuminance avutil.Rational
+	// HasPrimaries and HasLuminance tell which of the fields are set.
+	HasPrimaries bool
+	HasLuminance bool
+}
+
+// String returns md in the G(x,y)B(x,y)R(x,y)WP(x,y)L(max,min) form of the
+// x265 master-display option, chromaticities in 0.00002 and luminances in
+// 0.0001 cd/m² units.
+func (md MasteringDisplay) String() string {
+	xy := func(c [2]avutil.Rational) string {
+		return fmt.Sprintf("(%d,%d)", scaled(c[0], 50000), scaled(c[1], 50000))
+	}
+	r, g, b := md.Primaries[0], md.Primaries[1], md.Primaries[2]
+	return fmt.Sprintf("G%sB%sR%sWP%sL(%d,%d)", xy(g), xy(b), xy(r), xy(md.WhitePoint),
+		scaled(md.MaxLuminance, 10000), scaled(md.MinLuminance, 10000))
+}
+
+// scaled returns q*unit rounded to the nearest integer.
+func scaled(q avutil.Rational, unit int64) int64 {
+	if q.Den == 0 {
+		return 0
+	}
+	n, d := int64(q.Num)*unit, int64(q.Den)
+	if (n < 0) != (d < 0) {
+		return (n - d/2) / d
+	}
+	return (n + d/2) / d
+}
+
+func masteringDisplayFrom(m *libavutil.AVMasteringDisplayMetadata) MasteringDisplay {
+	md := MasteringDisplay{
+		MinLuminance: avutil.Rational(m.MinLuminance),
+		MaxLuminance: avutil.Rational(m.MaxLuminance),
+		HasPrimaries: m.HasPrimaries != 0,
+		HasLuminance: m.HasLuminance != 0,
+	}
+	for i := range m.DisplayPrimaries {
+		for j := range m.DisplayPrimaries[i] {
+			md.Primaries[i][j] = avutil.Rational(m.DisplayPrimaries[i][j])
+		}
+	}
+	for j := range m.WhitePoint {
+		md.WhitePoint[j] = avutil.Rational(m.WhitePoint[j])
+	}
+	return md
+}
+
+func (md MasteringDisplay) to(m *libavutil.AVMasteringDisplayMetadata) {
+	for i := range md.Primaries {
+		for j := range md.Primaries[i] {
+			m.DisplayPrimaries[i][j] = md.Primaries[i][j].AV()
+		}
+	}
+	for j := range md.WhitePoint {
+		m.WhitePoint[j] = md.WhitePoint[j].AV()
+	}
+	m.MinLuminance = md.MinLuminance.AV()
+	m.MaxLuminance = md.MaxLuminance.AV()
+	m.HasPrimaries = boolInt(md.HasPrimaries)
+	m.HasLuminance = boolInt(md.HasLuminance)
+}
+
+// MasteringDisplay returns the mastering display metadata of the frame.
+func (fr Frame) MasteringDisplay() (MasteringDisplay, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_MASTERING_DISPLAY_METADATA)
+	if sd == nil || int(sd.Size) < int(unsafe.Sizeof(libavutil.AVMasteringDisplayMetadata{})) {
+		return MasteringDisplay{}, false
+	}
+	return masteringDisplayFrom((*libavutil.AVMasteringDisplayMetadata)(unsafe.Pointer(sd.Data))), true
+}
+
+// SetMasteringDisplay replaces the mastering display metadata of the frame.
+func (fr Frame) SetMasteringDisplay(md MasteringDisplay) error {
+	fr.f.AvFrameRemoveSideData(libavutil.AV_FRAME_DATA_MASTERING_DISPLAY_METADATA)
+	m := fr.f.AvMasteringDisplayMetadataCreateSideData()
+	if m == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	md.to(m)
+	return nil
+}
+
+// ContentLightLevel is the CTA-861.3 content light level, in cd/m².
+type ContentLightLevel struct {
+	// MaxCLL is the maximum content light level of any pixel.
+	MaxCLL uint
+	// MaxFALL is the maximum frame average light level.
+	MaxFALL uint
+}
+
+// String returns c in the "MaxCLL,MaxFALL" form of the x265 max-cll
+// option.
+func (c ContentLightLevel) String() string {
+	return fmt.Sprintf("%d,%d", c.MaxCLL, c.MaxFALL)
+}
+
+// ContentLightLevel returns the content light level of the frame.
+func (fr Frame) ContentLightLevel() (ContentLightLevel, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_CONTENT_LIGHT_LEVEL)
+	if sd == nil || int(sd.Size) < int(unsafe.Sizeof(libavutil.AVContentLightMetadata{})) {
+		return ContentLightLevel{}, false
+	}
+	m := (*libavutil.AVContentLightMetadata)(unsafe.Pointer(sd.Data))
+	return ContentLightLevel{MaxCLL: uint(m.MaxCLL), MaxFALL: uint(m.MaxFALL)}, true
+}
+
+// SetContentLightLevel replaces the content light level of the frame.
+func (fr Frame) SetContentLightLevel(c ContentLightLevel) error {
+	fr.f.AvFrameRemoveSideData(libavutil.AV_FRAME_DATA_CONTENT_LIGHT_LEVEL)
+	m := fr.f.AvContentLightMetadataCreateSideData()
+	if m == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	m.MaxCLL = ffcommon.FUnsigned(c.MaxCLL)
+	m.MaxFALL = ffcommon.FUnsigned(c.MaxFALL)
+	return nil
+}
+
+// HDR10Plus is the SMPTE ST 2094-40 dynamic metadata of HDR10+.
+type HDR10Plus = libavutil.AVDynamicHDRPlus
+
+// HDR10Plus returns a copy of the HDR10+ dynamic metadata of the frame.
+func (fr Frame) HDR10Plus() (*HDR10Plus, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_DYNAMIC_HDR_PLUS)
+	if sd == nil || int(sd.Size) < int(unsafe.Sizeof(HDR10Plus{})) {
+		return nil, false
+	}
+	m := *(*HDR10Plus)(unsafe.Pointer(sd.Data))
+	return &m, true
+}
+
+// SetHDR10Plus replaces the HDR10+ dynamic metadata of the frame.
+func (fr Frame) SetHDR10Plus(m *HDR10Plus) error {
+	fr.f.AvFrameRemoveSideData(libavutil.AV_FRAME_DATA_DYNAMIC_HDR_PLUS)
+	p := fr.f.AvDynamicHdrPlusCreateSideData()
+	if p == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	*p = *m
+	return nil
+}
+
+// FilmGrain holds the parameters to synthesize film grain on the frame,
+// as coded in AV1.
+type FilmGrain = libavutil.AVFilmGrainParams
+
+// FilmGrain returns a copy of the film grain parameters of the frame.
+func (fr Frame) FilmGrain() (*FilmGrain, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_FILM_GRAIN_PARAMS)
+	if sd == nil || int(sd.Size) < int(unsafe.Sizeof(FilmGrain{})) {
+		return nil, false
+	}
+	p := *(*FilmGrain)(unsafe.Pointer(sd.Data))
+	return &p, true
+}
+
+// SetFilmGrain replaces the film grain parameters of the frame.
+func (fr Frame) SetFilmGrain(p *FilmGrain) error {
+	fr.f.AvFrameRemoveSideData(libavutil.AV_FRAME_DATA_FILM_GRAIN_PARAMS)
+	fg := fr.f.AvFilmGrainParamsCreateSideData()
+	if fg == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	*fg = *p
+	return nil
+}
+
+// DOVI is the Dolby Vision decoder configuration record.
+type DOVI struct {
+	VersionMajor, VersionMinor uint8
+	Profile, Level             uint8
+	// RPU, EL and BL tell whether the stream has reference processing
+	// units, an enhancement layer and a base layer.
+	RPU, EL, BL bool
+	// BLSignalCompatibilityID is the dv_bl_signal_compatibility_id of
+	// the base layer.
+	BLSignalCompatibilityID uint8
+}
+
+func doviFrom(r *libavutil.AVDOVIDecoderConfigurationRecord) DOVI {
+	return DOVI{
+		VersionMajor:            r.DvVersionMajor,
+		VersionMinor:            r.DvVersionMinor,
+		Profile:                 r.DvProfile,
+		Level:                   r.DvLevel,
+		RPU:                     r.RpuPresentFlag != 0,
+		EL:                      r.ElPresentFlag != 0,
+		BL:                      r.BlPresentFlag != 0,
+		BLSignalCompatibilityID: r.DvBlSignalCompatibilityId,
+	}
+}
+
+func (d DOVI) record() libavutil.AVDOVIDecoderConfigurationRecord {
+	flag := func(b bool) ffcommon.FUint8T {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return libavutil.AVDOVIDecoderConfigurationRecord{
+		DvVersionMajor:            d.VersionMajor,
+		DvVersionMinor:            d.VersionMinor,
+		DvProfile:                 d.Profile,
+		DvLevel:                   d.Level,
+		RpuPresentFlag:            flag(d.RPU),
+		ElPresentFlag:             flag(d.EL),
+		BlPresentFlag:             flag(d.BL),
+		DvBlSignalCompatibilityId: d.BLSignalCompatibilityID,
+	}
+}
@@ -0,0 +1,318 @@
+package sidedata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+
+	"github.com/dwdcth/ffmpeg-go/v7/dict"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// ParamChange announces new decoding parameters from the packet on. Zero
+// fields are unchanged.
+type ParamChange struct {
+	ChannelCount  int
+	ChannelLayout uint64
+	SampleRate    int
+	Width, Height int
+}
+
+// ParamChange returns the parameter change of the packet.
+func (d packetData) ParamChange() (ParamChange, bool) {
+	b := d.s.get(libavcodec.AV_PKT_DATA_PARAM_CHANGE)
+	if len(b) < 4 {
+		return ParamChange{}, false
+	}
+	flags := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+	var p ParamChange
+	u32 := func() (uint32, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		v := binary.LittleEndian.Uint32(b)
+		b = b[4:]
+		return v, true
+	}
+	if flags&libavcodec.AV_SIDE_DATA_PARAM_CHANGE_CHANNEL_COUNT != 0 {
+		v, ok := u32()
+		if !ok {
+			return ParamChange{}, false
+		}
+		p.ChannelCount = int(v)
+	}
+	if flags&libavcodec.AV_SIDE_DATA_PARAM_CHANGE_CHANNEL_LAYOUT != 0 {
+		if len(b) < 8 {
+			return ParamChange{}, false
+		}
+		p.ChannelLayout = binary.LittleEndian.Uint64(b)
+		b = b[8:]
+	}
+	if flags&libavcodec.AV_SIDE_DATA_PARAM_CHANGE_SAMPLE_RATE != 0 {
+		v, ok := u32()
+		if !ok {
+			return ParamChange{}, false
+		}
+		p.SampleRate = int(v)
+	}
+	if flags&libavcodec.AV_SIDE_DATA_PARAM_CHANGE_DIMENSIONS != 0 {
+		w, ok1 := u32()
+		h, ok2 := u32()
+		if !ok1 || !ok2 {
+			return ParamChange{}, false
+		}
+		p.Width, p.Height = int(w), int(h)
+	}
+	return p, true
+}
+
+// SetParamChange replaces the parameter change of the packet.
+func (d packetData) SetParamChange(p ParamChange) error {
+	var flags uint32
+	b := make([]byte, 4, 28)
+	if p.ChannelCount != 0 {
+		flags |= libavcodec.AV_SIDE_DATA_PARAM_CHANGE_CHANNEL_COUNT
+		b = binary.LittleEndian.AppendUint32(b, uint32(p.ChannelCount))
+	}
+	if p.ChannelLayout != 0 {
+		flags |= libavcodec.AV_SIDE_DATA_PARAM_CHANGE_CHANNEL_LAYOUT
+		b = binary.LittleEndian.AppendUint64(b, p.ChannelLayout)
+	}
+	if p.SampleRate != 0 {
+		flags |= libavcodec.AV_SIDE_DATA_PARAM_CHANGE_SAMPLE_RATE
+		b = binary.LittleEndian.AppendUint32(b, uint32(p.SampleRate))
+	}
+	if p.Width != 0 || p.Height != 0 {
+		flags |= libavcodec.AV_SIDE_DATA_PARAM_CHANGE_DIMENSIONS
+		b = binary.LittleEndian.AppendUint32(b, uint32(p.Width))
+		b = binary.LittleEndian.AppendUint32(b, uint32(p.Height))
+	}
+	binary.LittleEndian.PutUint32(b, flags)
+	return d.SetBytes(libavcodec.AV_PKT_DATA_PARAM_CHANGE, b)
+}
+
+// SkipSamples tells how many decoded audio samples to drop.
+type SkipSamples struct {
+	// Start samples are dropped from the start of the packet and End
+	// from its end.
+	Start, End uint32
+	// StartReason and EndReason are 0 for padding and 1 for codec
+	// convergence.
+	StartReason, EndReason uint8
+}
+
+// SkipSamples returns the samples to skip of the packet.
+func (d packetData) SkipSamples() (SkipSamples, bool) {
+	b := d.s.get(libavcodec.AV_PKT_DATA_SKIP_SAMPLES)
+	if len(b) < 10 {
+		return SkipSamples{}, false
+	}
+	return SkipSamples{
+		Start:       binary.LittleEndian.Uint32(b),
+		End:         binary.LittleEndian.Uint32(b[4:]),
+		StartReason: b[8],
+		EndReason:   b[9],
+	}, true
+}
+
+// SetSkipSamples replaces the samples to skip of the packet.
+func (d packetData) SetSkipSamples(s SkipSamples) error {
+	b := make([]byte, 10)
+	binary.LittleEndian.PutUint32(b, s.Start)
+	binary.LittleEndian.PutUint32(b[4:], s.End)
+	b[8], b[9] = s.StartReason, s.EndReason
+	return d.SetBytes(libavcodec.AV_PKT_DATA_SKIP_SAMPLES, b)
+}
+
+// ReplayGain holds the ReplayGain values of a track and its album. Gains
+// are in dB, NaN when unknown. Peaks are amplitudes where 1 is full scale,
+// 0 when unknown.
+type ReplayGain struct {
+	TrackGain, TrackPeak float64
+	AlbumGain, AlbumPeak float64
+}
+
+const (
+	gainScale = 100000
+	peakScale = 100000
+)
+
+// ReplayGain returns the ReplayGain values.
+func (d packetData) ReplayGain() (ReplayGain, bool) {
+	g, ok := structOf[libavutil.AVReplayGain](d, libavcodec.AV_PKT_DATA_REPLAYGAIN)
+	if !ok {
+		return ReplayGain{}, false
+	}
+	gain := func(v int32) float64 {
+		if v == math.MinInt32 {
+			return math.NaN()
+		}
+		return float64(v) / gainScale
+	}
+	return ReplayGain{
+		TrackGain: gain(g.TrackGain),
+		TrackPeak: float64(g.TrackPeak) / peakScale,
+		AlbumGain: gain(g.AlbumGain),
+		AlbumPeak: float64(g.AlbumPeak) / peakScale,
+	}, true
+}
+
+// SetReplayGain replaces the ReplayGain values.
+func (d packetData) SetReplayGain(r ReplayGain) error {
+	gain := func(v float64) int32 {
+		if math.IsNaN(v) {
+			return math.MinInt32
+		}
+		return int32(math.Round(v * gainScale))
+	}
+	return setStruct(d, libavcodec.AV_PKT_DATA_REPLAYGAIN, libavutil.AVReplayGain{
+		TrackGain: gain(r.TrackGain),
+		TrackPeak: uint32(math.Round(r.TrackPeak * peakScale)),
+		AlbumGain: gain(r.AlbumGain),
+		AlbumPeak: uint32(math.Round(r.AlbumPeak * peakScale)),
+	})
+}
+
+// QualityStats is what the encoder reports about an encoded frame.
+type QualityStats struct {
+	// Quality is the quantizer as a lambda, from 1 (good) to
+	// FF_LAMBDA_MAX (bad).
+	Quality  int
+	PictType libavutil.AVPictureType
+	// Errors holds the sum of squared differences of each plane, when the
+	// encoder computed them.
+	Errors []uint64
+}
+
+// QualityStats returns the encoder statistics of the packet.
+func (d packetData) QualityStats() (QualityStats, bool) {
+	b := d.s.get(libavcodec.AV_PKT_DATA_QUALITY_STATS)
+	if len(b) < 8 {
+		return QualityStats{}, false
+	}
+	q := QualityStats{
+		Quality:  int(binary.LittleEndian.Uint32(b)),
+		PictType: libavutil.AVPictureType(b[4]),
+	}
+	n := int(b[5])
+	b = b[8:]
+	for i := 0; i < n && len(b) >= 8; i++ {
+		q.Errors = append(q.Errors, binary.LittleEndian.Uint64(b))
+		b = b[8:]
+	}
+	return q, true
+}
+
+// SetQualityStats replaces the encoder statistics of the packet.
+func (d packetData) SetQualityStats(q QualityStats) error {
+	b := make([]byte, 8, 8+8*len(q.Errors))
+	binary.LittleEndian.PutUint32(b, uint32(q.Quality))
+	b[4], b[5] = byte(q.PictType), byte(len(q.Errors))
+	for _, e := range q.Errors {
+		b = binary.LittleEndian.AppendUint64(b, e)
+	}
+	return d.SetBytes(libavcodec.AV_PKT_DATA_QUALITY_STATS, b)
+}
+
+// StringsMetadata returns the tags carried by the packet, in the order
+// they were packed.
+func (d packetData) StringsMetadata() ([]dict.Entry, bool) {
+	b := d.s.get(libavcodec.AV_PKT_DATA_STRINGS_METADATA)
+	if b == nil {
+		return nil, false
+	}
+	// key and value pairs, each NUL terminated
+	var entries []dict.Entry
+	for len(b) > 0 {
+		k := bytes.IndexByte(b, 0)
+		if k < 0 {
+			break
+		}
+		v := bytes.IndexByte(b[k+1:], 0)
+		if v < 0 {
+			break
+		}
+		entries = append(entries, dict.Entry{Key: string(b[:k]), Value: string(b[k+1 : k+1+v])})
+		b = b[k+1+v+1:]
+	}
+	return entries, true
+}
+
+// SetStringsMetadata replaces the tags carried by the packet.
+func (d packetData) SetStringsMetadata(entries []dict.Entry) error {
+	var b []byte
+	for _, e := range entries {
+		b = append(append(append(append(b, e.Key...), 0), e.Value...), 0)
+	}
+	return d.SetBytes(libavcodec.AV_PKT_DATA_STRINGS_METADATA, b)
+}
+
+// Subsample is a run of clear bytes followed by a run of encrypted ones.
+type Subsample struct {
+	Clear, Protected uint32
+}
+
+// EncryptionInfo tells how the packet is encrypted, as in the Common
+// Encryption (CENC) specification.
+type EncryptionInfo struct {
+	// Scheme is the protection scheme fourcc, e.g. 'cenc' or 'cbcs'.
+	Scheme uint32
+	// CryptByteBlock and SkipByteBlock give the pattern of encrypted and
+	// clear 16-byte blocks, 0 when every block is encrypted.
+	CryptByteBlock, SkipByteBlock uint32
+	KeyID                         []byte
+	IV                            []byte
+	// Subsamples is empty when the whole packet is encrypted.
+	Subsamples []Subsample
+}
+
+// encryptionInfoExtra is the size of the fixed fields of the encryption
+// info side data.
+const encryptionInfoExtra = 24
+
+// EncryptionInfo returns the encryption parameters of the packet.
+func (d packetData) EncryptionInfo() (EncryptionInfo, bool) {
+	b := d.s.get(libavcodec.AV_PKT_DATA_ENCRYPTION_INFO)
+	if len(b) < encryptionInfoExtra {
+		return EncryptionInfo{}, false
+	}
+	// big endian, as av_encryption_info_add_side_data writes it
+	e := EncryptionInfo{
+		Scheme:         binary.BigEndian.Uint32(b),
+		CryptByteBlock: binary.BigEndian.Uint32(b[4:]),
+		SkipByteBlock:  binary.BigEndian.Uint32(b[8:]),
+	}
+	keyIDSize := uint64(binary.BigEndian.Uint32(b[12:]))
+	ivSize := uint64(binary.BigEndian.Uint32(b[16:]))
+	count := uint64(binary.BigEndian.Uint32(b[20:]))
+	b = b[encryptionInfoExtra:]
+	if uint64(len(b)) < keyIDSize+ivSize+8*count {
+		return EncryptionInfo{}, false
+	}
+	e.KeyID = append([]byte{}, b[:keyIDSize]...)
+	e.IV = append([]byte{}, b[keyIDSize:keyIDSize+ivSize]...)
+	b = b[keyIDSize+ivSize:]
+	for i := uint64(0); i < count; i++ {
+		e.Subsamples = append(e.Subsamples, Subsample{
+			Clear:     binary.BigEndian.Uint32(b[8*i:]),
+			Protected: binary.BigEndian.Uint32(b[8*i+4:]),
+		})
+	}
+	return e, true
+}
+
+// SetEncryptionInfo replaces the encryption parameters of the packet.
+func (d packetData) SetEncryptionInfo(e EncryptionInfo) error {
+	b := make([]byte, 0, encryptionInfoExtra+len(e.KeyID)+len(e.IV)+8*len(e.Subsamples))
+	for _, v := range []uint32{e.Scheme, e.CryptByteBlock, e.SkipByteBlock, uint32(len(e.KeyID)), uint32(len(e.IV)), uint32(len(e.Subsamples))} {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	b = append(append(b, e.KeyID...), e.IV...)
+	for _, s := range e.Subsamples {
+		b = binary.BigEndian.AppendUint32(b, s.Clear)
+		b = binary.BigEndian.AppendUint32(b, s.Protected)
+	}
+	return d.SetBytes(libavcodec.AV_PKT_DATA_ENCRYPTION_INFO, b)
+}
@@ -0,0 +1,281 @@
+package sidedata
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dwdcth/ffmpeg-go/v7/dict"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+)
+
+// memStore keeps side data in Go memory, so the layouts are tested without
+// the FFmpeg libraries.
+type memStore map[libavcodec.AVPacketSideDataType][]byte
+
+func (m memStore) get(typ libavcodec.AVPacketSideDataType) []byte {
+	return m[typ]
+}
+
+func (m memStore) alloc(typ libavcodec.AVPacketSideDataType, size int) *ffcommon.FUint8T {
+	b := make([]byte, size+1)
+	m[typ] = b[:size]
+	return (*ffcommon.FUint8T)(&b[0])
+}
+
+func newData() (packetData, memStore) {
+	m := memStore{}
+	return packetData{m}, m
+}
+
+func TestParamChangeRoundTrip(t *testing.T) {
+	for _, p := range []ParamChange{
+		{},
+		{ChannelCount: 6},
+		{ChannelLayout: 0x3f},
+		{SampleRate: 44100},
+		{Width: 1920},
+		{ChannelCount: 2, ChannelLayout: 3, SampleRate: 48000, Width: 1280, Height: 720},
+	} {
+		d, _ := newData()
+		if err := d.SetParamChange(p); err != nil {
+			t.Fatal(err)
+		}
+		got, ok := d.ParamChange()
+		if !ok || got != p {
+			t.Errorf("ParamChange() = %+v, %v, want %+v", got, ok, p)
+		}
+	}
+}
+
+func TestSkipSamplesRoundTrip(t *testing.T) {
+	d, _ := newData()
+	s := SkipSamples{Start: 1024, End: 0xfffffff0, StartReason: 1, EndReason: 0}
+	if err := d.SetSkipSamples(s); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := d.SkipSamples(); !ok || got != s {
+		t.Errorf("SkipSamples() = %+v, %v, want %+v", got, ok, s)
+	}
+}
+
+func TestReplayGainRoundTrip(t *testing.T) {
+	for _, r := range []ReplayGain{
+		{TrackGain: -6.5, TrackPeak: 0.98765, AlbumGain: 1.25, AlbumPeak: 1},
+		{TrackGain: math.NaN(), AlbumGain: math.NaN()},
+	} {
+		d, _ := newData()
+		if err := d.SetReplayGain(r); err != nil {
+			t.Fatal(err)
+		}
+		got, ok := d.ReplayGain()
+		same := func(a, b float64) bool { return a == b || math.IsNaN(a) && math.IsNaN(b) }
+		if !ok || !same(got.TrackGain, r.TrackGain) || got.TrackPeak != r.TrackPeak ||
+			!same(got.AlbumGain, r.AlbumGain) || got.AlbumPeak != r.AlbumPeak {
+			t.Errorf("ReplayGain() = %+v, %v, want %+v", got, ok, r)
+		}
+	}
+}
+
+func TestQualityStatsRoundTrip(t *testing.T) {
+	for _, q := range []QualityStats{
+		{Quality: 236, PictType: 1},
+		{Quality: 3000, PictType: 3, Errors: []uint64{1 << 40, 12345, 0}},
+	} {
+		d, _ := newData()
+		if err := d.SetQualityStats(q); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := d.QualityStats(); !ok || !reflect.DeepEqual(got, q) {
+			t.Errorf("QualityStats() = %+v, %v, want %+v", got, ok, q)
+		}
+	}
+}
+
+func TestStringsMetadataRoundTrip(t *testing.T) {
+	for _, entries := range [][]dict.Entry{
+		nil,
+		{{Key: "title", Value: "a"}},
+		{{Key: "lang", Value: "eng"}, {Key: "empty", Value: ""}, {Key: "title", Value: "b"}},
+	} {
+		d, _ := newData()
+		if err := d.SetStringsMetadata(entries); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := d.StringsMetadata(); !ok || !reflect.DeepEqual(got, entries) {
+			t.Errorf("StringsMetadata() = %q, %v, want %q", got, ok, entries)
+		}
+	}
+}
+
+func TestEncryptionInfoRoundTrip(t *testing.T) {
+	for _, e := range []EncryptionInfo{
+		{Scheme: 0x63656e63, KeyID: []byte{}, IV: []byte{}},
+		{
+			Scheme: 0x63626373, CryptByteBlock: 1, SkipByteBlock: 9,
+			KeyID:      bytes.Repeat([]byte{0x11}, 16),
+			IV:         bytes.Repeat([]byte{0x22}, 16),
+			Subsamples: []Subsample{{Clear: 5, Protected: 1024}, {Clear: 0, Protected: 16}},
+		},
+	} {
+		d, _ := newData()
+		if err := d.SetEncryptionInfo(e); err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := d.EncryptionInfo(); !ok || !reflect.DeepEqual(got, e) {
+			t.Errorf("EncryptionInfo() = %+v, %v, want %+v", got, ok, e)
+		}
+	}
+}
+
+func TestMissingOrShort(t *testing.T) {
+	d, m := newData()
+	if _, ok := d.SkipSamples(); ok {
+		t.Error("SkipSamples() found in empty side data")
+	}
+	if _, ok := d.StringsMetadata(); ok {
+		t.Error("StringsMetadata() found in empty side data")
+	}
+	m[libavcodec.AV_PKT_DATA_SKIP_SAMPLES] = make([]byte, 9)
+	if _, ok := d.SkipSamples(); ok {
+		t.Error("SkipSamples() accepted 9 bytes")
+	}
+	// the channel count flag without its value
+	m[libavcodec.AV_PKT_DATA_PARAM_CHANGE] = []byte{1, 0, 0, 0}
+	if _, ok := d.ParamChange(); ok {
+		t.Error("ParamChange() accepted a missing channel count")
+	}
+	// two subsamples announced, one present
+	b := make([]byte, encryptionInfoExtra+8)
+	b[23] = 2
+	m[libavcodec.AV_PKT_DATA_ENCRYPTION_INFO] = b
+	if _, ok := d.EncryptionInfo(); ok {
+		t.Error("EncryptionInfo() accepted a missing subsample")
+	}
+}
+
+// TestFFmpegLayouts checks the accessors against side data as the FFmpeg
+// 4.4 functions named in each case write it, and that the setters write
+// the same bytes.
+func TestFFmpegLayouts(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		typ  libavcodec.AVPacketSideDataType
+		data []byte
+		get  func(packetData) (any, bool)
+		set  func(packetData) error
+		want any
+	}{
+		{
+			// ff_add_param_change(pkt, 2, AV_CH_LAYOUT_STEREO, 48000, 0, 0)
+			name: "param change audio",
+			typ:  libavcodec.AV_PKT_DATA_PARAM_CHANGE,
+			data: []byte{
+				0x07, 0x00, 0x00, 0x00,
+				0x02, 0x00, 0x00, 0x00,
+				0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x80, 0xbb, 0x00, 0x00,
+			},
+			get: func(d packetData) (any, bool) { return d.ParamChange() },
+			set: func(d packetData) error {
+				return d.SetParamChange(ParamChange{ChannelCount: 2, ChannelLayout: 3, SampleRate: 48000})
+			},
+			want: ParamChange{ChannelCount: 2, ChannelLayout: 3, SampleRate: 48000},
+		},
+		{
+			// ff_add_param_change(pkt, 0, 0, 0, 1280, 720)
+			name: "param change video",
+			typ:  libavcodec.AV_PKT_DATA_PARAM_CHANGE,
+			data: []byte{
+				0x08, 0x00, 0x00, 0x00,
+				0x00, 0x05, 0x00, 0x00,
+				0xd0, 0x02, 0x00, 0x00,
+			},
+			get:  func(d packetData) (any, bool) { return d.ParamChange() },
+			set:  func(d packetData) error { return d.SetParamChange(ParamChange{Width: 1280, Height: 720}) },
+			want: ParamChange{Width: 1280, Height: 720},
+		},
+		{
+			// the mov demuxer skipping the 2112 priming samples of AAC
+			name: "skip samples",
+			typ:  libavcodec.AV_PKT_DATA_SKIP_SAMPLES,
+			data: []byte{0x40, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			get:  func(d packetData) (any, bool) { return d.SkipSamples() },
+			set:  func(d packetData) error { return d.SetSkipSamples(SkipSamples{Start: 2112}) },
+			want: SkipSamples{Start: 2112},
+		},
+		{
+			// ff_side_data_set_encoder_stats(pkt, 2 * FF_QP2LAMBDA,
+			// (int64_t[]){100, 200, 300}, 3, AV_PICTURE_TYPE_P)
+			name: "quality stats",
+			typ:  libavcodec.AV_PKT_DATA_QUALITY_STATS,
+			data: []byte{
+				0xec, 0x00, 0x00, 0x00, 0x02, 0x03, 0x00, 0x00,
+				0x64, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0xc8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x2c, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			get: func(d packetData) (any, bool) { return d.QualityStats() },
+			set: func(d packetData) error {
+				return d.SetQualityStats(QualityStats{Quality: 236, PictType: 2, Errors: []uint64{100, 200, 300}})
+			},
+			want: QualityStats{Quality: 236, PictType: 2, Errors: []uint64{100, 200, 300}},
+		},
+		{
+			// av_packet_pack_dictionary of {title: a, language: eng}
+			name: "strings metadata",
+			typ:  libavcodec.AV_PKT_DATA_STRINGS_METADATA,
+			data: []byte("title\x00a\x00language\x00eng\x00"),
+			get:  func(d packetData) (any, bool) { return d.StringsMetadata() },
+			set: func(d packetData) error {
+				return d.SetStringsMetadata([]dict.Entry{{Key: "title", Value: "a"}, {Key: "language", Value: "eng"}})
+			},
+			want: []dict.Entry{{Key: "title", Value: "a"}, {Key: "language", Value: "eng"}},
+		},
+		{
+			// av_encryption_info_add_side_data of a 'cenc' sample with a
+			// 16-byte key id, an 8-byte IV and two subsamples
+			name: "encryption info",
+			typ:  libavcodec.AV_PKT_DATA_ENCRYPTION_INFO,
+			data: []byte{
+				0x63, 0x65, 0x6e, 0x63,
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x10,
+				0x00, 0x00, 0x00, 0x08,
+				0x00, 0x00, 0x00, 0x02,
+				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+				0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+				0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x04, 0x00,
+				0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x10, 0x00,
+			},
+			get:  func(d packetData) (any, bool) { return d.EncryptionInfo() },
+			set:  func(d packetData) error { return d.SetEncryptionInfo(cencFixture) },
+			want: cencFixture,
+		},
+	} {
+		d, m := newData()
+		m[tc.typ] = tc.data
+		got, ok := tc.get(d)
+		if !ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, %v, want %+v", tc.name, got, ok, tc.want)
+		}
+		d, m = newData()
+		if err := tc.set(d); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !bytes.Equal(m[tc.typ], tc.data) {
+			t.Errorf("%s: set wrote % x, want % x", tc.name, m[tc.typ], tc.data)
+		}
+	}
+}
+
+var cencFixture = EncryptionInfo{
+	Scheme:     0x63656e63,
+	KeyID:      []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f},
+	IV:         []byte{0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7},
+	Subsamples: []Subsample{{Clear: 5, Protected: 1024}, {Clear: 16, Protected: 4096}},
+}
@@ -0,0 +1,96 @@
+package sidedata
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// MotionVector is the motion of one block of a frame, exported by the
+// decoder when the export_mvs flag is set.
+type MotionVector struct {
+	// Source is -1 when the block is predicted from a past frame and 1
+	// from a future one.
+	Source int
+	// W and H are the size of the block.
+	W, H int
+	// SrcX, SrcY is the center of the block in the reference frame and
+	// DstX, DstY in the current one. They may lie outside the frame.
+	SrcX, SrcY int
+	DstX, DstY int
+	Flags      uint64
+	// MotionX and MotionY are the motion in 1/MotionScale pixels, with
+	// SrcX = DstX + MotionX/MotionScale.
+	MotionX, MotionY int
+	MotionScale      int
+}
+
+// Magnitude returns the length of the motion in pixels.
+func (mv MotionVector) Magnitude() float64 {
+	scale := float64(mv.MotionScale)
+	if scale == 0 {
+		scale = 1
+	}
+	return math.Hypot(float64(mv.MotionX), float64(mv.MotionY)) / scale
+}
+
+// MotionVectors returns the motion vectors of the frame.
+func (fr Frame) MotionVectors() []MotionVector {
+	sd := fr.get(libavutil.AV_FRAME_DATA_MOTION_VECTORS)
+	if sd == nil {
+		return nil
+	}
+	n := int(sd.Size) / int(unsafe.Sizeof(libavutil.AVMotionVector{}))
+	if n == 0 {
+		return nil
+	}
+	raw := unsafe.Slice((*libavutil.AVMotionVector)(unsafe.Pointer(sd.Data)), n)
+	mvs := make([]MotionVector, n)
+	for i, m := range raw {
+		mvs[i] = MotionVector{
+			Source:      int(m.Source),
+			W:           int(m.W),
+			H:           int(m.H),
+			SrcX:        int(m.SrcX),
+			SrcY:        int(m.SrcY),
+			DstX:        int(m.DstX),
+			DstY:        int(m.DstY),
+			Flags:       uint64(m.Flags),
+			MotionX:     int(m.MotionX),
+			MotionY:     int(m.MotionY),
+			MotionScale: int(m.MotionScale),
+		}
+	}
+	return mvs
+}
+
+// SetMotionVectors replaces the motion vectors of the frame.
+func (fr Frame) SetMotionVectors(mvs []MotionVector) error {
+	if len(mvs) == 0 {
+		fr.f.AvFrameRemoveSideData(libavutil.AV_FRAME_DATA_MOTION_VECTORS)
+		return nil
+	}
+	data := fr.replace(libavutil.AV_FRAME_DATA_MOTION_VECTORS, len(mvs)*int(unsafe.Sizeof(libavutil.AVMotionVector{})))
+	if data == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	raw := unsafe.Slice((*libavutil.AVMotionVector)(unsafe.Pointer(data)), len(mvs))
+	for i, mv := range mvs {
+		raw[i] = libavutil.AVMotionVector{
+			Source:      ffcommon.FInt32T(mv.Source),
+			W:           ffcommon.FUint8T(mv.W),
+			H:           ffcommon.FUint8T(mv.H),
+			SrcX:        ffcommon.FInt16T(mv.SrcX),
+			SrcY:        ffcommon.FInt16T(mv.SrcY),
+			DstX:        ffcommon.FInt16T(mv.DstX),
+			DstY:        ffcommon.FInt16T(mv.DstY),
+			Flags:       ffcommon.FUint64T(mv.Flags),
+			MotionX:     ffcommon.FInt32T(mv.MotionX),
+			MotionY:     ffcommon.FInt32T(mv.MotionY),
+			MotionScale: ffcommon.FUint16T(mv.MotionScale),
+		}
+	}
+	return nil
+}
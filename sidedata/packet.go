@@ -0,0 +1,249 @@
+package sidedata
+
+import (
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// store holds the side data of a packet or a stream.
+type store interface {
+	// get returns the side data of type typ, nil if there is none.
+	get(typ libavcodec.AVPacketSideDataType) []byte
+	// alloc allocates size zeroed bytes of side data of type typ,
+	// replacing the previous ones, nil if out of memory.
+	alloc(typ libavcodec.AVPacketSideDataType, size int) *ffcommon.FUint8T
+}
+
+// packetData implements the accessors shared by packets and streams.
+type packetData struct {
+	s store
+}
+
+// Packet gives typed access to the side data of a packet.
+type Packet struct {
+	packetData
+}
+
+// OfPacket returns the side data of pkt.
+func OfPacket(pkt *libavcodec.AVPacket) Packet {
+	return Packet{packetData{packetStore{pkt}}}
+}
+
+type packetStore struct {
+	pkt *libavcodec.AVPacket
+}
+
+func (s packetStore) get(typ libavcodec.AVPacketSideDataType) []byte {
+	if s.pkt == nil {
+		return nil
+	}
+	var size ffcommon.FIntOrSizeT
+	data := s.pkt.AvPacketGetSideData(typ, &size)
+	if data == nil {
+		return nil
+	}
+	return ffcommon.ByteSliceFromByteP(data, int(size))
+}
+
+func (s packetStore) alloc(typ libavcodec.AVPacketSideDataType, size int) *ffcommon.FUint8T {
+	data := s.pkt.AvPacketNewSideData(typ, ffcommon.FIntOrSizeT(size))
+	if data != nil {
+		clear(ffcommon.ByteSliceFromByteP(data, size))
+	}
+	return data
+}
+
+// Stream gives typed access to the side data of a stream, which applies to
+// all of its packets.
+type Stream struct {
+	packetData
+}
+
+// OfStream returns the side data of st.
+func OfStream(st *libavformat.AVStream) Stream {
+	return Stream{packetData{streamStore{st}}}
+}
+
+type streamStore struct {
+	st *libavformat.AVStream
+}
+
+func (s streamStore) get(typ libavcodec.AVPacketSideDataType) []byte {
+	if s.st == nil {
+		return nil
+	}
+	var size ffcommon.FIntOrSizeT
+	data := s.st.AvStreamGetSideData(typ, &size)
+	if data == nil {
+		return nil
+	}
+	return ffcommon.ByteSliceFromByteP(data, int(size))
+}
+
+func (s streamStore) alloc(typ libavcodec.AVPacketSideDataType, size int) *ffcommon.FUint8T {
+	data := s.st.AvStreamNewSideData(typ, ffcommon.FIntOrSizeT(size))
+	if data != nil {
+		clear(ffcommon.ByteSliceFromByteP(data, size))
+	}
+	return data
+}
+
+// Bytes returns a copy of the side data of type typ.
+func (d packetData) Bytes(typ libavcodec.AVPacketSideDataType) ([]byte, bool) {
+	b := d.s.get(typ)
+	if b == nil {
+		return nil, false
+	}
+	return append([]byte{}, b...), true
+}
+
+// SetBytes replaces the side data of type typ with b.
+func (d packetData) SetBytes(typ libavcodec.AVPacketSideDataType, b []byte) error {
+	return setBytes(d.s.alloc(typ, len(b)), b)
+}
+
+// structOf returns the side data of type typ as a T, false if there is none
+// or it is too short.
+func structOf[T any](d packetData, typ libavcodec.AVPacketSideDataType) (T, bool) {
+	var v T
+	b := d.s.get(typ)
+	if len(b) < int(unsafe.Sizeof(v)) {
+		return v, false
+	}
+	return *(*T)(unsafe.Pointer(&b[0])), true
+}
+
+// setStruct replaces the side data of type typ with v.
+func setStruct[T any](d packetData, typ libavcodec.AVPacketSideDataType, v T) error {
+	data := d.s.alloc(typ, int(unsafe.Sizeof(v)))
+	if data == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	*(*T)(unsafe.Pointer(data)) = v
+	return nil
+}
+
+// NewExtradata returns the new codec extradata sent with the packet.
+func (d packetData) NewExtradata() ([]byte, bool) {
+	return d.Bytes(libavcodec.AV_PKT_DATA_NEW_EXTRADATA)
+}
+
+// SetNewExtradata replaces the new codec extradata.
+func (d packetData) SetNewExtradata(extradata []byte) error {
+	return d.SetBytes(libavcodec.AV_PKT_DATA_NEW_EXTRADATA, extradata)
+}
+
+// DisplayMatrix returns the display matrix.
+func (d packetData) DisplayMatrix() (DisplayMatrix, bool) {
+	return structOf[DisplayMatrix](d, libavcodec.AV_PKT_DATA_DISPLAYMATRIX)
+}
+
+// SetDisplayMatrix replaces the display matrix.
+func (d packetData) SetDisplayMatrix(m DisplayMatrix) error {
+	return setStruct(d, libavcodec.AV_PKT_DATA_DISPLAYMATRIX, m)
+}
+
+// CPBProperties describes the coded picture buffer of an encoded stream.
+// Bitrates are in bits per second, 0 when unknown.
+type CPBProperties struct {
+	MaxBitrate, MinBitrate, AvgBitrate int64
+	// BufferSize is the size of the VBV buffer in bits.
+	BufferSize int
+	// VBVDelay is the delay between the arrival of the first bit of the
+	// frame in the buffer and its decoding, in 90 kHz units, ^uint64(0)
+	// when unknown.
+	VBVDelay uint64
+}
+
+// CPBProperties returns the coded picture buffer properties.
+func (d packetData) CPBProperties() (CPBProperties, bool) {
+	p, ok := structOf[libavcodec.AVCPBProperties](d, libavcodec.AV_PKT_DATA_CPB_PROPERTIES)
+	if !ok {
+		return CPBProperties{}, false
+	}
+	return CPBProperties{
+		MaxBitrate: int64(p.MaxBitrate),
+		MinBitrate: int64(p.MinBitrate),
+		AvgBitrate: int64(p.AvgBitrate),
+		BufferSize: int(p.BufferSize),
+		VBVDelay:   uint64(p.VbvDelay),
+	}, true
+}
+
+// SetCPBProperties replaces the coded picture buffer properties.
+func (d packetData) SetCPBProperties(c CPBProperties) error {
+	return setStruct(d, libavcodec.AV_PKT_DATA_CPB_PROPERTIES, libavcodec.AVCPBProperties{
+		MaxBitrate: ffcommon.FInt(c.MaxBitrate),
+		MinBitrate: ffcommon.FInt(c.MinBitrate),
+		AvgBitrate: ffcommon.FInt(c.AvgBitrate),
+		BufferSize: ffcommon.FInt(c.BufferSize),
+		VbvDelay:   ffcommon.FUint64T(c.VBVDelay),
+	})
+}
+
+// MPEGTSStreamID returns the stream_id of the PES packets carrying the
+// stream in MPEG-TS.
+func (d packetData) MPEGTSStreamID() (uint8, bool) {
+	b := d.s.get(libavcodec.AV_PKT_DATA_MPEGTS_STREAM_ID)
+	if len(b) < 1 {
+		return 0, false
+	}
+	return b[0], true
+}
+
+// SetMPEGTSStreamID replaces the MPEG-TS stream_id.
+func (d packetData) SetMPEGTSStreamID(id uint8) error {
+	return d.SetBytes(libavcodec.AV_PKT_DATA_MPEGTS_STREAM_ID, []byte{id})
+}
+
+// DOVI returns the Dolby Vision configuration, which FFmpeg keeps with the
+// stream rather than the frames.
+func (d packetData) DOVI() (DOVI, bool) {
+	r, ok := structOf[libavutil.AVDOVIDecoderConfigurationRecord](d, libavcodec.AV_PKT_DATA_DOVI_CONF)
+	if !ok {
+		return DOVI{}, false
+	}
+	return doviFrom(&r), true
+}
+
+// SetDOVI replaces the Dolby Vision configuration.
+func (d packetData) SetDOVI(v DOVI) error {
+	return setStruct(d, libavcodec.AV_PKT_DATA_DOVI_CONF, v.record())
+}
+
+// MasteringDisplay returns the mastering display metadata.
+func (d packetData) MasteringDisplay() (MasteringDisplay, bool) {
+	m, ok := structOf[libavutil.AVMasteringDisplayMetadata](d, libavcodec.AV_PKT_DATA_MASTERING_DISPLAY_METADATA)
+	if !ok {
+		return MasteringDisplay{}, false
+	}
+	return masteringDisplayFrom(&m), true
+}
+
+// SetMasteringDisplay replaces the mastering display metadata.
+func (d packetData) SetMasteringDisplay(md MasteringDisplay) error {
+	var m libavutil.AVMasteringDisplayMetadata
+	md.to(&m)
+	return setStruct(d, libavcodec.AV_PKT_DATA_MASTERING_DISPLAY_METADATA, m)
+}
+
+// ContentLightLevel returns the content light level.
+func (d packetData) ContentLightLevel() (ContentLightLevel, bool) {
+	m, ok := structOf[libavutil.AVContentLightMetadata](d, libavcodec.AV_PKT_DATA_CONTENT_LIGHT_LEVEL)
+	if !ok {
+		return ContentLightLevel{}, false
+	}
+	return ContentLightLevel{MaxCLL: uint(m.MaxCLL), MaxFALL: uint(m.MaxFALL)}, true
+}
+
+// SetContentLightLevel replaces the content light level.
+func (d packetData) SetContentLightLevel(c ContentLightLevel) error {
+	return setStruct(d, libavcodec.AV_PKT_DATA_CONTENT_LIGHT_LEVEL, libavutil.AVContentLightMetadata{
+		MaxCLL:  ffcommon.FUnsigned(c.MaxCLL),
+		MaxFALL: ffcommon.FUnsigned(c.MaxFALL),
+	})
+}
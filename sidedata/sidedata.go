@@ -0,0 +1,193 @@
+// Package sidedata reads and writes the side data attached to frames,
+// packets and streams as Go values instead of raw buffers.
+package sidedata
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Frame gives typed access to the side data of a frame. The zero value
+// holds no frame.
+type Frame struct {
+	f *libavutil.AVFrame
+}
+
+// OfFrame returns the side data of f.
+func OfFrame(f *libavutil.AVFrame) Frame {
+	return Frame{f: f}
+}
+
+// get returns the data of the first side data of type typ, nil if there is
+// none.
+func (fr Frame) get(typ libavutil.AVFrameSideDataType) *libavutil.AVFrameSideData {
+	if fr.f == nil {
+		return nil
+	}
+	sd := fr.f.AvFrameGetSideData(typ)
+	if sd == nil || sd.Data == nil {
+		return nil
+	}
+	return sd
+}
+
+// all returns every side data of type typ, in frame order.
+func (fr Frame) all(typ libavutil.AVFrameSideDataType) []*libavutil.AVFrameSideData {
+	if fr.f == nil {
+		return nil
+	}
+	var res []*libavutil.AVFrameSideData
+	for i := 0; i < int(fr.f.NbSideData); i++ {
+		sd := *(**libavutil.AVFrameSideData)(unsafe.Add(unsafe.Pointer(fr.f.SideData), uintptr(i)*unsafe.Sizeof(*fr.f.SideData)))
+		if sd.Type == typ && sd.Data != nil {
+			res = append(res, sd)
+		}
+	}
+	return res
+}
+
+// replace removes the side data of type typ and adds size zeroed bytes of
+// it, nil if out of memory.
+func (fr Frame) replace(typ libavutil.AVFrameSideDataType, size int) *ffcommon.FUint8T {
+	fr.f.AvFrameRemoveSideData(typ)
+	return fr.add(typ, size)
+}
+
+func (fr Frame) add(typ libavutil.AVFrameSideDataType, size int) *ffcommon.FUint8T {
+	sd := fr.f.AvFrameNewSideData(typ, ffcommon.FIntOrSizeT(size))
+	if sd == nil || sd.Data == nil {
+		return nil
+	}
+	clear(ffcommon.ByteSliceFromByteP(sd.Data, size))
+	return sd.Data
+}
+
+// Remove removes the side data of type typ.
+func (fr Frame) Remove(typ libavutil.AVFrameSideDataType) {
+	fr.f.AvFrameRemoveSideData(typ)
+}
+
+func bytesOf(sd *libavutil.AVFrameSideData) []byte {
+	return append([]byte(nil), ffcommon.ByteSliceFromByteP(sd.Data, int(sd.Size))...)
+}
+
+func setBytes(data *ffcommon.FUint8T, b []byte) error {
+	if data == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	copy(ffcommon.ByteSliceFromByteP(data, len(b)), b)
+	return nil
+}
+
+// A53CC returns the ATSC A/53 closed caption data of the frame, cc_data_pkt
+// triplets as found in the user data of the video bitstream.
+func (fr Frame) A53CC() ([]byte, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_A53_CC)
+	if sd == nil {
+		return nil, false
+	}
+	return bytesOf(sd), true
+}
+
+// SetA53CC replaces the closed caption data of the frame.
+func (fr Frame) SetA53CC(data []byte) error {
+	return setBytes(fr.replace(libavutil.AV_FRAME_DATA_A53_CC, len(data)), data)
+}
+
+// SEIUnregistered is a user data unregistered SEI message.
+type SEIUnregistered struct {
+	UUID    [16]byte
+	Payload []byte
+}
+
+// SEIUnregistered returns the user data unregistered SEI messages of the
+// frame, of which there may be several.
+func (fr Frame) SEIUnregistered() []SEIUnregistered {
+	var res []SEIUnregistered
+	for _, sd := range fr.all(libavutil.AV_FRAME_DATA_SEI_UNREGISTERED) {
+		b := bytesOf(sd)
+		if len(b) < 16 {
+			continue
+		}
+		m := SEIUnregistered{Payload: b[16:]}
+		copy(m.UUID[:], b)
+		res = append(res, m)
+	}
+	return res
+}
+
+// AddSEIUnregistered attaches a user data unregistered SEI message to the
+// frame, after those it already has.
+func (fr Frame) AddSEIUnregistered(m SEIUnregistered) error {
+	return setBytes(fr.add(libavutil.AV_FRAME_DATA_SEI_UNREGISTERED, 16+len(m.Payload)), append(m.UUID[:], m.Payload...))
+}
+
+// DisplayMatrix is the 3x3 transformation matrix to apply to the decoded
+// picture for display, in the row-major 16.16 and 2.30 fixed point format
+// of libavutil/display.h.
+type DisplayMatrix [9]int32
+
+// NewDisplayMatrix returns the matrix rotating by angle degrees counter
+// clockwise, then flipping horizontally and/or vertically.
+func NewDisplayMatrix(angle float64, hflip, vflip bool) DisplayMatrix {
+	var m [9]ffcommon.FInt32T
+	libavutil.AvDisplayRotationSet(&m, ffcommon.FDouble(angle))
+	if hflip || vflip {
+		libavutil.AvDisplayMatrixFlip(&m, boolInt(hflip), boolInt(vflip))
+	}
+	return DisplayMatrix(m)
+}
+
+// Rotation returns the counter clockwise rotation of m in degrees, in
+// [-180, 180], NaN if m is singular.
+func (m DisplayMatrix) Rotation() float64 {
+	mm := [9]ffcommon.FInt32T(m)
+	return float64(libavutil.AvDisplayRotationGet(&mm))
+}
+
+// Flipped tells whether m mirrors the picture, which Rotation does not
+// account for.
+func (m DisplayMatrix) Flipped() bool {
+	return int64(m[0])*int64(m[4])-int64(m[1])*int64(m[3]) < 0
+}
+
+// DisplayMatrix returns the display matrix of the frame.
+func (fr Frame) DisplayMatrix() (DisplayMatrix, bool) {
+	var m DisplayMatrix
+	sd := fr.get(libavutil.AV_FRAME_DATA_DISPLAYMATRIX)
+	if sd == nil || int(sd.Size) < int(unsafe.Sizeof(m)) {
+		return m, false
+	}
+	return *(*DisplayMatrix)(unsafe.Pointer(sd.Data)), true
+}
+
+// Rotation returns the counter clockwise rotation of the frame in degrees
+// from its display matrix, false if it has none or it is singular.
+func (fr Frame) Rotation() (float64, bool) {
+	m, ok := fr.DisplayMatrix()
+	if !ok {
+		return 0, false
+	}
+	angle := m.Rotation()
+	return angle, !math.IsNaN(angle)
+}
+
+// SetDisplayMatrix replaces the display matrix of the frame.
+func (fr Frame) SetDisplayMatrix(m DisplayMatrix) error {
+	data := fr.replace(libavutil.AV_FRAME_DATA_DISPLAYMATRIX, int(unsafe.Sizeof(m)))
+	if data == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	*(*DisplayMatrix)(unsafe.Pointer(data)) = m
+	return nil
+}
+
+func boolInt(b bool) ffcommon.FInt {
+	if b {
+		return 1
+	}
+	return 0
+}
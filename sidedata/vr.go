@@ -0,0 +1,96 @@
+package sidedata
+
+import (
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Spherical describes how a 360° video is mapped on its frames.
+type Spherical struct {
+	Projection libavutil.AVSphericalProjection
+	// Yaw, Pitch and Roll orient the sphere, in degrees.
+	Yaw, Pitch, Roll float64
+	// BoundLeft, BoundTop, BoundRight and BoundBottom crop an
+	// equirectangular tile, in 0.32 fixed point fractions of the frame.
+	BoundLeft, BoundTop, BoundRight, BoundBottom uint32
+	// Padding is the number of pixels between the faces of a cubemap.
+	Padding uint32
+}
+
+const fixed16 = 1 << 16
+
+// Spherical returns the spherical mapping of the frame.
+func (fr Frame) Spherical() (Spherical, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_SPHERICAL)
+	if sd == nil {
+		return Spherical{}, false
+	}
+	m := (*libavutil.AVSphericalMapping)(unsafe.Pointer(sd.Data))
+	return Spherical{
+		Projection:  m.Projection,
+		Yaw:         float64(m.Yaw) / fixed16,
+		Pitch:       float64(m.Pitch) / fixed16,
+		Roll:        float64(m.Roll) / fixed16,
+		BoundLeft:   m.BoundLeft,
+		BoundTop:    m.BoundTop,
+		BoundRight:  m.BoundRight,
+		BoundBottom: m.BoundBottom,
+		Padding:     m.Padding,
+	}, true
+}
+
+// SetSpherical replaces the spherical mapping of the frame.
+func (fr Frame) SetSpherical(s Spherical) error {
+	data := fr.replace(libavutil.AV_FRAME_DATA_SPHERICAL, int(unsafe.Sizeof(libavutil.AVSphericalMapping{})))
+	if data == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	*(*libavutil.AVSphericalMapping)(unsafe.Pointer(data)) = libavutil.AVSphericalMapping{
+		Projection:  s.Projection,
+		Yaw:         ffcommon.FInt32T(s.Yaw * fixed16),
+		Pitch:       ffcommon.FInt32T(s.Pitch * fixed16),
+		Roll:        ffcommon.FInt32T(s.Roll * fixed16),
+		BoundLeft:   s.BoundLeft,
+		BoundTop:    s.BoundTop,
+		BoundRight:  s.BoundRight,
+		BoundBottom: s.BoundBottom,
+		Padding:     s.Padding,
+	}
+	return nil
+}
+
+// Stereo3D tells how the views of a stereoscopic video are packed in its
+// frames.
+type Stereo3D struct {
+	Type libavutil.AVStereo3DType
+	// View is the view held by the frame, for frame sequential packing.
+	View libavutil.AVStereo3DView
+	// Invert is set when the right view comes first.
+	Invert bool
+}
+
+// Stereo3D returns the stereoscopic packing of the frame.
+func (fr Frame) Stereo3D() (Stereo3D, bool) {
+	sd := fr.get(libavutil.AV_FRAME_DATA_STEREO3D)
+	if sd == nil {
+		return Stereo3D{}, false
+	}
+	s := (*libavutil.AVStereo3D)(unsafe.Pointer(sd.Data))
+	return Stereo3D{Type: s.Type, View: s.View, Invert: s.Flags&libavutil.AV_STEREO3D_FLAG_INVERT != 0}, true
+}
+
+// SetStereo3D replaces the stereoscopic packing of the frame.
+func (fr Frame) SetStereo3D(s Stereo3D) error {
+	fr.f.AvFrameRemoveSideData(libavutil.AV_FRAME_DATA_STEREO3D)
+	p := fr.f.AvStereo3dCreateSideData()
+	if p == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	p.Type, p.View, p.Flags = s.Type, s.View, 0
+	if s.Invert {
+		p.Flags = libavutil.AV_STEREO3D_FLAG_INVERT
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package subs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultHeader returns an ASS header with a single Default style for a
+// PlayResX x PlayResY script, like the one the libavcodec text decoders
+// generate.
+func DefaultHeader(playResX, playResY int) string {
+	return fmt.Sprintf(`[Script Info]
+; Script generated by FFmpeg/Lavc
+ScriptType: v4.00+
+PlayResX: %d
+PlayResY: %d
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,16,&Hffffff,&Hffffff,&H0,&H0,0,0,0,0,100,100,0,0,1,1,0,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`, playResX, playResY)
+}
+
+// Dialogue returns the ASS dialogue line of a cue with plain text, in the
+// form the libavcodec encoders take.
+func Dialogue(readOrder int, text string) string {
+	return fmt.Sprintf("%d,0,Default,,0,0,0,,%s", readOrder, EscapeText(text))
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "{", `\{`, "}", `\}`, "\r\n", `\N`, "\n", `\N`)
+
+// EscapeText converts plain text to ASS text, escaping backslashes,
+// override braces and line breaks like libavcodec.
+func EscapeText(text string) string {
+	return escaper.Replace(text)
+}
+
+// DialogueText returns the plain text of an ASS dialogue line, without
+// the leading fields and the override tags.
+func DialogueText(line string) string {
+	line = strings.TrimPrefix(line, "Dialogue:")
+	// ReadOrder,Layer,Style,Name,MarginL,MarginR,MarginV,Effect,Text
+	fields := strings.SplitN(line, ",", 9)
+	text := fields[len(fields)-1]
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text):
+			switch text[i+1] {
+			case 'N', 'n':
+				if depth == 0 {
+					b.WriteByte('\n')
+				}
+				i++
+				continue
+			case 'h':
+				if depth == 0 {
+					b.WriteByte(' ')
+				}
+				i++
+				continue
+			case '{', '}', '\\':
+				if depth == 0 {
+					b.WriteByte(text[i+1])
+				}
+				i++
+				continue
+			}
+			if depth == 0 {
+				b.WriteByte(c)
+			}
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		default:
+			if depth == 0 {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func joinLines(lines []string) string {
+	var kept []string
+	for _, l := range lines {
+		if l != "" {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,54 @@
+package subs
+
+import "testing"
+
+func TestEscapeText(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"plain, text", "plain, text"},
+		{`back\slash`, `back\\slash`},
+		{"{braces}", `\{braces\}`},
+		{"two\nlines", `two\Nlines`},
+		{"crlf\r\nlines", `crlf\Nlines`},
+		{`{\i1}`, `\{\\i1\}`},
+	} {
+		if got := EscapeText(tc.in); got != tc.want {
+			t.Errorf("EscapeText(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDialogueText(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"0,0,Default,,0,0,0,,Hello", "Hello"},
+		{"Dialogue:3,0,Default,,0,0,0,,Hello", "Hello"},
+		{"0,0,Default,,0,0,0,,Hello, world", "Hello, world"},
+		{`0,0,Default,,0,0,0,,two\Nlines\nhere`, "two\nlines\nhere"},
+		{`0,0,Default,,0,0,0,,hard\hspace`, "hard space"},
+		{`0,0,Default,,0,0,0,,{\i1}italic{\i0} and {\b1}bold{\b0}`, "italic and bold"},
+		{`0,0,Default,,0,0,0,,{\pos(10,20)\c&H00FF00&}green`, "green"},
+		{`0,0,Default,,0,0,0,,{comment {nested}}text`, "text"},
+		{`0,0,Default,,0,0,0,,\{not a tag\} \\`, `{not a tag} \`},
+		{`0,0,Default,,0,0,0,,{\N}hidden break`, "hidden break"},
+		{`0,0,Default,,0,0,0,,a \t b }`, `a \t b }`},
+		{"0,0,Default,,0,0,0,,  padded  ", "padded"},
+		{"0,0,Default,,0,0,0,,", ""},
+	} {
+		if got := DialogueText(tc.in); got != tc.want {
+			t.Errorf("DialogueText(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDialogueRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"Hello",
+		"a, b, c",
+		"line one\nline two",
+		`back\slash {braces} \N`,
+	} {
+		line := Dialogue(7, text)
+		if got := DialogueText(line); got != text {
+			t.Errorf("DialogueText(Dialogue(%q)) = %q (line %q)", text, got, line)
+		}
+	}
+}
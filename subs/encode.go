@@ -0,0 +1,245 @@
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Format is a text subtitle file format, named after its muxer.
+type Format string
+
+const (
+	SRT    Format = "srt"
+	ASS    Format = "ass"
+	WebVTT Format = "webvtt"
+)
+
+// FormatFor guesses the format of path from its extension.
+func FormatFor(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt":
+		return SRT, nil
+	case ".ass", ".ssa":
+		return ASS, nil
+	case ".vtt":
+		return WebVTT, nil
+	}
+	return "", fmt.Errorf("subs: unknown subtitle format for %s", path)
+}
+
+// CodecID returns the codec of the format.
+func (f Format) CodecID() libavcodec.AVCodecID {
+	switch f {
+	case SRT:
+		return libavcodec.AV_CODEC_ID_SUBRIP
+	case ASS:
+		return libavcodec.AV_CODEC_ID_ASS
+	case WebVTT:
+		return libavcodec.AV_CODEC_ID_WEBVTT
+	}
+	return libavcodec.AV_CODEC_ID_NONE
+}
+
+// ErrBitmap is returned when bitmap subtitles are to be encoded as text.
+var ErrBitmap = errors.New("subs: bitmap subtitles cannot be converted to text")
+
+// Packet is an encoded cue.
+type Packet struct {
+	Start, End time.Duration
+	Data       []byte
+}
+
+// millisecond is the time base of encoded subtitles.
+var millisecond = libavutil.AVRational{Num: 1, Den: 1000}
+
+// Encode encodes cues with the text subtitle encoder of codec id. header
+// is the ASS header the encoder reads the styles from, DefaultHeader if
+// empty. Cues keep their ASS dialogue lines if they have some, otherwise
+// their Text is used.
+func Encode(id libavcodec.AVCodecID, header string, cues []Cue) ([]Packet, error) {
+	codec := libavcodec.AvcodecFindEncoder(id)
+	if codec == nil {
+		return nil, fmt.Errorf("subs: no encoder for %s", libavcodec.AvcodecGetName(id))
+	}
+	ctx := codec.AvcodecAllocContext3()
+	if ctx == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvcodecFreeContext(&ctx)
+	if header == "" {
+		header = DefaultHeader(384, 288)
+	}
+	// freed with the context
+	ctx.SubtitleHeader = cBytes(header, 0)
+	ctx.SubtitleHeaderSizev = ffcommon.FInt(len(header))
+	ctx.TimeBase = millisecond
+	if ret := ctx.AvcodecOpen2(codec, nil); ret < 0 {
+		return nil, fmt.Errorf("subs: open encoder: %w", libavutil.ErrorFromCode(ret))
+	}
+
+	buf := make([]byte, 1<<20)
+	packets := make([]Packet, 0, len(cues))
+	for i, c := range cues {
+		lines := c.ASS
+		if len(lines) == 0 {
+			if c.Text == "" {
+				if len(c.Bitmaps) > 0 {
+					return nil, ErrBitmap
+				}
+				continue
+			}
+			lines = []string{Dialogue(i, c.Text)}
+		}
+		sub, err := textSubtitle(lines)
+		if err != nil {
+			return nil, err
+		}
+		sub.Pts = ffcommon.FInt64T(c.Start / time.Microsecond)
+		sub.EndDisplayTime = ffcommon.FUint32T((c.End - c.Start) / time.Millisecond)
+		n := ctx.AvcodecEncodeSubtitle(&buf[0], ffcommon.FInt(len(buf)), sub)
+		sub.AvsubtitleFree()
+		if n < 0 {
+			return nil, fmt.Errorf("subs: encode cue %d: %w", i, libavutil.ErrorFromCode(n))
+		}
+		packets = append(packets, Packet{Start: c.Start, End: c.End, Data: append([]byte(nil), buf[:n]...)})
+	}
+	return packets, nil
+}
+
+// textSubtitle builds an AVSubtitle whose rects hold lines, allocated the
+// way avsubtitle_free releases them.
+func textSubtitle(lines []string) (*libavcodec.AVSubtitle, error) {
+	sub := &libavcodec.AVSubtitle{}
+	mem := libavutil.AvMalloczArray(ffcommon.FSizeT(len(lines)), ffcommon.FSizeT(unsafe.Sizeof(uintptr(0))))
+	if mem == 0 {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	sub.Rects = *(***libavcodec.AVSubtitleRect)(unsafe.Pointer(&mem))
+	for i, line := range lines {
+		rm := libavutil.AvMallocz(ffcommon.FSizeT(unsafe.Sizeof(libavcodec.AVSubtitleRect{})))
+		r := *(**libavcodec.AVSubtitleRect)(unsafe.Pointer(&rm))
+		if r == nil {
+			sub.AvsubtitleFree()
+			return nil, libavutil.AVError(-libavutil.ENOMEM)
+		}
+		*(**libavcodec.AVSubtitleRect)(unsafe.Add(unsafe.Pointer(sub.Rects), uintptr(i)*unsafe.Sizeof(*sub.Rects))) = r
+		sub.NumRects++
+		r.Type = libavcodec.SUBTITLE_ASS
+		if r.Ass = uintptr(unsafe.Pointer(cBytes(line, 0))); r.Ass == 0 {
+			sub.AvsubtitleFree()
+			return nil, libavutil.AVError(-libavutil.ENOMEM)
+		}
+	}
+	return sub, nil
+}
+
+// cBytes copies s to a NUL terminated buffer from av_malloc, followed by
+// padding zero bytes.
+func cBytes(s string, padding int) *ffcommon.FUint8T {
+	mem := libavutil.AvMallocz(ffcommon.FSizeT(len(s) + 1 + padding))
+	p := *(**ffcommon.FUint8T)(unsafe.Pointer(&mem))
+	if p != nil {
+		copy(ffcommon.ByteSliceFromByteP(p, len(s)), s)
+	}
+	return p
+}
+
+// Write encodes cues to a subtitle file at path in format f. header is as
+// for Encode.
+func Write(path string, f Format, header string, cues []Cue) error {
+	if header == "" {
+		header = DefaultHeader(384, 288)
+	}
+	packets, err := Encode(f.CodecID(), header, cues)
+	if err != nil {
+		return err
+	}
+
+	var oc *libavformat.AVFormatContext
+	if ret := libavformat.AvformatAllocOutputContext2(&oc, nil, string(f), path); ret < 0 || oc == nil {
+		return fmt.Errorf("subs: create output %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	defer oc.AvformatFreeContext()
+	st := oc.AvformatNewStream(nil)
+	if st == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	st.TimeBase = millisecond
+	st.Codecpar.CodecType = libavutil.AVMEDIA_TYPE_SUBTITLE
+	st.Codecpar.CodecId = f.CodecID()
+	// the ASS muxer writes the header from the extradata
+	st.Codecpar.Extradata = cBytes(header, libavcodec.AV_INPUT_BUFFER_PADDING_SIZE)
+	st.Codecpar.ExtradataSize = ffcommon.FInt(len(header))
+
+	if ret := libavformat.AvioOpen(&oc.Pb, path, libavformat.AVIO_FLAG_WRITE); ret < 0 {
+		return fmt.Errorf("subs: open %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	defer libavformat.AvioClosep(&oc.Pb)
+	if ret := oc.AvformatWriteHeader(nil); ret < 0 {
+		return fmt.Errorf("subs: write header %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+	for _, p := range packets {
+		if ret := pkt.AvNewPacket(ffcommon.FInt(len(p.Data))); ret < 0 {
+			return libavutil.ErrorFromCode(ret)
+		}
+		copy(ffcommon.ByteSliceFromByteP(pkt.Data, len(p.Data)), p.Data)
+		pkt.Pts = libavutil.AvRescaleQ(ffcommon.FInt64T(p.Start/time.Millisecond), millisecond, st.TimeBase)
+		pkt.Dts = pkt.Pts
+		pkt.Duration = libavutil.AvRescaleQ(ffcommon.FInt64T((p.End-p.Start)/time.Millisecond), millisecond, st.TimeBase)
+		pkt.StreamIndex = 0
+		pkt.Flags |= libavcodec.AV_PKT_FLAG_KEY
+		if ret := oc.AvInterleavedWriteFrame(pkt); ret < 0 {
+			return fmt.Errorf("subs: write packet: %w", libavutil.ErrorFromCode(ret))
+		}
+	}
+	if ret := oc.AvWriteTrailer(); ret < 0 {
+		return fmt.Errorf("subs: write trailer %s: %w", path, libavutil.ErrorFromCode(ret))
+	}
+	return nil
+}
+
+// Convert writes the subtitle stream with index stream of src, or the
+// best one if stream is negative, to dst in the format of its extension.
+// Only text subtitles can be converted.
+func Convert(ctx context.Context, src string, stream int, dst string) error {
+	f, err := FormatFor(dst)
+	if err != nil {
+		return err
+	}
+	t, err := Decode(ctx, src, stream)
+	if err != nil {
+		return err
+	}
+	return Write(dst, f, t.Header, t.Cues)
+}
+
+// ToWebVTT returns the subtitle stream of src as a WebVTT document, like
+// Convert.
+func ToWebVTT(ctx context.Context, src string, stream int) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "subs")
+	if err != nil {
+		return nil, fmt.Errorf("subs: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "subs.vtt")
+	if err = Convert(ctx, src, stream, dst); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(dst)
+}
@@ -0,0 +1,246 @@
+// Package subs decodes, encodes and converts subtitles, text based (SRT,
+// ASS, WebVTT, mov_text) as well as bitmap based (PGS, DVB, DVD).
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"time"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/internal/remux"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Bitmap is a picture of a bitmap subtitle, placed at X, Y on the video.
+type Bitmap struct {
+	X, Y  int
+	Image *image.Paletted
+}
+
+// Cue is a subtitle shown from Start to End.
+type Cue struct {
+	Start, End time.Duration
+	// Text is the plain text of the cue, lines separated by "\n".
+	Text string
+	// ASS holds the ASS dialogue lines of text subtitles, in the
+	// "ReadOrder,Layer,Style,Name,MarginL,MarginR,MarginV,Effect,Text"
+	// form of the libavcodec decoders.
+	ASS     []string
+	Bitmaps []Bitmap
+	Forced  bool
+}
+
+// Track is a decoded subtitle stream.
+type Track struct {
+	// Codec is the name of the subtitle codec, e.g. "subrip".
+	Codec    string
+	Language string
+	// Header is the ASS header of text subtitles.
+	Header string
+	Cues   []Cue
+}
+
+// Decoder decodes the packets of one subtitle stream.
+type Decoder struct {
+	ctx *libavcodec.AVCodecContext
+}
+
+// NewDecoder opens a decoder for a stream with codec parameters par and
+// time base tb.
+func NewDecoder(par *libavcodec.AVCodecParameters, tb libavutil.AVRational) (*Decoder, error) {
+	codec := libavcodec.AvcodecFindDecoder(par.CodecId)
+	if codec == nil {
+		return nil, fmt.Errorf("subs: no decoder for %s", libavcodec.AvcodecGetName(par.CodecId))
+	}
+	d := &Decoder{ctx: codec.AvcodecAllocContext3()}
+	if d.ctx == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	if ret := d.ctx.AvcodecParametersToContext(par); ret < 0 {
+		d.Close()
+		return nil, fmt.Errorf("subs: %w", libavutil.ErrorFromCode(ret))
+	}
+	// needed to compute the subtitle times from the packets
+	d.ctx.PktTimebase = tb
+	if ret := d.ctx.AvcodecOpen2(codec, nil); ret < 0 {
+		d.Close()
+		return nil, fmt.Errorf("subs: open decoder: %w", libavutil.ErrorFromCode(ret))
+	}
+	return d, nil
+}
+
+// Header returns the ASS header the decoder generated, "" for bitmap
+// codecs.
+func (d *Decoder) Header() string {
+	return string(ffcommon.ByteSliceFromByteP(d.ctx.SubtitleHeader, int(d.ctx.SubtitleHeaderSizev)))
+}
+
+// Decode decodes pkt. It returns nil if the packet holds no complete
+// subtitle. A cue without text or bitmaps clears the screen, ending the
+// previous cue. End equals Start when the packet does not tell how long
+// the cue lasts.
+func (d *Decoder) Decode(pkt *libavcodec.AVPacket) (*Cue, error) {
+	var sub libavcodec.AVSubtitle
+	var got ffcommon.FInt
+	if ret := d.ctx.AvcodecDecodeSubtitle2(&sub, &got, pkt); ret < 0 {
+		return nil, fmt.Errorf("subs: decode: %w", libavutil.ErrorFromCode(ret))
+	}
+	if got == 0 {
+		return nil, nil
+	}
+	defer sub.AvsubtitleFree()
+
+	pts := int64(sub.Pts)
+	if sub.Pts == libavutil.AV_NOPTS_VALUE {
+		pts = 0
+		if pkt.Pts != libavutil.AV_NOPTS_VALUE {
+			pts = int64(libavutil.AvRescaleQ(pkt.Pts, d.ctx.PktTimebase, libavutil.AVRational{Num: 1, Den: libavutil.AV_TIME_BASE}))
+		}
+	}
+	base := time.Duration(pts) * time.Microsecond
+	cue := &Cue{
+		Start: base + time.Duration(sub.StartDisplayTime)*time.Millisecond,
+		End:   base + time.Duration(sub.EndDisplayTime)*time.Millisecond,
+	}
+	if sub.EndDisplayTime == 0 || sub.EndDisplayTime == ^ffcommon.FUint32T(0) {
+		cue.End = cue.Start
+	}
+	var lines []string
+	for i := ffcommon.FUnsigned(0); i < sub.NumRects; i++ {
+		r := *(**libavcodec.AVSubtitleRect)(unsafe.Add(unsafe.Pointer(sub.Rects), uintptr(i)*unsafe.Sizeof(*sub.Rects)))
+		if r.Flags&libavcodec.AV_SUBTITLE_FLAG_FORCED != 0 {
+			cue.Forced = true
+		}
+		switch r.Type {
+		case libavcodec.SUBTITLE_BITMAP:
+			cue.Bitmaps = append(cue.Bitmaps, bitmap(r))
+		case libavcodec.SUBTITLE_TEXT:
+			lines = append(lines, ffcommon.GoString(r.Text))
+		case libavcodec.SUBTITLE_ASS:
+			ass := ffcommon.GoString(r.Ass)
+			cue.ASS = append(cue.ASS, ass)
+			lines = append(lines, DialogueText(ass))
+		}
+	}
+	cue.Text = joinLines(lines)
+	return cue, nil
+}
+
+// Close frees the decoder.
+func (d *Decoder) Close() {
+	libavcodec.AvcodecFreeContext(&d.ctx)
+}
+
+// bitmap converts a PAL8 subtitle rectangle.
+func bitmap(r *libavcodec.AVSubtitleRect) Bitmap {
+	w, h := int(r.W), int(r.H)
+	img := image.NewPaletted(image.Rect(0, 0, w, h), make(color.Palette, r.NbColors))
+	// native endian 0xAARRGGBB
+	pal := unsafe.Slice((*uint32)(unsafe.Pointer(r.Data[1])), r.NbColors)
+	for i, c := range pal {
+		img.Palette[i] = color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: uint8(c >> 24)}
+	}
+	stride := int(r.Linesize[0])
+	if h > 0 {
+		src := ffcommon.ByteSliceFromByteP(r.Data[0], stride*(h-1)+w)
+		for y := 0; y < h; y++ {
+			copy(img.Pix[y*img.Stride:y*img.Stride+w], src[y*stride:])
+		}
+	}
+	return Bitmap{X: int(r.X), Y: int(r.Y), Image: img}
+}
+
+// Decode reads the subtitle stream with index stream of the file at path,
+// or the best subtitle stream if stream is negative. Cues of unknown
+// duration, as bitmap formats send them, end where the next one starts.
+func Decode(ctx context.Context, path string, stream int) (*Track, error) {
+	ic, err := remux.OpenInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("subs: %w", err)
+	}
+	defer libavformat.AvformatCloseInput(&ic)
+
+	if stream < 0 {
+		stream = int(ic.AvFindBestStream(libavutil.AVMEDIA_TYPE_SUBTITLE, -1, -1, nil, 0))
+		if stream < 0 {
+			return nil, fmt.Errorf("subs: %s: no subtitle stream", path)
+		}
+	}
+	if stream >= int(ic.NbStreams) {
+		return nil, fmt.Errorf("subs: %s: no stream %d", path, stream)
+	}
+	st := ic.GetStream(ffcommon.FUnsignedInt(stream))
+	if st.Codecpar.CodecType != libavutil.AVMEDIA_TYPE_SUBTITLE {
+		return nil, fmt.Errorf("subs: %s: stream %d is %s", path, stream, libavutil.AvGetMediaTypeString(st.Codecpar.CodecType))
+	}
+	for i := ffcommon.FUnsignedInt(0); i < ic.NbStreams; i++ {
+		if int(i) != stream {
+			ic.GetStream(i).Discard = libavcodec.AVDISCARD_ALL
+		}
+	}
+
+	d, err := NewDecoder(st.Codecpar, st.TimeBase)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	t := &Track{Codec: libavcodec.AvcodecGetName(st.Codecpar.CodecId), Header: d.Header()}
+	if e := st.Metadata.AvDictGet("language", nil, 0); e != nil {
+		t.Language = ffcommon.GoString(e.Value)
+	}
+
+	pkt := libavcodec.AvPacketAlloc()
+	if pkt == nil {
+		return nil, libavutil.AVError(-libavutil.ENOMEM)
+	}
+	defer libavcodec.AvPacketFree(&pkt)
+	open := -1
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		err = libavutil.ErrorFromCode(ic.AvReadFrame(pkt))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("subs: read packet: %w", err)
+		}
+		if int(pkt.StreamIndex) != stream {
+			pkt.AvPacketUnref()
+			continue
+		}
+		cue, err := d.Decode(pkt)
+		pkt.AvPacketUnref()
+		if err != nil {
+			return nil, err
+		}
+		if cue == nil {
+			continue
+		}
+		if open >= 0 {
+			if t.Cues[open].End = cue.Start; t.Cues[open].End < t.Cues[open].Start {
+				t.Cues[open].End = t.Cues[open].Start
+			}
+			open = -1
+		}
+		if cue.Text == "" && len(cue.Bitmaps) == 0 {
+			continue
+		}
+		t.Cues = append(t.Cues, *cue)
+		if cue.End == cue.Start {
+			open = len(t.Cues) - 1
+		}
+	}
+	sort.SliceStable(t.Cues, func(i, j int) bool { return t.Cues[i].Start < t.Cues[j].Start })
+	return t, nil
+}
@@ -0,0 +1,52 @@
+package subs
+
+import (
+	"image/color"
+	"testing"
+	"unsafe"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+)
+
+func TestBitmap(t *testing.T) {
+	// 0xAARRGGBB in native order, as the bitmap decoders write the palette
+	pal := []uint32{0x80ff0000, 0xff00ff00, 0x00000000}
+	// 3x2 picture with a stride of 4
+	pix := []byte{
+		0, 1, 2, 9,
+		1, 1, 0, 9,
+	}
+	r := &libavcodec.AVSubtitleRect{X: 10, Y: 20, W: 3, H: 2, NbColors: 3}
+	r.Data[0] = (*ffcommon.FUint8T)(&pix[0])
+	r.Data[1] = (*ffcommon.FUint8T)(unsafe.Pointer(&pal[0]))
+	r.Linesize[0] = 4
+
+	b := bitmap(r)
+	if b.X != 10 || b.Y != 20 {
+		t.Errorf("bitmap at %d,%d, want 10,20", b.X, b.Y)
+	}
+	want := color.Palette{
+		color.NRGBA{R: 0xff, A: 0x80},
+		color.NRGBA{G: 0xff, A: 0xff},
+		color.NRGBA{},
+	}
+	if len(b.Image.Palette) != len(want) {
+		t.Fatalf("%d colors, want %d", len(b.Image.Palette), len(want))
+	}
+	for i, c := range want {
+		if b.Image.Palette[i] != c {
+			t.Errorf("color %d = %v, want %v", i, b.Image.Palette[i], c)
+		}
+	}
+	if w, h := b.Image.Bounds().Dx(), b.Image.Bounds().Dy(); w != 3 || h != 2 {
+		t.Fatalf("size %dx%d, want 3x2", w, h)
+	}
+	for y, row := range [][]uint8{{0, 1, 2}, {1, 1, 0}} {
+		for x, i := range row {
+			if got := b.Image.ColorIndexAt(x, y); got != i {
+				t.Errorf("pixel %d,%d = %d, want %d", x, y, got, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Package ts repairs packet timestamps of live and broadcast inputs so they
+// can be muxed: missing values, timestamp wraparound, discontinuities and
+// non-monotonic decoding timestamps.
+package ts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/avutil"
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavformat"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+// Kind is the kind of a Correction.
+type Kind int
+
+const (
+	// MissingPTS is a presentation timestamp filled in.
+	MissingPTS Kind = iota
+	// MissingDTS is a decoding timestamp filled in.
+	MissingDTS
+	// Wrap is a timestamp unwrapped past the pts_wrap_bits range.
+	Wrap
+	// Discontinuity is a jump larger than the threshold that was removed.
+	Discontinuity
+	// NonMonotonic is a decoding timestamp raised above the previous one.
+	NonMonotonic
+	// PTSBeforeDTS is a presentation timestamp raised to the decoding one.
+	PTSBeforeDTS
+)
+
+var kindNames = [...]string{
+	MissingPTS:    "missing pts",
+	MissingDTS:    "missing dts",
+	Wrap:          "wraparound",
+	Discontinuity: "discontinuity",
+	NonMonotonic:  "non-monotonic dts",
+	PTSBeforeDTS:  "pts before dts",
+}
+
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Correction is a change made to a packet. Old is AV_NOPTS_VALUE for filled
+// in timestamps. For Wrap and Discontinuity, Old and New are the decoding
+// timestamps before and after the offset that now applies to every
+// following packet.
+type Correction struct {
+	Kind     Kind
+	Old, New ffcommon.FInt64T
+}
+
+func (c Correction) String() string {
+	if c.Old == libavutil.AV_NOPTS_VALUE {
+		return fmt.Sprintf("%v: set to %d", c.Kind, c.New)
+	}
+	return fmt.Sprintf("%v: %d -> %d", c.Kind, c.Old, c.New)
+}
+
+// Config configures a Sanitizer. Timestamps are in TimeBase.
+type Config struct {
+	TimeBase libavutil.AVRational
+	// WrapBits is the number of bits of the timestamps, 33 for MPEG-TS,
+	// 0 or 64 for no wraparound.
+	WrapBits int
+	// Threshold is the largest jump between consecutive decoding
+	// timestamps kept as is, 10 seconds by default.
+	Threshold time.Duration
+}
+
+// Sanitizer repairs the timestamps of the packets of one stream.
+type Sanitizer struct {
+	c         Config
+	threshold int64
+
+	// wrap is the offset added by unwrapping, shift the offset removing
+	// discontinuities
+	wrap, shift int64
+	// lastRaw is the last unwrapped input dts
+	lastRaw int64
+	// lastDts and lastDur are those of the last packet returned
+	lastDts, lastDur int64
+	started          bool
+}
+
+// New returns a Sanitizer for c.
+func New(c Config) *Sanitizer {
+	if c.Threshold <= 0 {
+		c.Threshold = 10 * time.Second
+	}
+	s := &Sanitizer{c: c}
+	s.threshold = avutil.RescaleQ(int64(c.Threshold/time.Microsecond), avutil.Q(1, libavutil.AV_TIME_BASE), avutil.Rational(c.TimeBase))
+	if s.threshold < 1 {
+		s.threshold = 1
+	}
+	return s
+}
+
+// ForStream returns a Sanitizer for the packets read from st, using its
+// time base and pts_wrap_bits.
+func ForStream(st *libavformat.AVStream) *Sanitizer {
+	return New(Config{TimeBase: st.TimeBase, WrapBits: int(st.PtsWrapBits)})
+}
+
+// Fix repairs the timestamps of pkt in place and returns the corrections
+// made, nil if the packet was fine.
+func (s *Sanitizer) Fix(pkt *libavcodec.AVPacket) []Correction {
+	var fixes []Correction
+	report := func(k Kind, from, to int64) {
+		fixes = append(fixes, Correction{Kind: k, Old: ffcommon.FInt64T(from), New: ffcommon.FInt64T(to)})
+	}
+	none := int64(libavutil.AV_NOPTS_VALUE)
+	pts, dts := int64(pkt.Pts), int64(pkt.Dts)
+
+	// unwrap against the previous packet, the pts against its own dts
+	if s.wrapping() {
+		period := int64(1) << uint(s.c.WrapBits)
+		if dts != none {
+			dts += s.wrap
+			if s.started {
+				if d := dts - s.lastRaw; d < -period/2 {
+					report(Wrap, dts+s.shift, dts+period+s.shift)
+					s.wrap += period
+					dts += period
+				} else if d > period/2 && s.wrap >= period {
+					// a late packet from before the wrap
+					dts -= period
+				}
+			}
+			s.lastRaw = dts
+		}
+		if pts != none {
+			pts += s.wrap
+			ref := dts
+			if ref == none {
+				ref = s.lastRaw
+			}
+			if s.started || dts != none {
+				if d := pts - ref; d < -period/2 {
+					pts += period
+				} else if d > period/2 {
+					pts -= period
+				}
+			}
+		}
+	}
+	if pts != none {
+		pts += s.shift
+	}
+	if dts != none {
+		dts += s.shift
+	}
+
+	dur := int64(pkt.Duration)
+	if dur <= 0 {
+		dur = s.lastDur
+	}
+	if dur <= 0 {
+		dur = 1
+	}
+	expected := s.lastDts + s.lastDur
+
+	// fill in missing values
+	switch {
+	case dts == none && pts == none:
+		if s.started {
+			dts = expected
+		} else {
+			dts = 0
+		}
+		pts = dts
+		report(MissingDTS, none, dts)
+		report(MissingPTS, none, pts)
+	case dts == none:
+		dts = pts
+		if s.started && dts > expected {
+			// keep room for the reordering delay of later packets
+			dts = expected
+		}
+		report(MissingDTS, none, dts)
+	case pts == none:
+		pts = dts
+		report(MissingPTS, none, pts)
+	}
+
+	// remove jumps, both forward and backward
+	if s.started {
+		if d := dts - expected; d > s.threshold || d < -s.threshold {
+			report(Discontinuity, dts, expected)
+			s.shift -= d
+			dts -= d
+			pts -= d
+		}
+	}
+
+	if s.started && dts <= s.lastDts {
+		report(NonMonotonic, dts, s.lastDts+1)
+		dts = s.lastDts + 1
+	}
+	if pts < dts {
+		report(PTSBeforeDTS, pts, dts)
+		pts = dts
+	}
+
+	if s.started && dts > s.lastDts && pkt.Duration <= 0 {
+		dur = dts - s.lastDts
+	}
+	s.lastDts, s.lastDur = dts, dur
+	s.started = true
+	pkt.Pts, pkt.Dts = ffcommon.FInt64T(pts), ffcommon.FInt64T(dts)
+	return fixes
+}
+
+func (s *Sanitizer) wrapping() bool {
+	return s.c.WrapBits > 0 && s.c.WrapBits < 63
+}
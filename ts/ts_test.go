@@ -0,0 +1,174 @@
+package ts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dwdcth/ffmpeg-go/v7/ffcommon"
+	"github.com/dwdcth/ffmpeg-go/v7/libavcodec"
+	"github.com/dwdcth/ffmpeg-go/v7/libavutil"
+)
+
+const (
+	none   = libavutil.AV_NOPTS_VALUE
+	period = int64(1) << 33
+)
+
+var tb90k = libavutil.AVRational{Num: 1, Den: 90000}
+
+// ts is a packet timestamp pair, given to Fix and expected back.
+type ts struct{ pts, dts int64 }
+
+func TestFix(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		bits  int
+		dur   int64
+		in    []ts
+		want  []ts
+		fixes [][]Correction
+	}{
+		{
+			name: "clean",
+			bits: 33, dur: 3000,
+			in:   []ts{{0, 0}, {9000, 3000}, {6000, 6000}},
+			want: []ts{{0, 0}, {9000, 3000}, {6000, 6000}},
+		},
+		{
+			name: "wraparound",
+			bits: 33, dur: 3000,
+			in:    []ts{{period - 3000, period - 3000}, {0, 0}, {3000, 3000}},
+			want:  []ts{{period - 3000, period - 3000}, {period, period}, {period + 3000, period + 3000}},
+			fixes: [][]Correction{1: {{Wrap, 0, ffcommon.FInt64T(period)}}},
+		},
+		{
+			name: "pts wrapped before its dts",
+			bits: 33, dur: 3000,
+			in:    []ts{{period - 6000, period - 6000}, {3000, period - 3000}, {6000, 0}},
+			want:  []ts{{period - 6000, period - 6000}, {period + 3000, period - 3000}, {period + 6000, period}},
+			fixes: [][]Correction{2: {{Wrap, 0, ffcommon.FInt64T(period)}}},
+		},
+		{
+			name: "no wraparound with 64 bits",
+			bits: 64, dur: 3000,
+			in:   []ts{{period - 3000, period - 3000}, {period, period}},
+			want: []ts{{period - 3000, period - 3000}, {period, period}},
+		},
+		{
+			name: "forward discontinuity",
+			bits: 33, dur: 3000,
+			in:    []ts{{0, 0}, {3000, 3000}, {9000000, 9000000}, {9003000, 9003000}},
+			want:  []ts{{0, 0}, {3000, 3000}, {6000, 6000}, {9000, 9000}},
+			fixes: [][]Correction{2: {{Discontinuity, 9000000, 6000}}},
+		},
+		{
+			name: "backward discontinuity",
+			bits: 33, dur: 3000,
+			in:    []ts{{90000000, 90000000}, {90003000, 90003000}, {0, 0}, {3000, 3000}},
+			want:  []ts{{90000000, 90000000}, {90003000, 90003000}, {90006000, 90006000}, {90009000, 90009000}},
+			fixes: [][]Correction{2: {{Discontinuity, 0, 90006000}}},
+		},
+		{
+			name: "jump at the threshold",
+			bits: 33, dur: 3000,
+			in:   []ts{{0, 0}, {3000, 3000}, {906000, 906000}},
+			want: []ts{{0, 0}, {3000, 3000}, {906000, 906000}},
+		},
+		{
+			name: "missing pts",
+			bits: 33, dur: 3000,
+			in:   []ts{{none, 0}, {none, 3000}},
+			want: []ts{{0, 0}, {3000, 3000}},
+			fixes: [][]Correction{
+				{{MissingPTS, none, 0}},
+				{{MissingPTS, none, 3000}},
+			},
+		},
+		{
+			name: "missing dts",
+			bits: 33, dur: 3000,
+			in:   []ts{{0, none}, {9000, none}},
+			want: []ts{{0, 0}, {9000, 3000}},
+			fixes: [][]Correction{
+				{{MissingDTS, none, 0}},
+				{{MissingDTS, none, 3000}},
+			},
+		},
+		{
+			name: "missing both, duration from the dts",
+			bits: 33,
+			in:   []ts{{0, 0}, {3000, 3000}, {none, none}},
+			want: []ts{{0, 0}, {3000, 3000}, {6000, 6000}},
+			fixes: [][]Correction{2: {
+				{MissingDTS, none, 6000},
+				{MissingPTS, none, 6000},
+			}},
+		},
+		{
+			name: "non-monotonic dts",
+			bits: 33, dur: 3000,
+			in:   []ts{{0, 0}, {3000, 3000}, {3000, 3000}, {6000, 6000}},
+			want: []ts{{0, 0}, {3000, 3000}, {3001, 3001}, {6000, 6000}},
+			fixes: [][]Correction{2: {
+				{NonMonotonic, 3000, 3001},
+				{PTSBeforeDTS, 3000, 3001},
+			}},
+		},
+		{
+			name: "pts before dts",
+			bits: 33, dur: 3000,
+			in:    []ts{{0, 1000}},
+			want:  []ts{{1000, 1000}},
+			fixes: [][]Correction{{{PTSBeforeDTS, 0, 1000}}},
+		},
+	} {
+		s := New(Config{TimeBase: tb90k, WrapBits: tc.bits})
+		for i, in := range tc.in {
+			pkt := &libavcodec.AVPacket{Pts: ffcommon.FInt64T(in.pts), Dts: ffcommon.FInt64T(in.dts), Duration: ffcommon.FInt64T(tc.dur)}
+			fixes := s.Fix(pkt)
+			if got := (ts{int64(pkt.Pts), int64(pkt.Dts)}); got != tc.want[i] {
+				t.Errorf("%s: packet %d: pts, dts = %d, %d, want %d, %d", tc.name, i, got.pts, got.dts, tc.want[i].pts, tc.want[i].dts)
+			}
+			var want []Correction
+			if i < len(tc.fixes) {
+				want = tc.fixes[i]
+			}
+			if !reflect.DeepEqual(fixes, want) {
+				t.Errorf("%s: packet %d: corrections %v, want %v", tc.name, i, fixes, want)
+			}
+		}
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		tb        libavutil.AVRational
+		threshold time.Duration
+		want      int64
+	}{
+		{tb90k, 0, 900000},
+		{libavutil.AVRational{Num: 1, Den: 1000}, 2 * time.Second, 2000},
+		{libavutil.AVRational{Num: 1, Den: 1}, 100 * time.Millisecond, 1},
+	} {
+		if got := New(Config{TimeBase: tc.tb, Threshold: tc.threshold}).threshold; got != tc.want {
+			t.Errorf("threshold %v in %d/%d = %d, want %d", tc.threshold, tc.tb.Num, tc.tb.Den, got, tc.want)
+		}
+	}
+}
+
+func TestCorrectionString(t *testing.T) {
+	for _, tc := range []struct {
+		c    Correction
+		want string
+	}{
+		{Correction{Wrap, 0, 8589934592}, "wraparound: 0 -> 8589934592"},
+		{Correction{Discontinuity, 9000000, 6000}, "discontinuity: 9000000 -> 6000"},
+		{Correction{MissingPTS, none, 3000}, "missing pts: set to 3000"},
+		{Correction{Kind(9), 1, 2}, "Kind(9): 1 -> 2"},
+	} {
+		if got := tc.c.String(); got != tc.want {
+			t.Errorf("%#v.String() = %q, want %q", tc.c, got, tc.want)
+		}
+	}
+}